@@ -3,19 +3,27 @@ package main
 /**
 API Gateway for Immogestion
 - Handles CORS
-- Proxies requests to auth-service for user registration
-- Provides health check endpoint
-- Implements rate limiting
+- Proxies requests to upstream services (auth-service, ...) from a route table
+- Authenticates non-public routes and forwards the identity (X-User-Id, X-User-Role, X-Token-Id, X-Org-Id)
+- Enforces per-route role/scope policies declared in the route table
+- Checks upstream health, retries idempotent requests and trips a circuit breaker per upstream
+- Provides health check and upstream status endpoints
+- Rate limits per route group, per user (or per IP without token), shared across replicas via Redis
 
 Environment Variables:
 - PORT: Port for the gateway (default: 8080)
+- GATEWAY_CONFIG: Path of the route table (default: config/gateway.yaml)
+- AUTH_SERVICE_URL, PROPERTY_SERVICE_URL, TENANT_SERVICE_URL: Upstream URLs referenced by the route table
+- AUTH_CACHE_TTL: How long a validated token is cached (default: 15s)
 - CORS_ORIGINS: Comma-separated list of allowed CORS origins (default: http://localhost:4200,http://localhost:4201)
-- RATE_LIMIT_REQUESTS: Number of requests allowed (default: 100)
-- RATE_LIMIT_DURATION: Duration for rate limiting (default: 1m)
+- REDIS_URL: Redis shared by the replicas for rate limit counters (empty: per-instance memory)
+- RATE_LIMIT_REQUESTS, RATE_LIMIT_DURATION: Default rate limit, e.g. 300 and M for 300 requests per minute
+- RATE_LIMIT_AUTH_FAILURES: 401 responses allowed per IP before it gets 429 (default: 30-M)
+- TRUSTED_PROXIES: Comma-separated proxies/CIDRs allowed to set X-Forwarded-For (default: none)
 
 Production Deployment:
 - Use Docker and Kubernetes for deployment
-- Ensure auth-service is reachable at http://auth-service:8081
+- Ensure auth-service is reachable at AUTH_SERVICE_URL (default: http://auth-service:8081)
 
 - Monitor logs and performance
 - Scale services as needed
@@ -28,7 +36,7 @@ go run gateway.go
 */
 
 import (
-	"io"
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -36,13 +44,15 @@ import (
 	"strings"
 	"time"
 
+	"api/gateway/internal/auth"
+	"api/gateway/internal/config"
+	"api/gateway/internal/proxy"
+	"api/gateway/internal/ratelimit"
+	"api/gateway/internal/upstream"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-
 	//"github.com/spf13/viper"
-	"github.com/ulule/limiter/v3"
-	mgin "github.com/ulule/limiter/v3/drivers/middleware/gin" // Updated import for v3 middleware
-	"github.com/ulule/limiter/v3/drivers/store/memory"        // v3 memory store
 )
 
 func main() {
@@ -50,6 +60,16 @@ func main() {
 	// Initialize Gin router with default middleware (logging, recovery)
 	r := gin.Default()
 
+	// Adresse IP du client (limitation de débit par IP, X-Forwarded-For transmis aux services) :
+	// X-Forwarded-For n'est lu que s'il provient d'un proxy de confiance
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		trustedProxies = strings.Split(value, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Récupération des origines CORS depuis ENV
 	corsOrigins := os.Getenv("CORS_ORIGINS")
 	if corsOrigins == "" {
@@ -70,119 +90,51 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// Rate limiting middleware
-	// Configure rate limiting middleware using v3 API
-	rate, err := limiter.NewRateFromFormatted(os.Getenv("RATE_LIMIT_REQUESTS") + "-" + os.Getenv("RATE_LIMIT_DURATION"))
-	if err != nil {
-		log.Fatalf("Failed to configure rate limiting: %v", err)
-	}
-	store := memory.NewStore() // v3 memory store
-	limiter := limiter.New(store, rate)
-	limiterMiddleware := mgin.NewMiddleware(limiter) // v3 middleware constructor
-	r.Use(limiterMiddleware)
-
 	// Health check endpoint for API Gateway
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
 
-	// Endpoint to handle user registration requests from Angular
-	r.POST("/api/v1/auth/register", func(c *gin.Context) {
-		// Create a new HTTP request to forward to auth-service
-		req, err := http.NewRequest(http.MethodPost, "http://auth-service:"+os.Getenv("AUTH_SERVICE_PORT")+"/register", c.Request.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request: " + err.Error()})
-			return
-		}
-
-		// Copy relevant headers from the incoming request
-		req.Header.Set("Content-Type", c.Request.Header.Get("Content-Type"))
-
-		// Send the request to auth-service
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to proxy to auth-service: " + err.Error()})
-			return
-		}
-		defer resp.Body.Close()
-
-		// Read the response body from auth-service
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response: " + err.Error()})
-			return
-		}
-
-		// Forward the response back to the client (Angular)
-		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
-	})
-
-	// Endpoint to handle user login requests from Angular
-	r.POST("/api/v1/auth/login", func(c *gin.Context) {
-		// Create a new HTTP request to forward to auth-service
-		req, err := http.NewRequest(http.MethodPost, "http://auth-service:"+os.Getenv("AUTH_SERVICE_PORT")+"/login", c.Request.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request: " + err.Error()})
-			return
-		}
-
-		// Copy relevant headers from the incoming request
-		req.Header.Set("Content-Type", c.Request.Header.Get("Content-Type"))
-
-		// Send the request to auth-service
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to proxy to auth-service: " + err.Error()})
-			return
-		}
-		defer resp.Body.Close()
+	// Table de routage : chaque préfixe est relayé vers son upstream (voir config/gateway.yaml)
+	configPath := os.Getenv("GATEWAY_CONFIG")
+	if configPath == "" {
+		configPath = "config/gateway.yaml"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load gateway config: %v", err)
+	}
+	for _, route := range cfg.Routes {
+		log.Printf("🔀 Route %s → %s (%s)", route.Prefix, route.Upstream, cfg.Upstreams[route.Upstream].URL)
+	}
 
-		// Read the response body from auth-service
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response: " + err.Error()})
-			return
+	// Santé et disjoncteurs des upstreams, partagés par le proxy et la validation des tokens
+	upstreams := upstream.New(cfg)
+	upstreams.StartHealthChecks(context.Background())
+
+	// État des upstreams : dernière vérification de santé et disjoncteur (503 si l'un est indisponible)
+	r.GET("/status", func(c *gin.Context) {
+		statuses := upstreams.Status()
+		status, code := "healthy", http.StatusOK
+		for _, s := range statuses {
+			if !s.Healthy || s.Circuit != upstream.StateClosed.String() {
+				status, code = "degraded", http.StatusServiceUnavailable
+			}
 		}
-
-		// Forward the response back to the client (Angular)
-		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		c.JSON(code, gin.H{"status": status, "upstreams": statuses})
 	})
 
-	// Endpoint to handle token refresh requests from Angular
-	r.POST("/api/v1/auth/refresh", func(c *gin.Context) {
-		// Create a new HTTP request to forward to auth-service
-		req, err := http.NewRequest(http.MethodPost, "http://auth-service:"+os.Getenv("AUTH_SERVICE_PORT")+"/refresh", c.Request.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request: " + err.Error()})
-			return
-		}
-
-		// Copy relevant headers from the incoming request
-		req.Header.Set("Content-Type", c.Request.Header.Get("Content-Type"))
-		req.Header.Set("Authorization", c.Request.Header.Get("Authorization"))
-
-		// Send the request to auth-service
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to proxy to auth-service: " + err.Error()})
-			return
-		}
-		defer resp.Body.Close()
-
-		// Read the response body from auth-service
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response: " + err.Error()})
-			return
-		}
-
-		// Forward the response back to the client (Angular)
-		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
-
-	})
+	// Toute requête hors endpoints propres à la gateway passe par la table de routage, après
+	// limitation des échecs d'authentification par IP, authentification des endpoints non publics,
+	// limitation de débit et contrôle des politiques d'accès (sections "public", "rate_limit" et
+	// "policies" de la config)
+	gatewayProxy := proxy.New(cfg, upstreams)
+	validator := auth.NewValidator(cfg, upstreams)
+	rateLimiter, err := ratelimit.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure rate limiting: %v", err)
+	}
+	r.NoRoute(gatewayProxy.Match, rateLimiter.LimitAuthFailures, auth.Middleware(cfg, validator), rateLimiter.Limit, auth.Authorize(cfg), gatewayProxy.Forward)
 
 	// Get port from environment variable or default to 8080
 	port := os.Getenv("PORT")
@@ -191,8 +143,7 @@ func main() {
 	}
 
 	// Start the server
-	err = r.Run(":" + port)
-	if err != nil {
+	if err := r.Run(":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
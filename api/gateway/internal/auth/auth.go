@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"api/gateway/internal/config"
+	"api/gateway/internal/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// identityKey est la clé du contexte Gin contenant l'identité authentifiée
+const identityKey = "gateway.identity"
+
+// En-têtes d'identité transmis aux upstreams. Ils ne sont dignes de confiance que parce que
+// la gateway retire systématiquement ceux envoyés par le client.
+const (
+	HeaderUserID     = "X-User-Id"
+	HeaderUserEmail  = "X-User-Email"
+	HeaderUserRole   = "X-User-Role"
+	HeaderUserScopes = "X-User-Scopes"
+	// HeaderEmailVerified vaut "true" ou "false" : un email non vérifié n'a que des permissions de lecture
+	HeaderEmailVerified  = "X-User-Email-Verified"
+	HeaderTokenID        = "X-Token-Id"
+	HeaderTokenType      = "X-Token-Type"
+	HeaderOrgID          = "X-Org-Id"
+	HeaderOrgRole        = "X-Org-Role"
+	HeaderImpersonatorID = "X-Impersonator-Id"
+)
+
+// identityHeaders liste les en-têtes retirés des requêtes entrantes
+var identityHeaders = []string{
+	HeaderUserID, HeaderUserEmail, HeaderUserRole, HeaderUserScopes, HeaderEmailVerified,
+	HeaderTokenID, HeaderTokenType, HeaderOrgID, HeaderOrgRole, HeaderImpersonatorID,
+}
+
+// ErrInvalidToken indique un token refusé par le service d'authentification (401)
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Identity est l'identité renvoyée par l'endpoint /validate d'auth-service
+type Identity struct {
+	UserID        uint     `json:"user_id"`
+	Email         string   `json:"email"`
+	Role          string   `json:"role"`
+	TokenID       string   `json:"token_id"`
+	TokenType     string   `json:"token_type"`
+	EmailVerified bool     `json:"email_verified"`
+	Scopes        []string `json:"scopes"`
+	OrgID         uint     `json:"org_id"`
+	// OrgRole est le rôle dans l'organisation active (owner, admin, member)
+	OrgRole string `json:"org_role"`
+	// Impersonated est vrai pour un token émis à un administrateur agissant au nom de l'utilisateur
+	Impersonated bool `json:"impersonated"`
+	ActorUserID  uint `json:"actor_user_id"`
+}
+
+// HasScope indique si l'identité porte la permission demandée
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator valide les tokens (JWT et tokens d'API) auprès d'auth-service. Les identités valides
+// sont mises en cache brièvement pour éviter un aller-retour par requête.
+type Validator struct {
+	endpoint string
+	client   *http.Client
+	cacheTTL time.Duration
+	maxSize  int
+
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+type cachedIdentity struct {
+	identity  *Identity
+	expiresAt time.Time
+}
+
+// NewValidator crée un validateur appelant l'endpoint de validation de l'upstream d'authentification,
+// via le transport de cet upstream (son disjoncteur protège aussi la validation)
+func NewValidator(cfg *config.Config, upstreams *upstream.Pool) *Validator {
+	target := cfg.Upstreams[cfg.Auth.Upstream]
+	return &Validator{
+		endpoint: strings.TrimSuffix(target.URL, "/") + cfg.Auth.ValidatePath,
+		client:   &http.Client{Timeout: time.Duration(target.Timeout), Transport: upstreams.Transport(cfg.Auth.Upstream)},
+		cacheTTL: time.Duration(cfg.Auth.CacheTTL),
+		maxSize:  cfg.Auth.CacheMaxSize,
+		cache:    make(map[string]cachedIdentity),
+	}
+}
+
+// Validate retourne l'identité associée à un token. ErrInvalidToken signale un token refusé ;
+// toute autre erreur signale un service d'authentification injoignable.
+func (v *Validator) Validate(ctx context.Context, token string, forwarded http.Header) (*Identity, error) {
+	key := cacheKey(token)
+	if identity := v.cached(key); identity != nil {
+		return identity, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	for name, values := range forwarded {
+		req.Header[name] = values
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, ErrInvalidToken
+	case resp.StatusCode != http.StatusOK:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("auth service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data Identity `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid auth service response: %w", err)
+	}
+	if body.Data.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	identity := &body.Data
+	// Chaque requête d'une impersonation doit être journalisée par auth-service : jamais en cache
+	if !identity.Impersonated {
+		v.store(key, identity)
+	}
+	return identity, nil
+}
+
+// cached retourne l'identité en cache si elle n'a pas expiré
+func (v *Validator) cached(key string) *Identity {
+	if v.cacheTTL <= 0 {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry, ok := v.cache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(v.cache, key)
+		return nil
+	}
+	return entry.identity
+}
+
+// store met une identité en cache ; le cache plein est purgé de ses entrées expirées,
+// puis vidé si cela ne suffit pas
+func (v *Validator) store(key string, identity *Identity) {
+	if v.cacheTTL <= 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	if len(v.cache) >= v.maxSize {
+		for k, entry := range v.cache {
+			if now.After(entry.expiresAt) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= v.maxSize {
+			v.cache = make(map[string]cachedIdentity)
+		}
+	}
+	v.cache[key] = cachedIdentity{identity: identity, expiresAt: now.Add(v.cacheTTL)}
+}
+
+// cacheKey évite de conserver les tokens en clair en mémoire
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Middleware authentifie les requêtes : les en-têtes d'identité envoyés par le client sont
+// toujours retirés, puis les endpoints non publics exigent un token Bearer valide.
+// L'identité est placée dans le contexte Gin (voir FromContext) et transmise aux upstreams
+// via les en-têtes X-User-* (dont X-User-Email-Verified), X-Token-* et X-Org-*.
+func Middleware(cfg *config.Config, validator *Validator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, header := range identityHeaders {
+			c.Request.Header.Del(header)
+		}
+
+		if cfg.IsPublic(c.Request.Method, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			Unauthorized(c, "Authentication required")
+			return
+		}
+
+		// Méthode et chemin d'origine : auth-service les journalise pour les impersonations
+		forwarded := http.Header{}
+		forwarded.Set("X-Forwarded-For", c.ClientIP())
+		forwarded.Set("X-Forwarded-Method", c.Request.Method)
+		forwarded.Set("X-Forwarded-Uri", c.Request.URL.RequestURI())
+		forwarded.Set("User-Agent", c.Request.UserAgent())
+
+		identity, err := validator.Validate(c.Request.Context(), token, forwarded)
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) {
+				Unauthorized(c, "Invalid or expired token")
+				return
+			}
+			var circuitErr *upstream.CircuitOpenError
+			if errors.As(err, &circuitErr) {
+				c.Header("Retry-After", circuitErr.RetryAfterSeconds())
+			} else {
+				log.Printf("⚠️ Token validation failed: %v", err)
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication service unavailable"})
+			return
+		}
+
+		c.Set(identityKey, identity)
+		setIdentityHeaders(c.Request.Header, identity)
+		c.Next()
+	}
+}
+
+// FromContext retourne l'identité authentifiée par Middleware, ou nil (endpoint public)
+func FromContext(c *gin.Context) *Identity {
+	if value, ok := c.Get(identityKey); ok {
+		return value.(*Identity)
+	}
+	return nil
+}
+
+// Unauthorized répond 401 avec le challenge Bearer
+func Unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="immogestion"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// Forbidden répond 403
+func Forbidden(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+}
+
+// bearerToken extrait le token d'un en-tête "Authorization: Bearer <token>"
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// setIdentityHeaders renseigne les en-têtes d'identité transmis aux upstreams
+func setIdentityHeaders(header http.Header, identity *Identity) {
+	header.Set(HeaderUserID, strconv.FormatUint(uint64(identity.UserID), 10))
+	header.Set(HeaderUserEmail, identity.Email)
+	header.Set(HeaderUserRole, identity.Role)
+	header.Set(HeaderUserScopes, strings.Join(identity.Scopes, " "))
+	header.Set(HeaderEmailVerified, strconv.FormatBool(identity.EmailVerified))
+	header.Set(HeaderTokenID, identity.TokenID)
+	header.Set(HeaderTokenType, identity.TokenType)
+	if identity.OrgID != 0 {
+		header.Set(HeaderOrgID, strconv.FormatUint(uint64(identity.OrgID), 10))
+	}
+	if identity.OrgRole != "" {
+		header.Set(HeaderOrgRole, identity.OrgRole)
+	}
+	if identity.Impersonated {
+		header.Set(HeaderImpersonatorID, strconv.FormatUint(uint64(identity.ActorUserID), 10))
+	}
+}
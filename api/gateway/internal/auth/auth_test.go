@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"api/gateway/internal/config"
+	"api/gateway/internal/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAuthService simule l'endpoint /validate d'auth-service : la réponse dépend du token
+type testAuthService struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Tokens reconnus par testAuthService
+const (
+	tokenValid        = "valid"
+	tokenImpersonated = "impersonated"
+	tokenRejected     = "rejected"
+	tokenServerError  = "server-error"
+	tokenUnavailable  = "unavailable"
+)
+
+func newTestAuthService(t *testing.T) *testAuthService {
+	t.Helper()
+	s := &testAuthService{calls: make(map[string]int)}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		s.calls[token]++
+		s.mu.Unlock()
+
+		var identity Identity
+		switch token {
+		case tokenValid:
+			identity = Identity{UserID: 7, Email: "jane@example.com", Role: "user", TokenType: "access", Scopes: []string{"properties:read"}}
+		case tokenImpersonated:
+			identity = Identity{UserID: 7, Email: "jane@example.com", Role: "user", TokenType: "access", Impersonated: true, ActorUserID: 1}
+		case tokenServerError:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case tokenUnavailable:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": identity})
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// validations retourne le nombre d'appels à /validate reçus pour un token
+func (s *testAuthService) validations(token string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[token]
+}
+
+// newTestRouter protège toutes les routes par Middleware, sauf /public ; le handler final
+// renvoie les en-têtes d'identité reçus, tels qu'un upstream les verrait
+func newTestRouter(t *testing.T, authService *testAuthService) *gin.Engine {
+	t.Helper()
+	cfg, err := config.Parse([]byte("upstreams:\n  auth:\n    url: " + authService.server.URL +
+		"\nroutes:\n  - prefix: /\n    upstream: auth\nauth:\n  upstream: auth\n  cache_ttl: 1m\npublic:\n  - path: /public\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(Middleware(cfg, NewValidator(cfg, upstream.New(cfg))), func(c *gin.Context) {
+		received := map[string]string{}
+		for _, header := range identityHeaders {
+			if value := c.Request.Header.Get(header); value != "" {
+				received[header] = value
+			}
+		}
+		c.JSON(http.StatusOK, received)
+	})
+	return r
+}
+
+// serve envoie une requête avec le token (absent si vide) et les en-têtes donnés
+func serve(r *gin.Engine, path, token string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareStripsClientIdentityHeaders(t *testing.T) {
+	r := newTestRouter(t, newTestAuthService(t))
+	spoofed := map[string]string{
+		HeaderUserID:         "1",
+		HeaderUserRole:       "admin",
+		HeaderUserScopes:     "users:write",
+		HeaderImpersonatorID: "1",
+		HeaderOrgID:          "99",
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  map[string]string
+	}{
+		{name: "public route", path: "/public", want: map[string]string{}},
+		{name: "public route with token", path: "/public", token: tokenValid, want: map[string]string{}},
+		{
+			name:  "protected route",
+			path:  "/private",
+			token: tokenValid,
+			want: map[string]string{
+				HeaderUserID:        "7",
+				HeaderUserEmail:     "jane@example.com",
+				HeaderUserRole:      "user",
+				HeaderUserScopes:    "properties:read",
+				HeaderEmailVerified: "false",
+				HeaderTokenType:     "access",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.path, tt.token, spoofed)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			var received map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if len(received) != len(tt.want) {
+				t.Fatalf("identity headers = %v, want %v", received, tt.want)
+			}
+			for name, value := range tt.want {
+				if received[name] != value {
+					t.Fatalf("%s = %q, want %q (headers %v)", name, received[name], value, received)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareValidationErrors(t *testing.T) {
+	r := newTestRouter(t, newTestAuthService(t))
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "missing token", want: http.StatusUnauthorized},
+		{name: "token rejected by /validate", token: tokenRejected, want: http.StatusUnauthorized},
+		{name: "/validate error", token: tokenServerError, want: http.StatusServiceUnavailable},
+		{name: "/validate unavailable", token: tokenUnavailable, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, "/private", tt.token, nil)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("missing WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestValidatorCache(t *testing.T) {
+	authService := newTestAuthService(t)
+	r := newTestRouter(t, authService)
+
+	for i := 0; i < 3; i++ {
+		for _, token := range []string{tokenValid, tokenImpersonated} {
+			if w := serve(r, "/private", token, nil); w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d, want %d", token, w.Code, http.StatusOK)
+			}
+		}
+	}
+
+	if got := authService.validations(tokenValid); got != 1 {
+		t.Fatalf("validations of a regular token = %d, want 1 (cached)", got)
+	}
+	// Chaque requête d'une impersonation est journalisée par auth-service : jamais en cache
+	if got := authService.validations(tokenImpersonated); got != 3 {
+		t.Fatalf("validations of an impersonation token = %d, want 3 (never cached)", got)
+	}
+
+	w := serve(r, "/private", tokenImpersonated, map[string]string{HeaderImpersonatorID: "42"})
+	var received map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if received[HeaderImpersonatorID] != "1" {
+		t.Fatalf("%s = %q, want the actor from /validate", HeaderImpersonatorID, received[HeaderImpersonatorID])
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"strings"
+
+	"api/gateway/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize applique les politiques d'accès de la configuration avant le relais vers l'upstream :
+// 401 sans identité, 403 si une politique couvrant la requête n'est pas satisfaite.
+// Doit être chaîné après Middleware.
+func Authorize(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies := cfg.MatchPolicies(c.Request.Method, c.Request.URL.Path)
+		if len(policies) == 0 {
+			c.Next()
+			return
+		}
+
+		identity := FromContext(c)
+		if identity == nil {
+			Unauthorized(c, "Authentication required")
+			return
+		}
+		for _, policy := range policies {
+			if !Allows(policy, identity) {
+				Forbidden(c, "Insufficient permissions")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Allows indique si une identité satisfait une politique
+func Allows(policy *config.Policy, identity *Identity) bool {
+	if len(policy.Roles) > 0 && !hasAnyRole(identity, policy.Roles) {
+		return false
+	}
+	if len(policy.Scopes) > 0 && !hasAnyScope(identity, policy.Scopes) {
+		return false
+	}
+	if policy.EmailVerified && !identity.EmailVerified {
+		return false
+	}
+	return true
+}
+
+// hasAnyRole vérifie le rôle plateforme, ou le rôle dans l'organisation active pour "org:<rôle>"
+func hasAnyRole(identity *Identity, roles []string) bool {
+	for _, role := range roles {
+		if orgRole, ok := strings.CutPrefix(role, config.OrgRolePrefix); ok {
+			if identity.OrgRole != "" && identity.OrgRole == orgRole {
+				return true
+			}
+			continue
+		}
+		if identity.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyScope vérifie que le token porte au moins une des permissions
+func hasAnyScope(identity *Identity, scopes []string) bool {
+	for _, scope := range scopes {
+		if identity.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,525 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Délais par défaut appliqués aux upstreams qui n'en déclarent pas
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultConnectTimeout = 5 * time.Second
+)
+
+// Valeurs par défaut de la résilience des upstreams
+const (
+	DefaultHealthCheckPath     = "/health"
+	DefaultHealthCheckInterval = 10 * time.Second
+	DefaultHealthCheckTimeout  = 2 * time.Second
+	DefaultRetryAttempts       = 3
+	DefaultRetryBackoff        = 100 * time.Millisecond
+	DefaultFailureThreshold    = 5
+	DefaultOpenTimeout         = 30 * time.Second
+)
+
+// Valeurs par défaut de l'authentification
+const (
+	DefaultValidatePath     = "/validate"
+	DefaultAuthCacheTTL     = 15 * time.Second
+	DefaultAuthCacheMaxSize = 10000
+)
+
+// Valeurs par défaut de la limitation de débit
+const (
+	DefaultRateLimit       = "300-M"
+	DefaultRateLimitPrefix = "gateway:ratelimit"
+	// DefaultRateLimitGroup nomme la limite appliquée aux requêtes hors groupe
+	DefaultRateLimitGroup = "default"
+	// DefaultAuthFailureLimit borne les échecs d'authentification (401) par IP
+	DefaultAuthFailureLimit = "30-M"
+)
+
+// Config est la table de routage de la gateway : services en aval (upstreams)
+// et préfixes de chemins publics associés
+type Config struct {
+	Upstreams map[string]*Upstream `yaml:"upstreams"`
+	Routes    []*Route             `yaml:"routes"`
+	Auth      Auth                 `yaml:"auth"`
+	// Public liste les endpoints accessibles sans token ; tous les autres exigent un token valide
+	Public []*Rule `yaml:"public"`
+	// Policies restreint des endpoints à certains rôles ou permissions
+	Policies []*Policy `yaml:"policies"`
+	// RateLimit limite le débit par utilisateur (ou par IP sans token), par groupe de routes
+	RateLimit RateLimit `yaml:"rate_limit"`
+}
+
+// Auth configure la validation des tokens par la gateway
+type Auth struct {
+	// Upstream est le service d'authentification qui expose l'endpoint de validation
+	Upstream     string `yaml:"upstream"`
+	ValidatePath string `yaml:"validate_path"`
+	// CacheTTL est la durée de mise en cache d'un token valide (0 : valeur par défaut, négatif : pas de cache).
+	// Un token révoqué reste donc accepté au plus CacheTTL.
+	CacheTTL     Duration `yaml:"cache_ttl"`
+	CacheMaxSize int      `yaml:"cache_max_size"`
+}
+
+// RateLimit configure la limitation de débit. Les compteurs sont partagés par les réplicas
+// de la gateway via Redis ; sans RedisURL, chaque instance compte en mémoire.
+type RateLimit struct {
+	RedisURL string `yaml:"redis_url"`
+	// Prefix préfixe les clés Redis des compteurs
+	Prefix string `yaml:"prefix"`
+	// Default s'applique aux requêtes couvertes par aucun groupe
+	Default Rate `yaml:"default"`
+	// Groups : le premier groupe dont une règle couvre la requête l'emporte
+	Groups []*RateLimitGroup `yaml:"groups"`
+	// AuthFailures limite les réponses 401 par IP ; au-delà, les requêtes de l'IP sont refusées
+	// avant même la validation de leur token
+	AuthFailures Rate `yaml:"auth_failures"`
+
+	DefaultGroup *RateLimitGroup `yaml:"-"`
+}
+
+// RateLimitGroup applique une limite commune à un ensemble d'endpoints. Le compteur est propre
+// au groupe : les requêtes d'un groupe n'entament pas la limite des autres.
+type RateLimitGroup struct {
+	Name  string  `yaml:"name"`
+	Rate  Rate    `yaml:"rate"`
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rate est une limite au format "<requêtes>-<période>", période parmi S, M, H, D ("10-M" : 10 par minute)
+type Rate limiter.Rate
+
+// UnmarshalYAML implémente yaml.Unmarshaler
+func (r *Rate) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := limiter.NewRateFromFormatted(value.Value)
+	if err != nil || parsed.Limit <= 0 {
+		return fmt.Errorf("line %d: invalid rate %q", value.Line, value.Value)
+	}
+	*r = Rate(parsed)
+	return nil
+}
+
+// Rule désigne un ensemble d'endpoints par motif de chemin et méthodes.
+// Dans le motif, "*" couvre un segment, ou tout le reste du chemin en dernière position :
+// /api/v1/properties/* couvre /api/v1/properties/12 et /api/v1/properties/12/photos.
+type Rule struct {
+	Path    string   `yaml:"path"`
+	Methods []string `yaml:"methods"`
+}
+
+// OrgRolePrefix préfixe, dans Policy.Roles, un rôle dans l'organisation active (org:owner)
+const OrgRolePrefix = "org:"
+
+// Policy exige un rôle ou une permission pour les endpoints couverts par sa règle.
+// Toutes les politiques couvrant une requête doivent être satisfaites.
+type Policy struct {
+	Rule `yaml:",inline"`
+	// Roles : rôle plateforme (user, admin) ou, préfixé par "org:", rôle dans l'organisation
+	// active (org:owner, org:admin, org:member) ; un seul suffit
+	Roles []string `yaml:"roles"`
+	// Scopes : permissions portées par le token (RBAC ou scopes d'un token d'API) ; une seule suffit.
+	// Quand Roles et Scopes sont renseignés, les deux conditions s'appliquent.
+	Scopes []string `yaml:"scopes"`
+	// EmailVerified exige en plus une adresse email vérifiée
+	EmailVerified bool `yaml:"email_verified"`
+}
+
+// Upstream décrit un service en aval
+type Upstream struct {
+	Name string `yaml:"-"`
+	// URL de base du service (ex: http://auth-service:8081)
+	URL string `yaml:"url"`
+	// Timeout borne l'attente des en-têtes de réponse (le corps reste streamé sans limite)
+	Timeout Duration `yaml:"timeout"`
+	// ConnectTimeout borne l'établissement de la connexion TCP
+	ConnectTimeout Duration `yaml:"connect_timeout"`
+
+	HealthCheck    HealthCheck    `yaml:"health_check"`
+	Retry          Retry          `yaml:"retry"`
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+
+	Target *url.URL `yaml:"-"`
+}
+
+// HealthCheck configure la vérification périodique d'un upstream (GET, réponse 2xx attendue)
+type HealthCheck struct {
+	Path     string   `yaml:"path"`
+	Interval Duration `yaml:"interval"`
+	Timeout  Duration `yaml:"timeout"`
+}
+
+// Retry configure la réémission des requêtes idempotentes sans corps (GET, HEAD, OPTIONS,
+// PUT, DELETE) en cas d'échec de connexion ou de réponse 502, 503 ou 504
+type Retry struct {
+	// Attempts est le nombre total de tentatives (1 : pas de réémission)
+	Attempts int `yaml:"attempts"`
+	// Backoff est le délai avant la deuxième tentative, doublé ensuite (avec une part aléatoire)
+	Backoff Duration `yaml:"backoff"`
+}
+
+// CircuitBreaker configure le disjoncteur d'un upstream : après FailureThreshold échecs
+// consécutifs, les requêtes sont refusées (503) pendant OpenTimeout, puis une requête test décide
+// de la reprise
+type CircuitBreaker struct {
+	FailureThreshold int      `yaml:"failure_threshold"`
+	OpenTimeout      Duration `yaml:"open_timeout"`
+}
+
+// Route associe un préfixe de chemin à un upstream
+type Route struct {
+	// Prefix est comparé par segments : /api/v1/auth couvre /api/v1/auth/login mais pas /api/v1/authx
+	Prefix   string `yaml:"prefix"`
+	Upstream string `yaml:"upstream"`
+	// Rewrite remplace le préfixe dans le chemin transmis ("/" le retire) ; vide = chemin inchangé
+	Rewrite string `yaml:"rewrite"`
+	// Methods restreint les méthodes HTTP acceptées (toutes si vide)
+	Methods []string `yaml:"methods"`
+}
+
+// Duration est une durée au format time.ParseDuration ("10s", "1m30s")
+type Duration time.Duration
+
+// UnmarshalYAML implémente yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", value.Line, value.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Load lit la table de routage depuis un fichier YAML (ou JSON, sous-ensemble de YAML).
+// Les références ${VAR} et ${VAR:-défaut} sont remplacées par les variables d'environnement.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gateway config: %w", err)
+	}
+	cfg, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("gateway config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse lit la table de routage depuis un contenu YAML, comme Load
+func Parse(raw []byte) (*Config, error) {
+	cfg, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("gateway config: %w", err)
+	}
+	return cfg, nil
+}
+
+// parse décode et valide la configuration, après substitution des variables d'environnement
+func parse(raw []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(expandEnv(string(raw)))))
+	decoder.KnownFields(true)
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validate vérifie la cohérence de la configuration et complète les valeurs par défaut
+func (c *Config) validate() error {
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("no route configured")
+	}
+
+	for name, upstream := range c.Upstreams {
+		if upstream == nil {
+			return fmt.Errorf("upstream %q: missing definition", name)
+		}
+		target, err := url.Parse(upstream.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("upstream %q: invalid url %q", name, upstream.URL)
+		}
+		upstream.Name, upstream.Target = name, target
+		if upstream.Timeout <= 0 {
+			upstream.Timeout = Duration(DefaultTimeout)
+		}
+		if upstream.ConnectTimeout <= 0 {
+			upstream.ConnectTimeout = Duration(DefaultConnectTimeout)
+		}
+		if err := upstream.applyResilienceDefaults(); err != nil {
+			return fmt.Errorf("upstream %q: %w", name, err)
+		}
+	}
+
+	if c.Auth.Upstream == "" {
+		return fmt.Errorf("auth: missing upstream")
+	}
+	if _, ok := c.Upstreams[c.Auth.Upstream]; !ok {
+		return fmt.Errorf("auth: unknown upstream %q", c.Auth.Upstream)
+	}
+	if c.Auth.ValidatePath == "" {
+		c.Auth.ValidatePath = DefaultValidatePath
+	}
+	if c.Auth.CacheTTL == 0 {
+		c.Auth.CacheTTL = Duration(DefaultAuthCacheTTL)
+	}
+	if c.Auth.CacheMaxSize <= 0 {
+		c.Auth.CacheMaxSize = DefaultAuthCacheMaxSize
+	}
+	for _, rule := range c.Public {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("public: %w", err)
+		}
+	}
+	for _, policy := range c.Policies {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("policies: %w", err)
+		}
+		if len(policy.Roles) == 0 && len(policy.Scopes) == 0 && !policy.EmailVerified {
+			return fmt.Errorf("policies: rule %q: roles, scopes or email_verified required", policy.Path)
+		}
+	}
+	if err := c.RateLimit.validate(); err != nil {
+		return fmt.Errorf("rate_limit: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			return fmt.Errorf("route %q: prefix must start with /", route.Prefix)
+		}
+		if route.Prefix != "/" {
+			route.Prefix = strings.TrimSuffix(route.Prefix, "/")
+		}
+		if _, ok := c.Upstreams[route.Upstream]; !ok {
+			return fmt.Errorf("route %q: unknown upstream %q", route.Prefix, route.Upstream)
+		}
+		if route.Rewrite != "" && !strings.HasPrefix(route.Rewrite, "/") {
+			return fmt.Errorf("route %q: rewrite must start with /", route.Prefix)
+		}
+		for i, method := range route.Methods {
+			route.Methods[i] = strings.ToUpper(method)
+		}
+		key := route.Prefix + " " + strings.Join(route.Methods, ",")
+		if seen[key] {
+			return fmt.Errorf("route %q: declared twice", route.Prefix)
+		}
+		seen[key] = true
+	}
+
+	// Le préfixe le plus long l'emporte, quel que soit l'ordre de déclaration
+	sort.SliceStable(c.Routes, func(i, j int) bool {
+		return len(c.Routes[i].Prefix) > len(c.Routes[j].Prefix)
+	})
+	return nil
+}
+
+// applyResilienceDefaults complète les vérifications de santé, réémissions et disjoncteur
+func (u *Upstream) applyResilienceDefaults() error {
+	if u.HealthCheck.Path == "" {
+		u.HealthCheck.Path = DefaultHealthCheckPath
+	}
+	if !strings.HasPrefix(u.HealthCheck.Path, "/") {
+		return fmt.Errorf("health_check: path must start with /")
+	}
+	if u.HealthCheck.Interval <= 0 {
+		u.HealthCheck.Interval = Duration(DefaultHealthCheckInterval)
+	}
+	if u.HealthCheck.Timeout <= 0 {
+		u.HealthCheck.Timeout = Duration(DefaultHealthCheckTimeout)
+	}
+	if u.Retry.Attempts <= 0 {
+		u.Retry.Attempts = DefaultRetryAttempts
+	}
+	if u.Retry.Backoff <= 0 {
+		u.Retry.Backoff = Duration(DefaultRetryBackoff)
+	}
+	if u.CircuitBreaker.FailureThreshold <= 0 {
+		u.CircuitBreaker.FailureThreshold = DefaultFailureThreshold
+	}
+	if u.CircuitBreaker.OpenTimeout <= 0 {
+		u.CircuitBreaker.OpenTimeout = Duration(DefaultOpenTimeout)
+	}
+	return nil
+}
+
+// Match retourne la route la plus spécifique pour une méthode et un chemin, ou nil
+func (c *Config) Match(method, path string) *Route {
+	for _, route := range c.Routes {
+		if route.matchesPath(path) && allowsMethod(route.Methods, method) {
+			return route
+		}
+	}
+	return nil
+}
+
+// matchesPath compare le chemin au préfixe segment par segment
+func (r *Route) matchesPath(path string) bool {
+	if r.Prefix == "/" || path == r.Prefix {
+		return true
+	}
+	return strings.HasPrefix(path, r.Prefix+"/")
+}
+
+// allowsMethod indique si la méthode fait partie de la liste (toutes si la liste est vide)
+func allowsMethod(methods []string, method string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, allowed := range methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublic indique si un endpoint est accessible sans token
+func (c *Config) IsPublic(method, path string) bool {
+	for _, rule := range c.Public {
+		if rule.Matches(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPolicies retourne les politiques couvrant une méthode et un chemin
+func (c *Config) MatchPolicies(method, path string) []*Policy {
+	var matched []*Policy
+	for _, policy := range c.Policies {
+		if policy.Matches(method, path) {
+			matched = append(matched, policy)
+		}
+	}
+	return matched
+}
+
+// MatchRateLimit retourne le groupe de limitation d'une méthode et d'un chemin
+// (le groupe par défaut si aucun ne les couvre)
+func (c *Config) MatchRateLimit(method, path string) *RateLimitGroup {
+	for _, group := range c.RateLimit.Groups {
+		for _, rule := range group.Rules {
+			if rule.Matches(method, path) {
+				return group
+			}
+		}
+	}
+	return c.RateLimit.DefaultGroup
+}
+
+// validate vérifie les groupes de limitation et complète les valeurs par défaut
+func (r *RateLimit) validate() error {
+	if r.Prefix == "" {
+		r.Prefix = DefaultRateLimitPrefix
+	}
+	if r.Default.Limit == 0 {
+		rate, _ := limiter.NewRateFromFormatted(DefaultRateLimit)
+		r.Default = Rate(rate)
+	}
+	if r.AuthFailures.Limit == 0 {
+		rate, _ := limiter.NewRateFromFormatted(DefaultAuthFailureLimit)
+		r.AuthFailures = Rate(rate)
+	}
+	r.DefaultGroup = &RateLimitGroup{Name: DefaultRateLimitGroup, Rate: r.Default}
+
+	seen := map[string]bool{DefaultRateLimitGroup: true}
+	for _, group := range r.Groups {
+		if group == nil || group.Name == "" {
+			return fmt.Errorf("group: missing name")
+		}
+		if seen[group.Name] {
+			return fmt.Errorf("group %q: declared twice", group.Name)
+		}
+		seen[group.Name] = true
+		if group.Rate.Limit == 0 {
+			return fmt.Errorf("group %q: missing rate", group.Name)
+		}
+		if len(group.Rules) == 0 {
+			return fmt.Errorf("group %q: no rule", group.Name)
+		}
+		for _, rule := range group.Rules {
+			if err := rule.validate(); err != nil {
+				return fmt.Errorf("group %q: %w", group.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate vérifie le motif de la règle et normalise ses méthodes
+func (r *Rule) validate() error {
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("rule %q: path must start with /", r.Path)
+	}
+	for i, method := range r.Methods {
+		r.Methods[i] = strings.ToUpper(method)
+	}
+	return nil
+}
+
+// Matches indique si la règle couvre une méthode et un chemin. Les segments vides ("//", "/"
+// final) sont ignorés : /api//v1/x/ est couvert comme /api/v1/x, sans quoi un "//" suffirait à
+// contourner une politique.
+func (r *Rule) Matches(method, path string) bool {
+	if !allowsMethod(r.Methods, method) {
+		return false
+	}
+
+	patternSegments := pathSegments(r.Path)
+	segments := pathSegments(path)
+	for i, segment := range patternSegments {
+		if segment == "*" && i == len(patternSegments)-1 {
+			// "*" final : au moins un segment restant
+			return len(segments) > i
+		}
+		if i >= len(segments) || (segment != "*" && segment != segments[i]) {
+			return false
+		}
+	}
+	return len(segments) == len(patternSegments)
+}
+
+// pathSegments découpe un chemin en segments non vides
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
+
+// RewritePath calcule le chemin transmis à l'upstream
+func (r *Route) RewritePath(path string) string {
+	if r.Rewrite == "" {
+		return path
+	}
+	rest := path
+	if r.Prefix != "/" {
+		rest = strings.TrimPrefix(path, r.Prefix)
+	}
+	rewritten := strings.TrimSuffix(r.Rewrite, "/") + "/" + strings.TrimPrefix(rest, "/")
+	if rest == "" && r.Rewrite != "/" {
+		rewritten = r.Rewrite
+	}
+	return rewritten
+}
+
+// expandEnv remplace ${VAR} et ${VAR:-défaut} par les variables d'environnement
+func expandEnv(raw string) string {
+	return os.Expand(raw, func(name string) string {
+		name, fallback, hasFallback := strings.Cut(name, ":-")
+		if value, ok := os.LookupEnv(name); ok && (value != "" || !hasFallback) {
+			return value
+		}
+		return fallback
+	})
+}
@@ -0,0 +1,157 @@
+package config
+
+import "testing"
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		method  string
+		path    string
+		matches bool
+	}{
+		{name: "exact path", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "/api/v1/auth/login", matches: true},
+		{name: "trailing slash in path", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "/api/v1/auth/login/", matches: true},
+		{name: "trailing slash in pattern", rule: Rule{Path: "/api/v1/auth/login/"}, method: "POST", path: "/api/v1/auth/login", matches: true},
+		{name: "longer path", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "/api/v1/auth/login/2fa"},
+		{name: "segment prefix only", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "/api/v1/auth/loginx"},
+		{name: "double slash", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "/api/v1/auth//login", matches: true},
+		{name: "leading double slash", rule: Rule{Path: "/api/v1/auth/login"}, method: "POST", path: "//api/v1/auth/login", matches: true},
+		{name: "method allowed", rule: Rule{Path: "/api/v1/auth/token", Methods: []string{"POST"}}, method: "POST", path: "/api/v1/auth/token", matches: true},
+		{name: "method not allowed", rule: Rule{Path: "/api/v1/auth/token", Methods: []string{"POST"}}, method: "GET", path: "/api/v1/auth/token"},
+		{name: "inner star covers one segment", rule: Rule{Path: "/api/v1/auth/users/*/impersonate"}, method: "POST", path: "/api/v1/auth/users/12/impersonate", matches: true},
+		{name: "inner star needs a segment", rule: Rule{Path: "/api/v1/auth/users/*/impersonate"}, method: "POST", path: "/api/v1/auth/users/impersonate"},
+		{name: "inner star with double slash", rule: Rule{Path: "/api/v1/auth/users/*/impersonate"}, method: "POST", path: "/api/v1/auth/users//12/impersonate", matches: true},
+		{name: "final star covers one segment", rule: Rule{Path: "/api/v1/properties/*"}, method: "GET", path: "/api/v1/properties/12", matches: true},
+		{name: "final star covers the rest", rule: Rule{Path: "/api/v1/properties/*"}, method: "GET", path: "/api/v1/properties/12/photos", matches: true},
+		{name: "final star needs a segment", rule: Rule{Path: "/api/v1/properties/*"}, method: "GET", path: "/api/v1/properties"},
+		{name: "final star ignores trailing slash", rule: Rule{Path: "/api/v1/properties/*"}, method: "GET", path: "/api/v1/properties/"},
+		{name: "final star ignores double slash", rule: Rule{Path: "/api/v1/properties/*"}, method: "GET", path: "/api/v1/properties//"},
+		{name: "root pattern", rule: Rule{Path: "/"}, method: "GET", path: "/", matches: true},
+		{name: "root pattern is exact", rule: Rule{Path: "/"}, method: "GET", path: "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.method, tt.path); got != tt.matches {
+				t.Fatalf("Rule{%q}.Matches(%s, %q) = %v, want %v", tt.rule.Path, tt.method, tt.path, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestRouteRewritePath(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		path  string
+		want  string
+	}{
+		{name: "no rewrite", route: Route{Prefix: "/api/v1/auth"}, path: "/api/v1/auth/login", want: "/api/v1/auth/login"},
+		{name: "rewrite to root", route: Route{Prefix: "/api/v1/auth", Rewrite: "/"}, path: "/api/v1/auth/login", want: "/login"},
+		{name: "rewrite to root, prefix only", route: Route{Prefix: "/api/v1/auth", Rewrite: "/"}, path: "/api/v1/auth", want: "/"},
+		{name: "rewrite to root, trailing slash", route: Route{Prefix: "/api/v1/auth", Rewrite: "/"}, path: "/api/v1/auth/", want: "/"},
+		{name: "rewrite to path", route: Route{Prefix: "/api/v1/tenants", Rewrite: "/tenants"}, path: "/api/v1/tenants/12", want: "/tenants/12"},
+		{name: "rewrite to path, prefix only", route: Route{Prefix: "/api/v1/tenants", Rewrite: "/tenants"}, path: "/api/v1/tenants", want: "/tenants"},
+		{name: "rewrite to path, trailing slash", route: Route{Prefix: "/api/v1/tenants", Rewrite: "/tenants"}, path: "/api/v1/tenants/", want: "/tenants/"},
+		{name: "rewrite to path with trailing slash", route: Route{Prefix: "/api/v1/tenants", Rewrite: "/tenants/"}, path: "/api/v1/tenants/12", want: "/tenants/12"},
+		{name: "root prefix", route: Route{Prefix: "/", Rewrite: "/v2"}, path: "/properties/12", want: "/v2/properties/12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.RewritePath(tt.path); got != tt.want {
+				t.Fatalf("RewritePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// testConfig déclare volontairement les routes du préfixe le plus court au plus long
+const testConfig = `
+upstreams:
+  auth:
+    url: http://auth-service:8081
+  property:
+    url: http://property-service:8082
+  fallback:
+    url: http://fallback:8080
+routes:
+  - prefix: /
+    upstream: fallback
+  - prefix: /api/v1/
+    upstream: property
+  - prefix: /api/v1/auth
+    upstream: auth
+    rewrite: /
+  - prefix: /api/v1/auth/admin
+    upstream: auth
+    methods: [get]
+auth:
+  upstream: auth
+`
+
+func TestConfigMatch(t *testing.T) {
+	cfg, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantPrefix string
+	}{
+		{name: "longest prefix wins over declaration order", method: "POST", path: "/api/v1/auth/login", wantPrefix: "/api/v1/auth"},
+		{name: "prefix itself", method: "GET", path: "/api/v1/auth", wantPrefix: "/api/v1/auth"},
+		{name: "prefix with trailing slash", method: "GET", path: "/api/v1/auth/", wantPrefix: "/api/v1/auth"},
+		{name: "segment boundary", method: "GET", path: "/api/v1/authx", wantPrefix: "/api/v1"},
+		{name: "trailing slash removed from declared prefix", method: "GET", path: "/api/v1/properties/12", wantPrefix: "/api/v1"},
+		{name: "double slash after prefix", method: "POST", path: "/api/v1/auth//login", wantPrefix: "/api/v1/auth"},
+		{name: "method restricted route", method: "GET", path: "/api/v1/auth/admin/users", wantPrefix: "/api/v1/auth/admin"},
+		{name: "method restricted route falls back", method: "DELETE", path: "/api/v1/auth/admin/users", wantPrefix: "/api/v1/auth"},
+		{name: "root catch-all", method: "GET", path: "/health", wantPrefix: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := cfg.Match(tt.method, tt.path)
+			if route == nil {
+				t.Fatalf("Match(%s, %q) = nil, want %q", tt.method, tt.path, tt.wantPrefix)
+			}
+			if route.Prefix != tt.wantPrefix {
+				t.Fatalf("Match(%s, %q) = %q, want %q", tt.method, tt.path, route.Prefix, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GATEWAY_TEST_SET", "value")
+	t.Setenv("GATEWAY_TEST_EMPTY", "")
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "set variable", raw: "url: ${GATEWAY_TEST_SET}", want: "url: value"},
+		{name: "unset variable", raw: "url: ${GATEWAY_TEST_UNSET}", want: "url: "},
+		{name: "set variable ignores default", raw: "ttl: ${GATEWAY_TEST_SET:-15s}", want: "ttl: value"},
+		{name: "unset variable uses default", raw: "ttl: ${GATEWAY_TEST_UNSET:-15s}", want: "ttl: 15s"},
+		{name: "empty variable uses default", raw: "ttl: ${GATEWAY_TEST_EMPTY:-15s}", want: "ttl: 15s"},
+		{name: "empty variable without default", raw: "ttl: ${GATEWAY_TEST_EMPTY}", want: "ttl: "},
+		{name: "default with colon", raw: "url: ${GATEWAY_TEST_UNSET:-http://auth:8081}", want: "url: http://auth:8081"},
+		{name: "several references", raw: "${GATEWAY_TEST_SET}-${GATEWAY_TEST_UNSET:-x}", want: "value-x"},
+		{name: "no reference", raw: "prefix: /api/v1", want: "prefix: /api/v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandEnv(tt.raw); got != tt.want {
+				t.Fatalf("expandEnv(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
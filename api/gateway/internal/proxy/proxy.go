@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"api/gateway/internal/config"
+	"api/gateway/internal/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeKey est la clé du contexte Gin contenant la route résolue par Match
+const routeKey = "gateway.route"
+
+// clientIPKey est la clé du contexte de la requête contenant l'IP du client résolue par Gin
+type clientIPKey struct{}
+
+// Proxy relaie les requêtes vers les upstreams selon la table de routage.
+// Les corps de requête et de réponse sont streamés, jamais chargés en mémoire.
+type Proxy struct {
+	config  *config.Config
+	proxies map[*config.Route]*httputil.ReverseProxy
+}
+
+// New construit un reverse proxy par route ; les routes d'un même upstream partagent
+// son pool de connexions, ses délais, ses réémissions et son disjoncteur
+func New(cfg *config.Config, upstreams *upstream.Pool) *Proxy {
+	p := &Proxy{config: cfg, proxies: make(map[*config.Route]*httputil.ReverseProxy, len(cfg.Routes))}
+	for _, route := range cfg.Routes {
+		target := cfg.Upstreams[route.Upstream]
+		p.proxies[route] = &httputil.ReverseProxy{
+			Rewrite:        rewrite(route, target.Target),
+			Transport:      upstreams.Transport(route.Upstream),
+			ModifyResponse: stripCORSHeaders,
+			ErrorHandler:   errorHandler(target.Name),
+		}
+	}
+	return p
+}
+
+// Match résout la route de la requête et la place dans le contexte Gin (voir Route) ;
+// répond 404 si aucune route ne correspond. À chaîner avant les contrôles propres à la route.
+func (p *Proxy) Match(c *gin.Context) {
+	route := p.config.Match(c.Request.Method, c.Request.URL.Path)
+	if route == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	c.Set(routeKey, route)
+	c.Next()
+}
+
+// Forward relaie la requête vers l'upstream de la route résolue par Match
+func (p *Proxy) Forward(c *gin.Context) {
+	route := Route(c)
+	if route == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	// L'IP du client est celle retenue par Gin (TRUSTED_PROXIES), comme pour la limitation de débit
+	ctx := context.WithValue(c.Request.Context(), clientIPKey{}, c.ClientIP())
+	p.proxies[route].ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// Route retourne la route résolue par Match, ou nil
+func Route(c *gin.Context) *config.Route {
+	if value, ok := c.Get(routeKey); ok {
+		return value.(*config.Route)
+	}
+	return nil
+}
+
+// rewrite construit la requête sortante : chemin réécrit, en-têtes X-Forwarded-*.
+// En mode Rewrite, httputil.ReverseProxy retire déjà les en-têtes hop-by-hop et les
+// X-Forwarded-* reçus du client : la gateway est le point d'entrée, ils ne sont pas fiables.
+// X-Forwarded-For porte l'IP du client résolue par Gin, et non l'adresse de la connexion :
+// derrière un load balancer de confiance, ce serait la sienne pour tous les clients.
+func rewrite(route *config.Route, target *url.URL) func(*httputil.ProxyRequest) {
+	return func(pr *httputil.ProxyRequest) {
+		escaped := route.RewritePath(pr.In.URL.EscapedPath())
+		if path, err := url.PathUnescape(escaped); err == nil {
+			pr.Out.URL.Path, pr.Out.URL.RawPath = path, escaped
+		}
+		pr.SetURL(target)
+		pr.SetXForwarded()
+		if clientIP, ok := pr.In.Context().Value(clientIPKey{}).(string); ok && clientIP != "" {
+			pr.Out.Header.Set("X-Forwarded-For", clientIP)
+		}
+		pr.Out.Header.Set("X-Forwarded-Prefix", route.Prefix)
+	}
+}
+
+// stripCORSHeaders retire les en-têtes CORS des upstreams : seule la gateway les gère,
+// des valeurs en double seraient rejetées par les navigateurs
+func stripCORSHeaders(resp *http.Response) error {
+	for name := range resp.Header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			resp.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// errorHandler répond sans exposer l'erreur réseau (adresse interne, message de dial)
+func errorHandler(name string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(err, context.Canceled) {
+			// Le client a abandonné la requête
+			return
+		}
+
+		status, message := http.StatusBadGateway, "Upstream service unavailable"
+		var circuitErr *upstream.CircuitOpenError
+		var netErr net.Error
+		switch {
+		case errors.As(err, &circuitErr):
+			// Échec immédiat, la transition du disjoncteur est déjà journalisée
+			status = http.StatusServiceUnavailable
+			w.Header().Set("Retry-After", circuitErr.RetryAfterSeconds())
+		case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+			status, message = http.StatusGatewayTimeout, "Upstream service timeout"
+			log.Printf("⚠️ Upstream %s error on %s %s: %v", name, r.Method, r.URL.Path, err)
+		default:
+			log.Printf("⚠️ Upstream %s error on %s %s: %v", name, r.Method, r.URL.Path, err)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":"` + message + `"}`))
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"api/gateway/internal/config"
+	"api/gateway/internal/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestGateway relaie /api/v1/echo vers un upstream qui renvoie son X-Forwarded-For
+func newTestGateway(t *testing.T, trustedProxies []string) *httptest.Server {
+	t.Helper()
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Forwarded-For"))
+	}))
+	t.Cleanup(echo.Close)
+	return startGateway(t, "upstreams:\n  echo:\n    url: "+echo.URL+"\nroutes:\n  - prefix: /api/v1/echo\n    upstream: echo\n    rewrite: /\nauth:\n  upstream: echo\n", trustedProxies)
+}
+
+// startGateway démarre la gateway (routage et relais seuls) sur la configuration raw
+func startGateway(t *testing.T, raw string, trustedProxies []string) *httptest.Server {
+	t.Helper()
+	cfg, err := config.Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	p := New(cfg, upstream.New(cfg))
+	r.NoRoute(p.Match, p.Forward)
+
+	gateway := httptest.NewServer(r)
+	t.Cleanup(gateway.Close)
+	return gateway
+}
+
+func TestForwardSetsClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		forwardedFor   string
+		want           string
+	}{
+		{name: "direct client", want: "127.0.0.1"},
+		{name: "spoofed header from untrusted client", forwardedFor: "203.0.113.7", want: "127.0.0.1"},
+		{name: "client behind trusted load balancer", trustedProxies: []string{"127.0.0.1"}, forwardedFor: "203.0.113.7", want: "203.0.113.7"},
+		{
+			name:           "only the address seen by the trusted load balancer",
+			trustedProxies: []string{"127.0.0.1"},
+			forwardedFor:   "198.51.100.1, 203.0.113.7",
+			want:           "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := newTestGateway(t, tt.trustedProxies)
+			req, err := http.NewRequest(http.MethodGet, gateway.URL+"/api/v1/echo", nil)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if got := string(body); got != tt.want {
+				t.Fatalf("upstream X-Forwarded-For = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardCircuitOpen(t *testing.T) {
+	var hits atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+	gateway := startGateway(t, "upstreams:\n  down:\n    url: "+down.URL+
+		"\n    retry:\n      attempts: 1\n    circuit_breaker:\n      failure_threshold: 2\n      open_timeout: 30s\n"+
+		"routes:\n  - prefix: /api/v1/down\n    upstream: down\nauth:\n  upstream: down\n", nil)
+
+	for i := 1; i <= 3; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/down")
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("request %d: status = %d, want %d", i, resp.StatusCode, http.StatusServiceUnavailable)
+		}
+		retryAfter := resp.Header.Get("Retry-After")
+		if i < 3 && retryAfter != "" {
+			t.Fatalf("request %d: Retry-After = %q from a closed circuit, want none", i, retryAfter)
+		}
+		if i == 3 && retryAfter != "30" {
+			t.Fatalf("request %d: Retry-After = %q, want 30", i, retryAfter)
+		}
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("upstream hits = %d, want 2 (no request while the circuit is open)", got)
+	}
+}
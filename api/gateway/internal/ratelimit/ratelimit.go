@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"api/gateway/internal/auth"
+	"api/gateway/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	redisstore "github.com/ulule/limiter/v3/drivers/store/redis"
+)
+
+// En-têtes de limitation (draft IETF "RateLimit header fields for HTTP")
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+)
+
+// Délais Redis volontairement courts : en cas de panne, les requêtes passent sans limite
+// plutôt que d'attendre
+const (
+	redisDialTimeout = time.Second
+	redisIOTimeout   = 500 * time.Millisecond
+	// reconnectInterval espace les tentatives de connexion quand Redis est injoignable au démarrage
+	reconnectInterval = 10 * time.Second
+)
+
+// Limiter applique les limites de débit de la configuration. Si le store est indisponible,
+// les requêtes sont acceptées (fail open) : une panne de Redis ne doit pas couper l'API.
+type Limiter struct {
+	config *config.Config
+	client *redis.Client
+	// store est nil tant que Redis n'a pas répondu ; il est lu sans verrou sur chaque requête
+	store atomic.Pointer[limiter.Store]
+}
+
+// New crée le limiteur : store Redis partagé par les réplicas si redis_url est renseigné,
+// mémoire locale sinon
+func New(cfg *config.Config) (*Limiter, error) {
+	l := &Limiter{config: cfg}
+	if cfg.RateLimit.RedisURL == "" {
+		log.Printf("⚠️ Rate limit: no redis_url, counters are local to this instance")
+		store := memory.NewStoreWithOptions(limiter.StoreOptions{
+			Prefix:          cfg.RateLimit.Prefix,
+			CleanUpInterval: limiter.DefaultCleanUpInterval,
+		})
+		l.store.Store(&store)
+		return l, nil
+	}
+
+	options, err := redis.ParseURL(cfg.RateLimit.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: invalid redis_url: %w", err)
+	}
+	options.DialTimeout = redisDialTimeout
+	options.ReadTimeout = redisIOTimeout
+	options.WriteTimeout = redisIOTimeout
+	l.client = redis.NewClient(options)
+	go l.connect()
+	return l, nil
+}
+
+// Limit est le middleware Gin de limitation. À chaîner après auth.Middleware pour que
+// les requêtes authentifiées soient comptées par utilisateur plutôt que par IP.
+func (l *Limiter) Limit(c *gin.Context) {
+	store := l.currentStore()
+	if store == nil {
+		c.Next()
+		return
+	}
+
+	group := l.config.MatchRateLimit(c.Request.Method, c.Request.URL.Path)
+	rate := limiter.Rate(group.Rate)
+	result, err := store.Increment(c.Request.Context(), key(c, group), 1, rate)
+	if err != nil {
+		log.Printf("⚠️ Rate limit store error, request allowed: %v", err)
+		c.Next()
+		return
+	}
+
+	resetIn := resetSeconds(result.Reset)
+	c.Header(HeaderLimit, strconv.FormatInt(result.Limit, 10))
+	c.Header(HeaderRemaining, strconv.FormatInt(result.Remaining, 10))
+	c.Header(HeaderReset, strconv.FormatInt(resetIn, 10))
+	c.Header(HeaderPolicy, fmt.Sprintf("%d;w=%d", rate.Limit, int64(rate.Period.Seconds())))
+
+	if result.Reached {
+		c.Header("Retry-After", strconv.FormatInt(resetIn, 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+		return
+	}
+	c.Next()
+}
+
+// LimitAuthFailures compte les réponses 401 par IP et refuse les requêtes d'une IP qui a épuisé
+// son quota d'échecs. À chaîner avant auth.Middleware : les tokens invalides, refusés par celui-ci,
+// ne sont jamais comptés par Limit.
+func (l *Limiter) LimitAuthFailures(c *gin.Context) {
+	store := l.currentStore()
+	if store == nil {
+		c.Next()
+		return
+	}
+
+	rate := limiter.Rate(l.config.RateLimit.AuthFailures)
+	key := "auth_failures:ip:" + c.ClientIP()
+	result, err := store.Peek(c.Request.Context(), key, rate)
+	if err != nil {
+		log.Printf("⚠️ Rate limit store error, request allowed: %v", err)
+		c.Next()
+		return
+	}
+	if result.Remaining == 0 {
+		c.Header("Retry-After", strconv.FormatInt(resetSeconds(result.Reset), 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed authentication attempts"})
+		return
+	}
+
+	c.Next()
+
+	if c.Writer.Status() == http.StatusUnauthorized {
+		// La requête peut déjà être annulée par le client : l'échec doit être compté quand même
+		if _, err := store.Increment(context.WithoutCancel(c.Request.Context()), key, 1, rate); err != nil {
+			log.Printf("⚠️ Rate limit store error, authentication failure not counted: %v", err)
+		}
+	}
+}
+
+// currentStore retourne le store, ou nil tant que Redis n'est pas connecté
+func (l *Limiter) currentStore() limiter.Store {
+	if store := l.store.Load(); store != nil {
+		return *store
+	}
+	return nil
+}
+
+// connect crée le store Redis en arrière-plan, en réessayant tant que Redis est injoignable :
+// aucune requête n'attend la connexion (elles passent sans limite d'ici là)
+func (l *Limiter) connect() {
+	for {
+		store, err := l.newRedisStore()
+		if err == nil {
+			log.Printf("✅ Rate limit: redis store connected")
+			l.store.Store(&store)
+			return
+		}
+		log.Printf("⚠️ Rate limit: redis unreachable, requests are not limited: %v", err)
+		time.Sleep(reconnectInterval)
+	}
+}
+
+// newRedisStore crée le store Redis ; il charge ses scripts Lua à la création : Redis doit répondre
+func (l *Limiter) newRedisStore() (limiter.Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout+redisIOTimeout)
+	defer cancel()
+	if err := l.client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return redisstore.NewStoreWithOptions(l.client, limiter.StoreOptions{Prefix: l.config.RateLimit.Prefix})
+}
+
+// key identifie le compteur : par groupe, puis par utilisateur authentifié ou à défaut par IP
+func key(c *gin.Context, group *config.RateLimitGroup) string {
+	if identity := auth.FromContext(c); identity != nil {
+		return group.Name + ":user:" + strconv.FormatUint(uint64(identity.UserID), 10)
+	}
+	return group.Name + ":ip:" + c.ClientIP()
+}
+
+// resetSeconds convertit l'instant de réinitialisation (timestamp Unix) en secondes restantes
+func resetSeconds(reset int64) int64 {
+	remaining := time.Until(time.Unix(reset, 0)).Seconds()
+	return int64(math.Max(0, math.Ceil(remaining)))
+}
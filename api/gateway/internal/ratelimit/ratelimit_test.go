@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api/gateway/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestLimiter crée un limiteur sur une configuration minimale complétée par rateLimit (section rate_limit)
+func newTestLimiter(t *testing.T, rateLimit string) *Limiter {
+	t.Helper()
+	cfg, err := config.Parse([]byte("upstreams:\n  auth:\n    url: http://auth-service:8081\nroutes:\n  - prefix: /\n    upstream: auth\nauth:\n  upstream: auth\nrate_limit:\n" + rateLimit))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return l
+}
+
+func TestLimitDoesNotWaitForRedis(t *testing.T) {
+	// Redis injoignable : la connexion TCP est acceptée mais rien ne répond jamais
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	l := newTestLimiter(t, "  redis_url: redis://"+listener.Addr().String()+"\n  default: 1-M\n")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", l.Limit, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want %d (fail open)", i, w.Code, http.StatusNoContent)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > redisIOTimeout {
+		t.Fatalf("requests took %v while redis is unreachable, want no wait", elapsed)
+	}
+}
+
+func TestLimitAuthFailures(t *testing.T) {
+	l := newTestLimiter(t, "  auth_failures: 3-M\n")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies([]string{"192.0.2.1"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	r.GET("/", l.LimitAuthFailures, func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer valid" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(clientIP, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", clientIP)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := do("203.0.113.7", "valid"); w.Code != http.StatusNoContent {
+			t.Fatalf("successful request %d: status = %d, want %d", i, w.Code, http.StatusNoContent)
+		}
+		if w := do("203.0.113.7", "bogus"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failed request %d: status = %d, want %d", i, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	w := do("203.0.113.7", "valid")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after 3 failures: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("after 3 failures: missing Retry-After")
+	}
+	if w := do("198.51.100.1", "bogus"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("other client: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
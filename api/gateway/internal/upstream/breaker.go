@@ -0,0 +1,179 @@
+package upstream
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// State est l'état d'un disjoncteur
+type State int
+
+const (
+	// StateClosed : les requêtes passent, les échecs consécutifs sont comptés
+	StateClosed State = iota
+	// StateOpen : les requêtes sont refusées jusqu'à la fin du délai d'ouverture
+	StateOpen
+	// StateHalfOpen : une seule requête test passe ; son résultat ferme ou rouvre le disjoncteur
+	StateHalfOpen
+)
+
+// String implémente fmt.Stringer (valeur exposée par l'endpoint de statut)
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// halfOpenRetryAfter est le délai suggéré aux requêtes refusées pendant la requête test
+const halfOpenRetryAfter = time.Second
+
+// CircuitOpenError est renvoyée, sans appel réseau, quand le disjoncteur d'un upstream est ouvert
+type CircuitOpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+// Error implémente error
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("upstream %s: circuit open", e.Upstream)
+}
+
+// RetryAfterSeconds formate le délai pour l'en-tête Retry-After (au moins une seconde)
+func (e *CircuitOpenError) RetryAfterSeconds() string {
+	seconds := int64((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprint(seconds)
+}
+
+// Breaker est le disjoncteur d'un upstream. Les échecs sont les erreurs réseau et les réponses
+// 502, 503 et 504 ; les vérifications de santé y contribuent aussi, ce qui ouvre le disjoncteur
+// d'un upstream tombé même sans trafic et accélère sa reprise.
+type Breaker struct {
+	upstream    string
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker crée un disjoncteur fermé
+func NewBreaker(upstream string, threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{upstream: upstream, threshold: threshold, openTimeout: openTimeout}
+}
+
+// Allow indique si une requête peut partir ; sinon l'erreur porte le délai avant nouvel essai.
+// Chaque requête autorisée doit être suivie de Success, Failure ou Cancel.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		remaining := time.Until(b.openedAt.Add(b.openTimeout))
+		if remaining > 0 {
+			return &CircuitOpenError{Upstream: b.upstream, RetryAfter: remaining}
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+	case StateHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{Upstream: b.upstream, RetryAfter: halfOpenRetryAfter}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Success enregistre une requête réussie : la requête test referme le disjoncteur
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		b.failures, b.probing = 0, false
+		b.setState(StateClosed)
+	}
+	// Ouvert : réponse d'une requête partie avant l'ouverture, ignorée
+}
+
+// Failure enregistre un échec : ouvre le disjoncteur au seuil, ou le rouvre après la requête test
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.failures++
+		b.open()
+	}
+}
+
+// Cancel libère une requête autorisée dont le résultat n'est pas significatif (client parti)
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+// HealthCheckPassed enregistre une vérification de santé réussie : un disjoncteur ouvert passe
+// en semi-ouvert sans attendre la fin du délai, un disjoncteur semi-ouvert se referme
+func (b *Breaker) HealthCheckPassed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateOpen:
+		b.probing = false
+		b.setState(StateHalfOpen)
+	case StateHalfOpen:
+		b.failures, b.probing = 0, false
+		b.setState(StateClosed)
+	}
+}
+
+// Snapshot retourne l'état, le nombre d'échecs consécutifs et, si ouvert, le délai restant
+func (b *Breaker) Snapshot() (State, int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var retryAfter time.Duration
+	if b.state == StateOpen {
+		retryAfter = max(0, time.Until(b.openedAt.Add(b.openTimeout)))
+	}
+	return b.state, b.failures, retryAfter
+}
+
+// open ouvre le disjoncteur (verrou détenu)
+func (b *Breaker) open() {
+	b.openedAt, b.probing = time.Now(), false
+	b.setState(StateOpen)
+}
+
+// setState change d'état et journalise la transition (verrou détenu)
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	log.Printf("🔌 Circuit breaker %s: %s → %s", b.upstream, b.state, state)
+	b.state = state
+}
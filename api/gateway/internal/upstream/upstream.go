@@ -0,0 +1,254 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"api/gateway/internal/config"
+)
+
+// Pool regroupe, pour chaque upstream, son pool de connexions, son disjoncteur et l'état de
+// ses vérifications de santé. Le proxy et le validateur de tokens partagent ainsi le même
+// disjoncteur pour un même service.
+type Pool struct {
+	upstreams map[string]*Upstream
+}
+
+// Upstream est l'état d'exécution d'un service en aval
+type Upstream struct {
+	config    *config.Upstream
+	transport *transport
+	breaker   *Breaker
+	health    *http.Client
+
+	mu        sync.RWMutex
+	healthy   bool
+	lastCheck time.Time
+}
+
+// Status est l'état d'un upstream exposé par l'endpoint de statut de la gateway
+type Status struct {
+	Name string `json:"name"`
+	// Healthy est le résultat de la dernière vérification de santé (faux avant la première)
+	Healthy   bool       `json:"healthy"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	Circuit   string     `json:"circuit"`
+	// ConsecutiveFailures compte les échecs depuis le dernier succès
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// RetryAfter : secondes avant la requête test, si le disjoncteur est ouvert
+	RetryAfter int64 `json:"retry_after,omitempty"`
+}
+
+// New crée l'état des upstreams utilisés par une route ou par l'authentification ; les autres
+// (services déclarés mais pas encore routés) ne sont pas vérifiés
+func New(cfg *config.Config) *Pool {
+	used := map[string]bool{cfg.Auth.Upstream: true}
+	for _, route := range cfg.Routes {
+		used[route.Upstream] = true
+	}
+
+	p := &Pool{upstreams: make(map[string]*Upstream, len(used))}
+	for name, upstream := range cfg.Upstreams {
+		if !used[name] {
+			continue
+		}
+		base := newTransport(upstream)
+		breaker := NewBreaker(name, upstream.CircuitBreaker.FailureThreshold, time.Duration(upstream.CircuitBreaker.OpenTimeout))
+		p.upstreams[name] = &Upstream{
+			config:    upstream,
+			transport: &transport{base: base, breaker: breaker, retry: upstream.Retry},
+			breaker:   breaker,
+			health:    &http.Client{Transport: base, Timeout: time.Duration(upstream.HealthCheck.Timeout)},
+		}
+	}
+	return p
+}
+
+// Transport retourne le transport d'un upstream : disjoncteur et réémissions inclus
+func (p *Pool) Transport(name string) http.RoundTripper {
+	return p.upstreams[name].transport
+}
+
+// StartHealthChecks vérifie la santé de chaque upstream immédiatement puis à intervalle
+// régulier, jusqu'à l'annulation du contexte
+func (p *Pool) StartHealthChecks(ctx context.Context) {
+	for _, upstream := range p.upstreams {
+		go upstream.runHealthChecks(ctx)
+	}
+}
+
+// Status retourne l'état des upstreams, triés par nom
+func (p *Pool) Status() []Status {
+	statuses := make([]Status, 0, len(p.upstreams))
+	for name, upstream := range p.upstreams {
+		state, failures, retryAfter := upstream.breaker.Snapshot()
+		status := Status{
+			Name:                name,
+			Circuit:             state.String(),
+			ConsecutiveFailures: failures,
+		}
+		if retryAfter > 0 {
+			status.RetryAfter = int64((retryAfter + time.Second - 1) / time.Second)
+		}
+		upstream.mu.RLock()
+		status.Healthy = upstream.healthy
+		if !upstream.lastCheck.IsZero() {
+			lastCheck := upstream.lastCheck
+			status.LastCheck = &lastCheck
+		}
+		upstream.mu.RUnlock()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// runHealthChecks exécute les vérifications de santé d'un upstream
+func (u *Upstream) runHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(u.config.HealthCheck.Interval))
+	defer ticker.Stop()
+	for {
+		u.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth appelle l'endpoint de santé (réponse 2xx attendue) et informe le disjoncteur
+func (u *Upstream) checkHealth(ctx context.Context) {
+	target := *u.config.Target
+	target.Path = u.config.HealthCheck.Path
+
+	healthy := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = u.health.Do(req); err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	u.mu.Lock()
+	changed := healthy != u.healthy || u.lastCheck.IsZero()
+	u.healthy, u.lastCheck = healthy, time.Now()
+	u.mu.Unlock()
+
+	if healthy {
+		u.breaker.HealthCheckPassed()
+	} else {
+		u.breaker.Failure()
+	}
+	if changed {
+		if healthy {
+			log.Printf("✅ Upstream %s healthy", u.config.Name)
+		} else {
+			log.Printf("⚠️ Upstream %s unhealthy: %v", u.config.Name, healthCheckError(err))
+		}
+	}
+}
+
+// healthCheckError décrit l'échec d'une vérification de santé pour les logs
+func healthCheckError(err error) error {
+	if err == nil {
+		return errors.New("non-2xx response")
+	}
+	return err
+}
+
+// newTransport crée le pool de connexions d'un upstream
+func newTransport(upstream *config.Upstream) *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(upstream.ConnectTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ResponseHeaderTimeout: time.Duration(upstream.Timeout),
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+// transport soumet chaque tentative au disjoncteur et réémet les requêtes idempotentes sans corps
+type transport struct {
+	base    http.RoundTripper
+	breaker *Breaker
+	retry   config.Retry
+}
+
+// RoundTrip implémente http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := time.Duration(t.retry.Backoff)
+	for attempt := 1; ; attempt++ {
+		if err := t.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		switch {
+		case err != nil && req.Context().Err() != nil:
+			// Le client a abandonné la requête : ni succès ni échec de l'upstream
+			t.breaker.Cancel()
+			return nil, err
+		case err != nil || unavailable(resp.StatusCode):
+			t.breaker.Failure()
+		default:
+			t.breaker.Success()
+			return resp, nil
+		}
+
+		if attempt >= t.retry.Attempts || !retryable(req, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		// Délai exponentiel, avec une part aléatoire pour étaler les réémissions des réplicas
+		delay := backoff/2 + rand.N(backoff/2+1)
+		backoff *= 2
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// unavailable indique une réponse signalant un upstream indisponible (ou un proxy intermédiaire)
+func unavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// retryable indique si une requête peut être réémise : méthode idempotente, pas de corps à
+// rejouer, et pas de délai de réponse dépassé (la requête peut être encore en cours côté upstream,
+// la réémettre multiplierait l'attente du client)
+func retryable(req *http.Request, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	var netErr net.Error
+	return !(errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,292 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api/gateway/internal/config"
+)
+
+// testUpstream est un service en aval dont le statut des réponses et de /health est piloté par le test
+type testUpstream struct {
+	server  *httptest.Server
+	status  atomic.Int32
+	healthy atomic.Bool
+	hits    atomic.Int32
+	// received, s'il est renseigné, est signalé à chaque requête reçue ; la réponse (statut lu à
+	// la réception) attend release
+	received chan struct{}
+	release  chan struct{}
+}
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	t.Helper()
+	u := &testUpstream{}
+	u.status.Store(http.StatusOK)
+	u.healthy.Store(true)
+	u.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if !u.healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		u.hits.Add(1)
+		status := int(u.status.Load())
+		if u.received != nil {
+			u.received <- struct{}{}
+			<-u.release
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(u.server.Close)
+	return u
+}
+
+// newTestPool crée le pool d'un upstream "svc" avec les sections retry et circuit_breaker données
+func newTestPool(t *testing.T, u *testUpstream, resilience string) *Upstream {
+	t.Helper()
+	cfg, err := config.Parse([]byte("upstreams:\n  svc:\n    url: " + u.server.URL + "\n" + resilience +
+		"routes:\n  - prefix: /\n    upstream: svc\nauth:\n  upstream: svc\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return New(cfg).upstreams["svc"]
+}
+
+// roundTrip envoie une requête par le transport de l'upstream et retourne le statut ou l'erreur
+func (u *Upstream) roundTrip(t *testing.T, method string, body string) (int, error) {
+	t.Helper()
+	var req *http.Request
+	var err error
+	if body == "" {
+		req, err = http.NewRequest(method, u.config.URL+"/items", nil)
+	} else {
+		req, err = http.NewRequest(method, u.config.URL+"/items", strings.NewReader(body))
+	}
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := u.transport.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// assertState vérifie l'état du disjoncteur
+func assertState(t *testing.T, u *Upstream, want State) {
+	t.Helper()
+	if state, _, _ := u.breaker.Snapshot(); state != want {
+		t.Fatalf("circuit = %s, want %s", state, want)
+	}
+}
+
+// assertCircuitOpen vérifie que la requête est refusée sans appel à l'upstream
+func assertCircuitOpen(t *testing.T, u *Upstream, upstream *testUpstream) *CircuitOpenError {
+	t.Helper()
+	hits := upstream.hits.Load()
+	_, err := u.roundTrip(t, http.MethodGet, "")
+	var circuitErr *CircuitOpenError
+	if !errors.As(err, &circuitErr) {
+		t.Fatalf("RoundTrip() error = %v, want CircuitOpenError", err)
+	}
+	if got := upstream.hits.Load(); got != hits {
+		t.Fatalf("upstream hits = %d, want %d (no request while the circuit is open)", got, hits)
+	}
+	return circuitErr
+}
+
+const noRetry = "    retry:\n      attempts: 1\n"
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	upstream := newTestUpstream(t)
+	u := newTestPool(t, upstream, noRetry+"    circuit_breaker:\n      failure_threshold: 3\n      open_timeout: 1m\n")
+
+	upstream.status.Store(http.StatusServiceUnavailable)
+	for i := 1; i <= 3; i++ {
+		if status, err := u.roundTrip(t, http.MethodGet, ""); err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("request %d: RoundTrip() = %d, %v, want %d", i, status, err, http.StatusServiceUnavailable)
+		}
+		want := StateClosed
+		if i == 3 {
+			want = StateOpen
+		}
+		assertState(t, u, want)
+	}
+
+	circuitErr := assertCircuitOpen(t, u, upstream)
+	if got := circuitErr.RetryAfterSeconds(); got != "60" {
+		t.Fatalf("RetryAfterSeconds() = %s, want 60", got)
+	}
+
+	// Un succès remet le compteur à zéro : seuls les échecs consécutifs ouvrent le disjoncteur
+	upstream2 := newTestUpstream(t)
+	u2 := newTestPool(t, upstream2, noRetry+"    circuit_breaker:\n      failure_threshold: 2\n")
+	for _, status := range []int32{http.StatusBadGateway, http.StatusOK, http.StatusGatewayTimeout} {
+		upstream2.status.Store(status)
+		_, _ = u2.roundTrip(t, http.MethodGet, "")
+	}
+	assertState(t, u2, StateClosed)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	const openTimeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// reopen : la requête test échoue
+		reopen bool
+		// healthCheck : le passage en semi-ouvert vient d'une vérification de santé réussie
+		// (délai d'ouverture long), sinon de la fin du délai d'ouverture
+		healthCheck bool
+	}{
+		{name: "probe succeeds after open timeout"},
+		{name: "probe fails after open timeout", reopen: true},
+		{name: "probe succeeds after health check", healthCheck: true},
+		{name: "probe fails after health check", healthCheck: true, reopen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := openTimeout
+			if tt.healthCheck {
+				timeout = time.Minute
+			}
+			upstream := newTestUpstream(t)
+			u := newTestPool(t, upstream, noRetry+"    circuit_breaker:\n      failure_threshold: 1\n      open_timeout: "+timeout.String()+"\n")
+
+			upstream.status.Store(http.StatusServiceUnavailable)
+			_, _ = u.roundTrip(t, http.MethodGet, "")
+			assertState(t, u, StateOpen)
+			assertCircuitOpen(t, u, upstream)
+
+			if tt.healthCheck {
+				u.checkHealth(context.Background())
+				assertState(t, u, StateHalfOpen)
+			} else {
+				time.Sleep(openTimeout + 10*time.Millisecond)
+			}
+
+			// Une seule requête test : elle est retenue par l'upstream pendant qu'une autre est tentée
+			upstream.received, upstream.release = make(chan struct{}), make(chan struct{})
+			if tt.reopen {
+				upstream.status.Store(http.StatusServiceUnavailable)
+			} else {
+				upstream.status.Store(http.StatusOK)
+			}
+			probe := make(chan error, 1)
+			go func() {
+				_, err := u.roundTrip(t, http.MethodGet, "")
+				probe <- err
+			}()
+			<-upstream.received
+			assertState(t, u, StateHalfOpen)
+			if got := assertCircuitOpen(t, u, upstream).RetryAfter; got != halfOpenRetryAfter {
+				t.Fatalf("RetryAfter during probe = %v, want %v", got, halfOpenRetryAfter)
+			}
+			close(upstream.release)
+			if err := <-probe; err != nil {
+				t.Fatalf("probe: RoundTrip() error = %v", err)
+			}
+			upstream.received = nil
+
+			if tt.reopen {
+				assertState(t, u, StateOpen)
+				assertCircuitOpen(t, u, upstream)
+				return
+			}
+			assertState(t, u, StateClosed)
+			if status, err := u.roundTrip(t, http.MethodGet, ""); err != nil || status != http.StatusOK {
+				t.Fatalf("after probe: RoundTrip() = %d, %v, want %d", status, err, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestBreakerHealthCheck(t *testing.T) {
+	upstream := newTestUpstream(t)
+	u := newTestPool(t, upstream, "    circuit_breaker:\n      failure_threshold: 2\n      open_timeout: 1m\n")
+
+	// Les vérifications de santé ouvrent le disjoncteur d'un upstream tombé, même sans trafic
+	upstream.healthy.Store(false)
+	u.checkHealth(context.Background())
+	assertState(t, u, StateClosed)
+	u.checkHealth(context.Background())
+	assertState(t, u, StateOpen)
+
+	// Reprise : semi-ouvert à la première vérification réussie, fermé à la suivante
+	upstream.healthy.Store(true)
+	u.checkHealth(context.Background())
+	assertState(t, u, StateHalfOpen)
+	u.checkHealth(context.Background())
+	assertState(t, u, StateClosed)
+	if upstream.hits.Load() != 0 {
+		t.Fatalf("upstream hits = %d, want 0", upstream.hits.Load())
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		method   string
+		body     string
+		wantHits int32
+	}{
+		{method: http.MethodGet, wantHits: 3},
+		{method: http.MethodHead, wantHits: 3},
+		{method: http.MethodOptions, wantHits: 3},
+		{method: http.MethodPut, wantHits: 3},
+		{method: http.MethodDelete, wantHits: 3},
+		{method: http.MethodPut, body: `{"name":"a"}`, wantHits: 1},
+		{method: http.MethodDelete, body: `{"id":1}`, wantHits: 1},
+		{method: http.MethodPost, wantHits: 1},
+		{method: http.MethodPost, body: `{"name":"a"}`, wantHits: 1},
+		{method: http.MethodPatch, body: `{"name":"a"}`, wantHits: 1},
+	}
+
+	for _, tt := range tests {
+		name := tt.method
+		if tt.body != "" {
+			name += " with body"
+		}
+		t.Run(name, func(t *testing.T) {
+			upstream := newTestUpstream(t)
+			u := newTestPool(t, upstream, "    retry:\n      attempts: 3\n      backoff: 1ms\n    circuit_breaker:\n      failure_threshold: 10\n")
+
+			upstream.status.Store(http.StatusServiceUnavailable)
+			status, err := u.roundTrip(t, tt.method, tt.body)
+			if err != nil || status != http.StatusServiceUnavailable {
+				t.Fatalf("RoundTrip() = %d, %v, want %d", status, err, http.StatusServiceUnavailable)
+			}
+			if got := upstream.hits.Load(); got != tt.wantHits {
+				t.Fatalf("upstream hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+
+	// Une réémission qui aboutit renvoie la réponse de l'upstream rétabli
+	upstream := newTestUpstream(t)
+	u := newTestPool(t, upstream, "    retry:\n      attempts: 3\n      backoff: 1ms\n")
+	upstream.status.Store(http.StatusBadGateway)
+	upstream.received, upstream.release = make(chan struct{}), make(chan struct{})
+	go func() {
+		<-upstream.received
+		upstream.status.Store(http.StatusOK)
+		upstream.release <- struct{}{}
+		<-upstream.received
+		upstream.release <- struct{}{}
+	}()
+	if status, err := u.roundTrip(t, http.MethodGet, ""); err != nil || status != http.StatusOK {
+		t.Fatalf("RoundTrip() = %d, %v, want %d", status, err, http.StatusOK)
+	}
+	if got := upstream.hits.Load(); got != 2 {
+		t.Fatalf("upstream hits = %d, want 2", got)
+	}
+}
@@ -47,9 +47,252 @@ CREATE TABLE IF NOT EXISTS auth.users (
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     last_login_at TIMESTAMP,
-    is_active BOOLEAN NOT NULL DEFAULT true
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    email_verified_at TIMESTAMP,
+    totp_secret VARCHAR(64),
+    totp_enabled_at TIMESTAMP
 );
 
+-- Migration des bases existantes : vérification d'email
+ALTER TABLE auth.users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Migration des bases existantes : double authentification TOTP
+ALTER TABLE auth.users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE auth.users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+
+-- Codes de récupération 2FA (hash SHA-256, usage unique)
+CREATE TABLE IF NOT EXISTS auth.recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_recovery_codes_user ON auth.recovery_codes (user_id);
+
+-- RBAC : rôles, permissions et attributions
+-- Les rôles système 'user' et 'admin' correspondent à la colonne users.role
+CREATE TABLE IF NOT EXISTS auth.roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    is_system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Permissions au format "ressource:action", embarquées comme scopes dans l'access token
+CREATE TABLE IF NOT EXISTS auth.permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS auth.role_permissions (
+    role_id INTEGER NOT NULL REFERENCES auth.roles (id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES auth.permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- Rôles supplémentaires d'un utilisateur (en plus de users.role)
+CREATE TABLE IF NOT EXISTS auth.user_roles (
+    user_id INTEGER NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES auth.roles (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_user_roles_role ON auth.user_roles (role_id);
+
+DROP TRIGGER IF EXISTS update_auth_roles_updated_at ON auth.roles;
+
+CREATE TRIGGER update_auth_roles_updated_at
+    BEFORE UPDATE ON auth.roles
+    FOR EACH ROW EXECUTE FUNCTION auth.update_updated_at_column();
+
+-- Rôles et permissions par défaut
+INSERT INTO
+    auth.roles (name, description, is_system)
+VALUES
+    ('user', 'Utilisateur standard', true),
+    ('admin', 'Administrateur', true)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+    auth.permissions (name, description)
+VALUES
+    ('properties:read', 'Consulter les biens'),
+    ('properties:write', 'Créer et modifier les biens'),
+    ('tenants:read', 'Consulter les locataires'),
+    ('tenants:write', 'Créer et modifier les locataires'),
+    ('users:read', 'Consulter les utilisateurs'),
+    ('users:write', 'Administrer les utilisateurs'),
+    ('roles:read', 'Consulter les rôles et permissions'),
+    ('roles:write', 'Administrer les rôles et permissions'),
+    ('audit:read', 'Consulter et exporter le journal d''audit'),
+    ('clients:read', 'Consulter les clients OpenID Connect'),
+    ('clients:write', 'Enregistrer et supprimer les clients OpenID Connect'),
+    ('users:impersonate', 'Se connecter en tant qu''un utilisateur (support)')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+    auth.role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM auth.roles r
+    JOIN auth.permissions p ON (
+        r.name = 'admin'
+        OR p.name IN ('properties:read', 'properties:write', 'tenants:read', 'tenants:write')
+    )
+WHERE r.name IN ('user', 'admin')
+ON CONFLICT DO NOTHING;
+
+-- Organisations (agences) : les membres partagent le même portefeuille
+CREATE TABLE IF NOT EXISTS auth.organisations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_auth_organisations_updated_at ON auth.organisations;
+
+CREATE TRIGGER update_auth_organisations_updated_at
+    BEFORE UPDATE ON auth.organisations
+    FOR EACH ROW EXECUTE FUNCTION auth.update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS auth.organisation_members (
+    organisation_id INTEGER NOT NULL REFERENCES auth.organisations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (
+        role IN ('owner', 'admin', 'member')
+    ),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_organisation_members_user ON auth.organisation_members (user_id);
+
+-- Invitations par email (hash SHA-256 du token, usage unique)
+CREATE TABLE IF NOT EXISTS auth.organisation_invitations (
+    id SERIAL PRIMARY KEY,
+    organisation_id INTEGER NOT NULL REFERENCES auth.organisations (id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (
+        role IN ('owner', 'admin', 'member')
+    ),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES auth.users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_organisation_invitations_org ON auth.organisation_invitations (organisation_id);
+
+-- Journal d'audit des événements de sécurité (append-only)
+-- actor_id sans clé étrangère : l'historique survit à la suppression des utilisateurs
+CREATE TABLE IF NOT EXISTS auth.audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor_id INTEGER,
+    actor_email VARCHAR(255),
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id VARCHAR(64),
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    outcome VARCHAR(16) NOT NULL CHECK (
+        outcome IN ('success', 'failure', 'denied')
+    ),
+    metadata JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_logs_occurred_at ON auth.audit_logs (occurred_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_logs_actor ON auth.audit_logs (actor_id, occurred_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_logs_action ON auth.audit_logs (action, occurred_at DESC);
+
+-- Administrateur réel des requêtes effectuées avec un token d'impersonation
+ALTER TABLE auth.audit_logs ADD COLUMN IF NOT EXISTS impersonator_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_logs_impersonator ON auth.audit_logs (impersonator_id, occurred_at DESC) WHERE impersonator_id IS NOT NULL;
+
+-- Les événements ne peuvent être ni modifiés ni supprimés
+CREATE OR REPLACE FUNCTION auth.prevent_audit_log_mutation()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'auth.audit_logs is append-only (% refused)', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS prevent_auth_audit_logs_mutation ON auth.audit_logs;
+
+CREATE TRIGGER prevent_auth_audit_logs_mutation
+    BEFORE UPDATE OR DELETE ON auth.audit_logs
+    FOR EACH ROW EXECUTE FUNCTION auth.prevent_audit_log_mutation();
+
+DROP TRIGGER IF EXISTS prevent_auth_audit_logs_truncate ON auth.audit_logs;
+
+CREATE TRIGGER prevent_auth_audit_logs_truncate
+    BEFORE TRUNCATE ON auth.audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION auth.prevent_audit_log_mutation();
+
+-- Clients OpenID Connect (applications mobiles, outils tiers) ; secret_hash vide = client public (PKCE)
+CREATE TABLE IF NOT EXISTS auth.oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARCHAR(64),
+    redirect_uris JSONB NOT NULL DEFAULT '[]',
+    scopes JSONB NOT NULL DEFAULT '[]',
+    created_by INTEGER REFERENCES auth.users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_auth_oauth_clients_updated_at ON auth.oauth_clients;
+
+CREATE TRIGGER update_auth_oauth_clients_updated_at
+    BEFORE UPDATE ON auth.oauth_clients
+    FOR EACH ROW EXECUTE FUNCTION auth.update_updated_at_column();
+
+-- Identités externes (Google, Microsoft, ...) liées aux comptes : (provider, subject) identifie l'utilisateur chez le fournisseur
+CREATE TABLE IF NOT EXISTS auth.user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    CONSTRAINT idx_user_identities_provider_subject UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON auth.user_identities (user_id);
+
+-- Tokens d'API (personal access tokens) des intégrations : seul le hash SHA-256 est stocké
+CREATE TABLE IF NOT EXISTS auth.personal_access_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    organisation_id INTEGER REFERENCES auth.organisations (id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON auth.personal_access_tokens (user_id);
+
+-- Organisation active de l'utilisateur (claim "org_id")
+ALTER TABLE auth.users ADD COLUMN IF NOT EXISTS organisation_id INTEGER REFERENCES auth.organisations (id) ON DELETE SET NULL;
+
 CREATE UNIQUE INDEX IF NOT EXISTS idx_auth_users_email ON auth.users (LOWER(email));
 -- Index insensibilisé (optionnel)
 
@@ -60,6 +303,7 @@ CREATE TRIGGER update_auth_users_updated_at
     FOR EACH ROW EXECUTE FUNCTION auth.update_updated_at_column();
 
 -- Insertion d'un utilisateur admin par défaut (si pas déjà présent)
+-- Hash bcrypt (pgcrypto) : converti en Argon2id par l'auth service à la première connexion
 INSERT INTO
     auth.users (
         company,
@@ -67,7 +311,8 @@ INSERT INTO
         lastname,
         email,
         password_hash,
-        role
+        role,
+        email_verified_at
     )
 VALUES (
         'Immobilier System',
@@ -75,5 +320,20 @@ VALUES (
         'System',
         'admin@immobilier.local',
         crypt ('admin123', gen_salt ('bf')),
-        'admin'
-    ) ON CONFLICT (email) DO NOTHING;
\ No newline at end of file
+        'admin',
+        CURRENT_TIMESTAMP
+    ) ON CONFLICT (email) DO NOTHING;
+
+-- Migration des bases existantes : une organisation par utilisateur sans organisation
+-- (la colonne company n'identifie pas une agence de façon fiable, on ne fusionne pas)
+DO $$
+DECLARE
+    u RECORD;
+    new_org_id INTEGER;
+BEGIN
+    FOR u IN SELECT id, company FROM auth.users WHERE organisation_id IS NULL LOOP
+        INSERT INTO auth.organisations (name) VALUES (u.company) RETURNING id INTO new_org_id;
+        INSERT INTO auth.organisation_members (organisation_id, user_id, role) VALUES (new_org_id, u.id, 'owner');
+        UPDATE auth.users SET organisation_id = new_org_id WHERE id = u.id;
+    END LOOP;
+END $$;
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"api/services/auth/internal/audit"
 	"api/services/auth/internal/database"
+	autherrors "api/services/auth/internal/errors"
+	"api/services/auth/internal/mail"
 	model "api/services/auth/internal/models"
 	"api/services/auth/internal/repository"
 	"api/services/auth/internal/services"
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,7 +26,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterRequest represents the expected request structure for user registration
@@ -26,7 +34,10 @@ type RegisterRequest struct {
 	Lastname  string `json:"lastname" binding:"required"`
 	Firstname string `json:"firstname" binding:"required"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"`
+	// InvitationToken rattache le compte à l'organisation qui a envoyé l'invitation
+	// (sinon une organisation est créée à partir de Company)
+	InvitationToken string `json:"invitation_token"`
 }
 
 // LoginRequest represents the expected request structure for user login
@@ -40,6 +51,206 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// ForgotPasswordRequest represents the request structure for a password reset demand
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the request structure for a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// VerifyEmailRequest represents the request structure for email verification
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents the request structure to resend a verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Politiques de vérification d'email (EMAIL_VERIFICATION_POLICY)
+const (
+	// EmailVerificationBlock refuse la connexion tant que l'email n'est pas vérifié
+	EmailVerificationBlock = "block"
+	// EmailVerificationLimited délivre des tokens avec le claim email_verified=false et sans
+	// les permissions d'écriture (seules les permissions "*:read" sont conservées, voir tokenSubject) ;
+	// les endpoints sensibles exigent un email vérifié (requireVerifiedEmail)
+	EmailVerificationLimited = "limited"
+	// EmailVerificationOff désactive la vérification
+	EmailVerificationOff = "off"
+)
+
+// LoginTwoFactorRequest represents the second step of a login with 2FA enabled
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request structure to disable 2FA
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// Paramètres de la double authentification
+const (
+	recoveryCodeCount       = 10
+	pendingTOTPSecretTTL    = 10 * time.Minute
+	maxMFAChallengeFailures = 5
+)
+
+// RoleRequest represents the request structure to create or update a role
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionRequest represents the request structure to create a permission
+type PermissionRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// UserRolesRequest represents the request structure to replace the roles of a user
+type UserRolesRequest struct {
+	RoleIDs []uint `json:"role_ids"`
+}
+
+// UpdateUserRequest represents the request structure for an admin update of a user
+type UpdateUserRequest struct {
+	Company   string `json:"company" binding:"omitempty,max=100"`
+	Lastname  string `json:"lastname" binding:"omitempty,max=100"`
+	Firstname string `json:"firstname" binding:"omitempty,max=100"`
+	Role      string `json:"role" binding:"omitempty,oneof=user admin"`
+}
+
+// UpdateProfileRequest represents the request structure for a self-service profile update
+type UpdateProfileRequest struct {
+	Company   string `json:"company" binding:"omitempty,max=100"`
+	Lastname  string `json:"lastname" binding:"omitempty,max=100"`
+	Firstname string `json:"firstname" binding:"omitempty,max=100"`
+}
+
+// ChangePasswordRequest represents the request structure to change the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// InvitationRequest represents the request structure to invite someone into the current organisation
+type InvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+// AcceptInvitationRequest represents the request structure to accept an organisation invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ValidatePasswordRequest represents the request structure to evaluate a password against the policy
+type ValidatePasswordRequest struct {
+	Password  string `json:"password" binding:"required"`
+	Email     string `json:"email"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+}
+
+// PersonalAccessTokenRequest represents the request structure to create an API token
+type PersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+// ImpersonateRequest represents the optional request structure to impersonate a user
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// SSOCompleteRequest represents the request structure to exchange an external login code for tokens
+type SSOCompleteRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// AuthorizeRequest represents an OpenID Connect authorization request (query string on GET, JSON on POST)
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope" binding:"required"`
+	State               string `form:"state" json:"state"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	// Deny : l'utilisateur a refusé l'autorisation sur la page de consentement (POST uniquement)
+	Deny bool `form:"-" json:"deny"`
+}
+
+// OAuthClientRequest represents the request structure to register an OpenID Connect client
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,required"`
+	Scopes       []string `json:"scopes"`
+	// Confidential génère un client_secret (applications serveur) ; sinon le client est public (mobile, SPA)
+	Confidential bool `json:"confidential"`
+}
+
+// SwitchOrganisationRequest represents the request structure to change the active organisation
+type SwitchOrganisationRequest struct {
+	OrganisationID uint `json:"organisation_id" binding:"required"`
+}
+
+// Permissions requises par les endpoints d'administration RBAC
+const (
+	PermissionRolesRead  = "roles:read"
+	PermissionRolesWrite = "roles:write"
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+	PermissionAuditRead  = "audit:read"
+
+	PermissionClientsRead  = "clients:read"
+	PermissionClientsWrite = "clients:write"
+
+	PermissionUsersImpersonate = "users:impersonate"
+)
+
+// adminPermissions sont les permissions d'administration : un compte qui en détient une ne peut
+// pas être impersonné (le token porterait ses permissions sous une autre identité)
+var adminPermissions = []string{
+	PermissionRolesRead, PermissionRolesWrite,
+	PermissionUsersRead, PermissionUsersWrite, PermissionUsersImpersonate,
+	PermissionAuditRead,
+	PermissionClientsRead, PermissionClientsWrite,
+}
+
+// Pagination de GET /users
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// Pagination et export de GET /audit-logs
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	auditExportBatchSize = 1000
+	maxAuditExportRows   = 100000
+)
+
+// permissionNamePattern impose le format "ressource:action" (ex. "properties:write")
+var permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[a-z*][a-z0-9_*-]*$`)
+
 // RegisterResponse represents the response structure for registration endpoint
 type RegisterResponse struct {
 	Status  string      `json:"status"`
@@ -47,6 +258,28 @@ type RegisterResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// issuedTokens est une paire de tokens émise pour une session
+type issuedTokens struct {
+	AccessToken  string
+	RefreshToken string
+	TokenID      string
+	FamilyID     string
+	AccessExp    int64
+	RefreshExp   int64
+	// Scope liste les scopes accordés, pour les tokens d'un client OpenID Connect
+	Scope string
+}
+
+// refreshError est un refus de rotation d'un refresh token (statut HTTP et message client)
+type refreshError struct {
+	Status  int
+	Message string
+}
+
+func (e *refreshError) Error() string {
+	return e.Message
+}
+
 // TokenResponse represents the JWT token response
 type TokenResponse struct {
 	AccessToken  string      `json:"access_token"`
@@ -100,19 +333,403 @@ func main() {
 	sugar.Info("Connected to Redis successfully")
 
 	// Initialiser les services JWT
-	accessTokenTTL := 15 * time.Minute
-	refreshTokenTTL := 7 * 24 * time.Hour // 7 jours
+	jwtConfig := services.DefaultJWTConfig()
+	jwtConfig.Issuer = getEnv("JWT_ISSUER", jwtConfig.Issuer)
+	jwtConfig.Audience = getEnv("JWT_AUDIENCE", jwtConfig.Audience)
+	jwtConfig.AccessTokenTTL = getDurationEnv("JWT_ACCESS_TTL", jwtConfig.AccessTokenTTL)
+	jwtConfig.RefreshTokenTTL = getDurationEnv("JWT_REFRESH_TTL", jwtConfig.RefreshTokenTTL)
+	jwtConfig.ClockSkew = getDurationEnv("JWT_CLOCK_SKEW", jwtConfig.ClockSkew)
+
+	keyring, err := services.LoadKeyringFromEnv()
+	if err != nil {
+		sugar.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
 
-	jwtService, err := services.NewJWTService(accessTokenTTL, refreshTokenTTL)
+	jwtService, err := services.NewJWTService(keyring, jwtConfig)
 	if err != nil {
 		sugar.Fatalf("Failed to initialize JWT service: %v", err)
 	}
-	sugar.Info("JWT service initialized successfully")
+	sugar.Infof("JWT service initialized successfully (alg: %s)", jwtService.SigningAlgorithm())
+
+	// Hachage des mots de passe : Argon2id par défaut, les hashs bcrypt existants restent valides
+	// et sont mis à niveau à la connexion
+	passwordConfig := services.DefaultPasswordHasherConfig()
+	passwordConfig.Algorithm = getEnv("PASSWORD_HASH_ALGORITHM", passwordConfig.Algorithm)
+	passwordConfig.BcryptCost = getIntEnv("PASSWORD_BCRYPT_COST", passwordConfig.BcryptCost)
+	passwordConfig.Argon2id.Memory = uint32(getIntEnv("PASSWORD_ARGON2_MEMORY_KIB", int(passwordConfig.Argon2id.Memory)))
+	passwordConfig.Argon2id.Iterations = uint32(getIntEnv("PASSWORD_ARGON2_ITERATIONS", int(passwordConfig.Argon2id.Iterations)))
+	passwordConfig.Argon2id.Parallelism = uint8(getIntEnv("PASSWORD_ARGON2_PARALLELISM", int(passwordConfig.Argon2id.Parallelism)))
+
+	passwordHasher, err := services.NewPasswordHasher(passwordConfig)
+	if err != nil {
+		sugar.Fatalf("Failed to initialize password hasher: %v", err)
+	}
+	sugar.Infof("Password hasher initialized (alg: %s)", passwordConfig.Algorithm)
+
+	// Fournisseur OpenID Connect : l'issuer est l'URL publique de l'auth service (derrière la gateway)
+	oidcIssuer := strings.TrimRight(getEnv("OIDC_ISSUER", "http://localhost:8080/api/v1/auth"), "/")
+	oidcCodeTTL := getDurationEnv("OIDC_CODE_TTL", time.Minute)
+	if strings.HasPrefix(jwtService.SigningAlgorithm(), "HS") {
+		sugar.Warn("OIDC ID tokens are signed with a shared secret: configure RS256 or EdDSA keys so that clients can verify them")
+	}
+
+	// Connexion via des fournisseurs d'identité externes (Google, Microsoft, IdP de test local) :
+	// les callbacks sont servis sous l'URL publique de l'auth service
+	ssoConfigs, err := services.LoadExternalProvidersFromEnv(oidcIssuer)
+	if err != nil {
+		sugar.Fatalf("Invalid SSO configuration: %v", err)
+	}
+	ssoProviders, err := services.NewExternalProviders(ssoConfigs, nil)
+	if err != nil {
+		sugar.Fatalf("Failed to initialize SSO providers: %v", err)
+	}
+	for _, provider := range ssoProviders.List() {
+		sugar.Infof("SSO provider enabled: %s (%s)", provider.Config().Name, provider.Config().Issuer)
+	}
+	ssoStateTTL := getDurationEnv("SSO_STATE_TTL", 10*time.Minute)
+	ssoLoginCodeTTL := getDurationEnv("SSO_LOGIN_CODE_TTL", time.Minute)
+
+	// Politique de mot de passe (inscription, changement et reset)
+	policyConfig := services.DefaultPasswordPolicyConfig()
+	policyConfig.MinLength = getIntEnv("PASSWORD_MIN_LENGTH", policyConfig.MinLength)
+	policyConfig.MaxLength = getIntEnv("PASSWORD_MAX_LENGTH", policyConfig.MaxLength)
+	policyConfig.RequireLower = getBoolEnv("PASSWORD_REQUIRE_LOWERCASE", policyConfig.RequireLower)
+	policyConfig.RequireUpper = getBoolEnv("PASSWORD_REQUIRE_UPPERCASE", policyConfig.RequireUpper)
+	policyConfig.RequireDigit = getBoolEnv("PASSWORD_REQUIRE_DIGIT", policyConfig.RequireDigit)
+	policyConfig.RequireSymbol = getBoolEnv("PASSWORD_REQUIRE_SYMBOL", policyConfig.RequireSymbol)
+	policyConfig.ForbidPersonalInfo = getBoolEnv("PASSWORD_FORBID_PERSONAL_INFO", policyConfig.ForbidPersonalInfo)
+
+	// Liste hors ligne des mots de passe compromis (fichier de hashs SHA-1 ou répertoire de plages HIBP)
+	var breachedPasswords services.BreachedPasswordChecker
+	if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+		list, err := services.LoadBreachedPasswordList(path)
+		if err != nil {
+			sugar.Fatalf("Failed to load breached password list: %v", err)
+		}
+		breachedPasswords = list
+		sugar.Infof("Breached password list loaded from %s", path)
+	}
+
+	passwordPolicy, err := services.NewPasswordPolicy(policyConfig, breachedPasswords)
+	if err != nil {
+		sugar.Fatalf("Invalid password policy: %v", err)
+	}
+
+	// Envoi d'emails (reset de mot de passe, ...)
+	mailSender, err := mail.NewSenderFromEnv(sugar)
+	if err != nil {
+		sugar.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+	appBaseURL := strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:4200"), "/")
+	passwordResetTTL := getDurationEnv("PASSWORD_RESET_TTL", 30*time.Minute)
+	emailVerificationTTL := getDurationEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	mfaChallengeTTL := getDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute)
+	totpIssuer := getEnv("TOTP_ISSUER", "Immogestion")
+	invitationTTL := getDurationEnv("ORGANISATION_INVITATION_TTL", 7*24*time.Hour)
+	apiTokenDefaultTTL := getDurationEnv("API_TOKEN_DEFAULT_TTL", 90*24*time.Hour)
+	apiTokenMaxTTL := getDurationEnv("API_TOKEN_MAX_TTL", 365*24*time.Hour)
+	apiTokenMaxPerUser := int64(getIntEnv("API_TOKEN_MAX_PER_USER", 20))
+	impersonationTTL := getDurationEnv("IMPERSONATION_TTL", 10*time.Minute)
+
+	emailVerificationPolicy := strings.ToLower(getEnv("EMAIL_VERIFICATION_POLICY", EmailVerificationLimited))
+	switch emailVerificationPolicy {
+	case EmailVerificationBlock, EmailVerificationLimited, EmailVerificationOff:
+		sugar.Infof("Email verification policy: %s", emailVerificationPolicy)
+	default:
+		sugar.Fatalf("Invalid EMAIL_VERIFICATION_POLICY %q (expected block, limited or off)", emailVerificationPolicy)
+	}
 
 	// Créer les repositories avec adaptateur
 	userRepo := repository.NewUserRepository(db, sugar)
 	tokenRepo := database.NewRedisTokenRepository(redisClient)
 
+	// Journal d'audit des événements de sécurité (table append-only auth.audit_logs)
+	auditLog := audit.NewLogger(userRepo, sugar)
+
+	// Protection brute-force sur /login (compteurs par compte et par IP dans Redis)
+	loginGuard := services.NewLoginGuard(database.NewRedisLoginAttemptRepository(redisClient), services.LoginGuardConfig{
+		MaxAccountFailures: int64(getIntEnv("LOGIN_MAX_ACCOUNT_FAILURES", 5)),
+		MaxIPFailures:      int64(getIntEnv("LOGIN_MAX_IP_FAILURES", 20)),
+		FailureWindow:      getDurationEnv("LOGIN_FAILURE_WINDOW", time.Hour),
+		BaseLockout:        getDurationEnv("LOGIN_LOCKOUT_BASE", time.Minute),
+		MaxLockout:         getDurationEnv("LOGIN_LOCKOUT_MAX", time.Hour),
+	})
+
+	// enforcePasswordPolicy refuse (400 avec le détail des règles non respectées) un mot de passe
+	// non conforme. Une liste des fuites illisible n'empêche pas l'opération.
+	enforcePasswordPolicy := func(c *gin.Context, password string, owner services.PasswordOwner) bool {
+		check, err := passwordPolicy.Check(password, owner)
+		if err != nil {
+			sugar.Errorf("Password policy: %v", err)
+		}
+		if check.Valid {
+			return true
+		}
+		c.JSON(http.StatusBadRequest, RegisterResponse{
+			Status:  "error",
+			Message: "Password does not meet the password policy",
+			Data:    check,
+		})
+		return false
+	}
+
+	// sendVerificationEmail génère un token de vérification et l'envoie à l'utilisateur
+	sendVerificationEmail := func(user *model.User) error {
+		token, tokenHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			return err
+		}
+		if err := tokenRepo.StoreEmailVerificationToken(ctx, user.ID, tokenHash, emailVerificationTTL); err != nil {
+			return err
+		}
+
+		verifyURL := appBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+		return mailSender.Send(ctx, mail.Message{
+			To:      user.Email,
+			Subject: "Confirmez votre adresse email",
+			Body: fmt.Sprintf("Bonjour %s,\n\nMerci pour votre inscription. Confirmez votre adresse email en ouvrant ce lien (valable %s) :\n%s",
+				user.Firstname, emailVerificationTTL, verifyURL),
+		})
+	}
+
+	// sendInvitationEmail envoie le lien d'invitation à rejoindre une organisation
+	sendInvitationEmail := func(org *model.Organisation, inviter *services.AccessClaims, email, token string) error {
+		acceptURL := appBaseURL + "/invitations/accept?token=" + url.QueryEscape(token)
+		return mailSender.Send(ctx, mail.Message{
+			To:      email,
+			Subject: fmt.Sprintf("Invitation à rejoindre %s", org.Name),
+			Body: fmt.Sprintf("Bonjour,\n\n%s vous invite à rejoindre l'agence %s sur Immogestion. Ouvrez ce lien pour accepter l'invitation (valable %s) :\n%s",
+				inviter.Email, org.Name, invitationTTL, acceptURL),
+		})
+	}
+
+	// storeSession enregistre une nouvelle session (refresh token, métadonnées de l'appareil,
+	// famille de rotation) dans Redis
+	storeSession := func(userID uint, tokens *issuedTokens, session *model.Session) {
+		if err := tokenRepo.StoreRefreshToken(ctx, userID, tokens.TokenID, tokens.RefreshToken, tokens.RefreshExp); err != nil {
+			sugar.Errorf("Failed to store refresh token: %v", err)
+		}
+		if err := tokenRepo.StoreSession(ctx, userID, session); err != nil {
+			sugar.Errorf("Failed to store session: %v", err)
+		}
+		if err := tokenRepo.StoreTokenFamily(ctx, userID, tokens.TokenID, tokens.FamilyID, tokens.RefreshExp); err != nil {
+			sugar.Errorf("Failed to store token family: %v", err)
+		}
+	}
+
+	// startSession génère une paire de tokens pour l'utilisateur et enregistre la nouvelle
+	// session (refresh token, métadonnées de l'appareil, famille de rotation) dans Redis
+	startSession := func(c *gin.Context, user *model.User) (*issuedTokens, error) {
+		subject, err := tokenSubject(ctx, userRepo, user, emailVerificationPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user permissions: %w", err)
+		}
+		accessToken, refreshToken, tokenID, accessExp, refreshExp, err := jwtService.GenerateTokenPair(subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		}
+
+		tokens := &issuedTokens{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			TokenID:      tokenID,
+			FamilyID:     tokenID,
+			AccessExp:    accessExp,
+			RefreshExp:   refreshExp,
+		}
+		storeSession(user.ID, tokens, newSession(c, tokenID, refreshExp))
+		return tokens, nil
+	}
+
+	// startClientSession ouvre la session d'un client OpenID Connect : les tokens sont liés au
+	// client et limités aux scopes accordés, la session conserve les deux pour les rotations.
+	// Sans le scope offline_access, seul l'access token est remis et aucune session n'est ouverte.
+	startClientSession := func(c *gin.Context, user *model.User, clientID, scope string) (*issuedTokens, error) {
+		accessToken, refreshToken, tokenID, accessExp, refreshExp, err := jwtService.GenerateClientTokenPair(user.ID, clientID, scope, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		}
+
+		tokens := &issuedTokens{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			TokenID:      tokenID,
+			FamilyID:     tokenID,
+			AccessExp:    accessExp,
+			RefreshExp:   refreshExp,
+			Scope:        scope,
+		}
+		if !slices.Contains(strings.Fields(scope), services.ScopeOfflineAccess) {
+			tokens.RefreshToken = ""
+			return tokens, nil
+		}
+		session := newSession(c, tokenID, refreshExp)
+		session.ClientID, session.Scope = clientID, scope
+		storeSession(user.ID, tokens, session)
+		return tokens, nil
+	}
+
+	// registerLoginFailure comptabilise un échec de connexion (mot de passe ou second facteur) sur
+	// les compteurs du compte et de l'IP, et audite les verrouillages qu'il déclenche
+	registerLoginFailure := func(c *gin.Context, email string, userID uint) {
+		lockouts, err := loginGuard.RecordFailure(ctx, email, c.ClientIP())
+		if err != nil {
+			sugar.Errorf("Failed to record login failure: %v", err)
+		}
+		for _, lockout := range lockouts {
+			lockoutEvent := audit.Event{
+				ActorID:    userID,
+				ActorEmail: email,
+				Action:     audit.ActionLoginLockout,
+				Outcome:    audit.OutcomeDenied,
+				Metadata: map[string]any{
+					"scope":    lockout.Scope,
+					"failures": lockout.Failures,
+					"duration": lockout.Duration.String(),
+				},
+			}
+			if userID != 0 {
+				lockoutEvent.TargetType, lockoutEvent.TargetID = audit.TargetUser, audit.UserTarget(userID)
+			}
+			auditLog.RecordRequest(c, lockoutEvent)
+		}
+	}
+
+	// completeLogin termine une connexion réussie : émission des tokens, session et réponse.
+	// Le compteur d'échecs du compte n'est remis à zéro qu'ici, une fois tous les facteurs validés.
+	completeLogin := func(c *gin.Context, user *model.User) {
+		if err := loginGuard.RecordSuccess(ctx, user.Email); err != nil {
+			sugar.Errorf("Failed to reset login failures: %v", err)
+		}
+
+		// Mettre à jour la dernière connexion
+		now := time.Now()
+		user.LastLoginAt = &now
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to update last login: %v", err)
+		}
+
+		// Générer les tokens JWT et ouvrir la session
+		tokens, err := startSession(c, user)
+		if err != nil {
+			sugar.Errorf("Failed to start session for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to generate authentication tokens",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"session_id": tokens.TokenID, "mfa": user.IsTOTPEnabled()},
+		})
+
+		// Préparer les données utilisateur pour la réponse
+		userData := gin.H{
+			"id":              user.ID,
+			"company":         user.Company,
+			"firstname":       user.Firstname,
+			"lastname":        user.Lastname,
+			"email":           user.Email,
+			"role":            user.Role,
+			"is_active":       user.IsActive,
+			"email_verified":  user.IsEmailVerified(),
+			"organisation_id": user.OrganisationID,
+			"last_login":      now.Format(time.RFC3339),
+		}
+
+		// Réponse avec tokens JWT
+		tokenResponse := TokenResponse{
+			AccessToken:  tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    tokens.AccessExp,
+			User:         userData,
+		}
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Login successful",
+			Data:    tokenResponse,
+		})
+	}
+
+	// startMFAChallenge répond à une connexion dont le premier facteur est validé par un
+	// challenge 2FA à courte durée de vie, à compléter sur /login/2fa
+	startMFAChallenge := func(c *gin.Context, user *model.User) {
+		challenge, challengeHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate 2FA challenge: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to generate 2FA challenge",
+			})
+			return
+		}
+		if err := tokenRepo.StoreMFAChallenge(ctx, user.ID, challengeHash, mfaChallengeTTL); err != nil {
+			sugar.Errorf("Failed to store 2FA challenge: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to generate 2FA challenge",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Two-factor authentication required",
+			Data: gin.H{
+				"mfa_required":    true,
+				"challenge_token": challenge,
+				"expires_in":      int64(mfaChallengeTTL.Seconds()),
+			},
+		})
+	}
+
+	// verifySecondFactor vérifie un code TOTP (avec protection anti-rejeu) ou un code de récupération
+	verifySecondFactor := func(user *model.User, code string) bool {
+		if step, ok := services.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+			fresh, err := tokenRepo.MarkTOTPStepUsed(ctx, user.ID, step)
+			if err != nil {
+				sugar.Errorf("Failed to record TOTP usage: %v", err)
+				return false
+			}
+			return fresh
+		}
+
+		normalized := strings.ToLower(strings.TrimSpace(code))
+		used, err := userRepo.ConsumeRecoveryCode(ctx, user.ID, services.HashOpaqueToken(normalized))
+		if err != nil {
+			sugar.Errorf("Failed to check recovery code: %v", err)
+			return false
+		}
+		if used {
+			sugar.Warnf("Recovery code used: user ID=%d", user.ID)
+		}
+		return used
+	}
+
+	// newRecoveryCodes génère et enregistre un nouveau jeu de codes de récupération
+	newRecoveryCodes := func(userID uint) ([]string, error) {
+		codes, err := services.GenerateRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			return nil, err
+		}
+		hashes := make([]string, 0, len(codes))
+		for _, code := range codes {
+			hashes = append(hashes, services.HashOpaqueToken(code))
+		}
+		if err := userRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+			return nil, err
+		}
+		return codes, nil
+	}
+
 	// Initialize Gin router
 	sugar.Info("Setting up routes...")
 	r := gin.Default()
@@ -133,6 +750,45 @@ func main() {
 		c.Next()
 	})
 
+	// Traçabilité des tokens d'impersonation : chaque requête authentifiée par un tel token
+	// (directement ou via /validate depuis la gateway) est journalisée avec l'administrateur réel
+	r.Use(func(c *gin.Context) {
+		c.Next()
+
+		value, ok := c.Get("claims")
+		if !ok {
+			return
+		}
+		claims, ok := value.(*services.AccessClaims)
+		if !ok || !claims.IsImpersonated() {
+			return
+		}
+
+		method, path := c.Request.Method, c.Request.URL.Path
+		if forwarded := c.GetHeader("X-Forwarded-Method"); forwarded != "" {
+			method = forwarded
+		}
+		if forwarded := c.GetHeader("X-Forwarded-Uri"); forwarded != "" {
+			path = forwarded
+		}
+		outcome := audit.OutcomeSuccess
+		if c.Writer.Status() >= http.StatusBadRequest {
+			outcome = audit.OutcomeFailure
+		}
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionImpersonatedRequest,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(claims.UserID),
+			Outcome:    outcome,
+			Metadata: map[string]any{
+				"token_id": claims.ID,
+				"method":   method,
+				"path":     path,
+				"status":   c.Writer.Status(),
+			},
+		})
+	})
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -142,6 +798,38 @@ func main() {
 		})
 	})
 
+	// Readiness : le service ne reçoit du trafic que si PostgreSQL et Redis répondent
+	r.GET("/ready", func(c *gin.Context) {
+		pingCtx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		status := http.StatusOK
+		checks := gin.H{"database": "ok", "redis": "ok"}
+		if err := userRepo.Ping(pingCtx); err != nil {
+			status, checks["database"] = http.StatusServiceUnavailable, "unavailable"
+		}
+		if err := redisClient.Ping(pingCtx).Err(); err != nil {
+			sugar.Errorf("Redis ping failed: %v", err)
+			status, checks["redis"] = http.StatusServiceUnavailable, "unavailable"
+		}
+
+		ready := "ready"
+		if status != http.StatusOK {
+			ready = "not_ready"
+		}
+		c.JSON(status, gin.H{
+			"status":  ready,
+			"service": "auth-service",
+			"checks":  checks,
+		})
+	})
+
+	// JWKS : clés publiques pour vérifier les tokens localement (gateway, services)
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtService.JWKS())
+	})
+
 	// User registration endpoint avec JWT
 	sugar.Info("Setting up /register endpoint...")
 	r.POST("/register", func(c *gin.Context) {
@@ -154,8 +842,30 @@ func main() {
 			return
 		}
 
+		if !enforcePasswordPolicy(c, req.Password, services.PasswordOwner{
+			Email:     req.Email,
+			Firstname: req.Firstname,
+			Lastname:  req.Lastname,
+		}) {
+			return
+		}
+
+		// Une invitation invalide est refusée avant la création du compte
+		invitationHash := ""
+		if req.InvitationToken != "" {
+			invitationHash = services.HashOpaqueToken(req.InvitationToken)
+			invitation, err := userRepo.FindPendingInvitation(ctx, invitationHash)
+			if err != nil || !strings.EqualFold(invitation.Email, req.Email) {
+				c.JSON(http.StatusBadRequest, RegisterResponse{
+					Status:  "error",
+					Message: "Invalid or expired invitation",
+				})
+				return
+			}
+		}
+
 		// Hasher le mot de passe
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		hashedPassword, err := passwordHasher.Hash(req.Password)
 		if err != nil {
 			sugar.Errorf("Failed to hash password: %v", err)
 			c.JSON(http.StatusInternalServerError, RegisterResponse{
@@ -171,26 +881,81 @@ func main() {
 			Lastname:  req.Lastname,
 			Firstname: req.Firstname,
 			Email:     req.Email,
-			Password:  string(hashedPassword),
+			Password:  hashedPassword,
 			Role:      "user",
 			IsActive:  true,
 		}
 
 		if err := userRepo.Create(ctx, user); err != nil {
 			sugar.Errorf("Failed to create user in repository: %v", err)
-			c.JSON(http.StatusInternalServerError, RegisterResponse{
-				Status:  "error",
-				Message: "Failed to create user: " + err.Error(),
+			respondError(c, err, "Failed to create user")
+			return
+		}
+
+		// Rattacher le compte à une organisation : celle de l'invitation, sinon une nouvelle agence
+		if invitationHash != "" {
+			if _, err := userRepo.AcceptInvitation(ctx, invitationHash, user); err != nil {
+				sugar.Errorf("Failed to accept invitation for user ID=%d: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, RegisterResponse{
+					Status:  "error",
+					Message: "Failed to join organisation",
+				})
+				return
+			}
+			// L'invitation a été reçue sur cette adresse : l'email est vérifié
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			if err := userRepo.Update(ctx, user); err != nil {
+				sugar.Errorf("Failed to mark email as verified for user ID=%d: %v", user.ID, err)
+			}
+		} else {
+			org := &model.Organisation{Name: req.Company}
+			if err := userRepo.CreateOrganisation(ctx, org, user.ID); err != nil {
+				sugar.Errorf("Failed to create organisation for user ID=%d: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, RegisterResponse{
+					Status:  "error",
+					Message: "Failed to create organisation",
+				})
+				return
+			}
+			user.OrganisationID = &org.ID
+		}
+
+		if emailVerificationPolicy != EmailVerificationOff && !user.IsEmailVerified() {
+			if err := sendVerificationEmail(user); err != nil {
+				sugar.Errorf("Failed to send verification email to user ID=%d: %v", user.ID, err)
+			}
+		}
+
+		// Politique "block" : pas de tokens avant la confirmation de l'email
+		if emailVerificationPolicy == EmailVerificationBlock && !user.IsEmailVerified() {
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionRegister,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Metadata:   map[string]any{"email_verification": "pending", "organisation_id": user.OrganisationID},
+			})
+			c.Header("Location", fmt.Sprintf("/api/v1/auth/users/%d", user.ID))
+			c.JSON(http.StatusCreated, RegisterResponse{
+				Status:  "success",
+				Message: "User registered successfully, please verify your email address",
+				Data: gin.H{
+					"user": gin.H{
+						"id":             user.ID,
+						"email":          user.Email,
+						"email_verified": false,
+					},
+				},
 			})
 			return
 		}
 
-		// Générer les tokens JWT
-		accessToken, refreshToken, tokenID, accessExp, refreshExp, err := jwtService.GenerateTokenPair(
-			user.ID, user.Email, user.Role,
-		)
+		// Générer les tokens JWT et ouvrir la session
+		tokens, err := startSession(c, user)
 		if err != nil {
-			sugar.Errorf("Failed to generate tokens: %v", err)
+			sugar.Errorf("Failed to start session for user ID=%d: %v", user.ID, err)
 			c.JSON(http.StatusInternalServerError, RegisterResponse{
 				Status:  "error",
 				Message: "Failed to generate authentication tokens",
@@ -198,32 +963,35 @@ func main() {
 			return
 		}
 
-		// Stocker le refresh token dans Redis
-		if err := tokenRepo.StoreRefreshToken(ctx, user.ID, tokenID, refreshToken, refreshExp); err != nil {
-			sugar.Errorf("Failed to store refresh token: %v", err)
-		}
-
-		sugar.Infof("New user registration: ID=%d prenom=%s nom=%s mail=%s",
-			user.ID, req.Firstname, req.Lastname, req.Email)
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionRegister,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"invited": invitationHash != "", "organisation_id": user.OrganisationID},
+		})
 
 		// Préparer les données utilisateur pour la réponse
 		userData := gin.H{
-			"id":         user.ID,
-			"company":    user.Company,
-			"firstname":  user.Firstname,
-			"lastname":   user.Lastname,
-			"email":      user.Email,
-			"role":       user.Role,
-			"is_active":  user.IsActive,
-			"created_at": user.CreatedAt.Format(time.RFC3339),
+			"id":              user.ID,
+			"company":         user.Company,
+			"firstname":       user.Firstname,
+			"lastname":        user.Lastname,
+			"email":           user.Email,
+			"role":            user.Role,
+			"is_active":       user.IsActive,
+			"email_verified":  user.IsEmailVerified(),
+			"organisation_id": user.OrganisationID,
+			"created_at":      user.CreatedAt.Format(time.RFC3339),
 		}
 
 		// Réponse avec tokens JWT
 		tokenResponse := TokenResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
+			AccessToken:  tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
 			TokenType:    "Bearer",
-			ExpiresIn:    accessExp,
+			ExpiresIn:    tokens.AccessExp,
 			User:         userData,
 		}
 
@@ -248,9 +1016,46 @@ func main() {
 			return
 		}
 
+		// Compte ou IP verrouillé après trop d'échecs
+		retryAfter, err := loginGuard.Check(ctx, req.Email, c.ClientIP())
+		if err != nil {
+			sugar.Errorf("Failed to check login lockout: %v", err)
+		}
+		if retryAfter > 0 {
+			auditLog.RecordRequest(c, audit.Event{
+				ActorEmail: req.Email,
+				Action:     audit.ActionLogin,
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"reason": "locked_out"},
+			})
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, RegisterResponse{
+				Status:  "error",
+				Message: "Trop de tentatives de connexion, réessayez plus tard",
+			})
+			return
+		}
+
+		// recordFailure comptabilise l'échec, l'audite et journalise un éventuel verrouillage
+		recordFailure := func(reason string, userID uint) {
+			event := audit.Event{
+				ActorID:    userID,
+				ActorEmail: req.Email,
+				Action:     audit.ActionLogin,
+				Outcome:    audit.OutcomeFailure,
+				Metadata:   map[string]any{"reason": reason},
+			}
+			if userID != 0 {
+				event.TargetType, event.TargetID = audit.TargetUser, audit.UserTarget(userID)
+			}
+			auditLog.RecordRequest(c, event)
+			registerLoginFailure(c, req.Email, userID)
+		}
+
 		// Récupérer l'utilisateur par email
 		user, err := userRepo.FindByEmail(ctx, req.Email)
 		if err != nil {
+			recordFailure("unknown_email", 0)
 			c.JSON(http.StatusUnauthorized, RegisterResponse{
 				Status:  "error",
 				Message: "Email ou mot de passe incorrect",
@@ -260,15 +1065,25 @@ func main() {
 
 		// Vérifier si l'utilisateur est actif
 		if !user.IsActive {
-			c.JSON(http.StatusUnauthorized, RegisterResponse{
-				Status:  "error",
-				Message: "Compte utilisateur désactivé",
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionLogin,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"reason": "inactive"},
 			})
+			respondError(c, autherrors.ErrUserInactive, "")
 			return
 		}
 
 		// Vérifier le mot de passe
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if err := passwordHasher.Verify(req.Password, user.Password); err != nil {
+			if !errors.Is(err, services.ErrPasswordMismatch) {
+				sugar.Errorf("Failed to verify password hash for user ID=%d: %v", user.ID, err)
+			}
+			recordFailure("invalid_password", user.ID)
 			c.JSON(http.StatusUnauthorized, RegisterResponse{
 				Status:  "error",
 				Message: "Email ou mot de passe incorrect",
@@ -276,398 +1091,3926 @@ func main() {
 			return
 		}
 
-		// Mettre à jour la dernière connexion
-		now := time.Now()
-		user.LastLoginAt = &now
-		if err := userRepo.Update(ctx, user); err != nil {
-			sugar.Errorf("Failed to update last login: %v", err)
+		// Mise à niveau transparente des hashs obsolètes (bcrypt, coût ou paramètres plus faibles)
+		if passwordHasher.NeedsRehash(user.Password) {
+			if hashedPassword, err := passwordHasher.Hash(req.Password); err != nil {
+				sugar.Errorf("Failed to rehash password for user ID=%d: %v", user.ID, err)
+			} else {
+				user.Password = hashedPassword
+				if err := userRepo.Update(ctx, user); err != nil {
+					sugar.Errorf("Failed to store rehashed password for user ID=%d: %v", user.ID, err)
+				} else {
+					sugar.Infof("Password hash upgraded for user ID=%d", user.ID)
+				}
+			}
 		}
 
-		// Générer les tokens JWT
-		accessToken, refreshToken, tokenID, accessExp, refreshExp, err := jwtService.GenerateTokenPair(
-			user.ID, user.Email, user.Role,
-		)
-		if err != nil {
-			sugar.Errorf("Failed to generate tokens: %v", err)
-			c.JSON(http.StatusInternalServerError, RegisterResponse{
+		// Politique "block" : l'email doit être vérifié avant la première connexion
+		if emailVerificationPolicy == EmailVerificationBlock && !user.IsEmailVerified() {
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionLogin,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"reason": "email_not_verified"},
+			})
+			c.JSON(http.StatusForbidden, RegisterResponse{
 				Status:  "error",
-				Message: "Failed to generate authentication tokens",
+				Message: "Adresse email non vérifiée",
 			})
 			return
 		}
 
-		// Stocker le refresh token dans Redis
-		sugar.Infof("Stocker le refresh token dans Redis ---------- ")
-		if err := tokenRepo.DeleteRefreshToken(ctx, user.ID, tokenID); err != nil {
-			sugar.Errorf("Failed to store refresh token: %v", err)
-		}
-		if err := tokenRepo.StoreRefreshToken(ctx, user.ID, tokenID, refreshToken, refreshExp); err != nil {
-			sugar.Errorf("Failed to store refresh token: %v", err)
-		}
-
-		sugar.Infof("User login: ID=%d email=%s", user.ID, user.Email)
-
-		// Préparer les données utilisateur pour la réponse
-		userData := gin.H{
-			"id":         user.ID,
-			"company":    user.Company,
-			"firstname":  user.Firstname,
-			"lastname":   user.Lastname,
-			"email":      user.Email,
-			"role":       user.Role,
-			"is_active":  user.IsActive,
-			"last_login": now.Format(time.RFC3339),
-		}
-
-		// Réponse avec tokens JWT
-		tokenResponse := TokenResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			TokenType:    "Bearer",
-			ExpiresIn:    accessExp,
-			User:         userData,
+		// 2FA activée : le mot de passe seul ne suffit pas, on émet un challenge à courte durée de vie
+		if user.IsTOTPEnabled() {
+			startMFAChallenge(c, user)
+			return
 		}
 
-		c.JSON(http.StatusOK, RegisterResponse{
-			Status:  "success",
-			Message: "Login successful",
-			Data:    tokenResponse,
-		})
+		completeLogin(c, user)
 	})
 
-	// Refresh token endpoint
-	sugar.Info("Setting up /refresh endpoint...")
-	r.POST("/refresh", func(c *gin.Context) {
-
-		sugar.Infof("/refresh called")
-		var req RefreshTokenRequest
+	// Second facteur de la connexion : challenge + code TOTP ou code de récupération
+	sugar.Info("Setting up /login/2fa endpoint...")
+	r.POST("/login/2fa", func(c *gin.Context) {
+		var req LoginTwoFactorRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, RegisterResponse{
 				Status:  "error",
-				Message: "Refresh token required",
+				Message: "Invalid request: " + err.Error(),
 			})
 			return
 		}
 
-		// Valider le refresh token
-		sugar.Infof("Valider le refresh token ---------- ")
-		sugar.Infof("Token reçu: %s", req.RefreshToken)
-		claims, err := jwtService.ValidateRefreshToken(req.RefreshToken)
+		challengeHash := services.HashOpaqueToken(req.ChallengeToken)
+		userID, err := tokenRepo.GetMFAChallenge(ctx, challengeHash)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, RegisterResponse{
 				Status:  "error",
-				Message: "Invalid refresh token: " + err.Error(),
+				Message: "Invalid or expired 2FA challenge",
 			})
 			return
 		}
 
-		// Vérifier si le token existe dans Redis
-		storedToken, err := tokenRepo.GetRefreshToken(ctx, claims.UserID, claims.ID)
-		log.Printf("Stored token: %s", storedToken)
-		log.Printf("Claims token ID: %s", claims.ID)
-		log.Printf("Claims user ID: %d", claims.UserID)
-		log.Printf("Error retrieving token from Redis: %v", err)
-		// Si le token n'existe pas ou ne correspond pas, rejeter la requête
-		// Ceci gère aussi le cas où le token a expiré (car supprimé de Redis)
-		if err != nil || storedToken != req.RefreshToken {
+		user, err := userRepo.FindByID(ctx, userID)
+		if err != nil || !user.IsActive || !user.IsTOTPEnabled() {
+			tokenRepo.DeleteMFAChallenge(ctx, challengeHash)
 			c.JSON(http.StatusUnauthorized, RegisterResponse{
 				Status:  "error",
-				Message: "Refresh token not found or invalid",
+				Message: "Invalid or expired 2FA challenge",
 			})
 			return
 		}
 
-		// Récupérer l'utilisateur pour vérifier qu'il est toujours actif
-		user, err := userRepo.FindByID(ctx, claims.UserID)
+		// Le verrouillage du compte ou de l'IP s'applique aussi au second facteur
+		retryAfter, err := loginGuard.Check(ctx, user.Email, c.ClientIP())
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, RegisterResponse{
+			sugar.Errorf("Failed to check login lockout: %v", err)
+		}
+		if retryAfter > 0 {
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionLoginTwoFactor,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"reason": "locked_out"},
+			})
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, RegisterResponse{
 				Status:  "error",
-				Message: "User not found",
+				Message: "Trop de tentatives de connexion, réessayez plus tard",
 			})
 			return
 		}
 
-		if !user.IsActive {
+		if !verifySecondFactor(user, req.Code) {
+			// Trop d'échecs : le challenge est détruit, il faut recommencer la connexion
+			failures, err := tokenRepo.RecordMFAChallengeFailure(ctx, challengeHash)
+			if err != nil || failures >= maxMFAChallengeFailures {
+				tokenRepo.DeleteMFAChallenge(ctx, challengeHash)
+			}
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionLoginTwoFactor,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Outcome:    audit.OutcomeFailure,
+				Metadata:   map[string]any{"reason": "invalid_second_factor", "failures": failures},
+			})
+			// Les échecs du second facteur entament aussi le compteur du compte : un nouveau
+			// challenge ne redonne pas de tentatives
+			registerLoginFailure(c, user.Email, user.ID)
 			c.JSON(http.StatusUnauthorized, RegisterResponse{
 				Status:  "error",
-				Message: "User account is disabled",
+				Message: "Code de vérification incorrect",
 			})
 			return
 		}
 
-		// Générer de nouveaux tokens
-		accessToken, newRefreshToken, tokenID, accessExp, refreshExp, err := jwtService.GenerateTokenPair(
-			user.ID, user.Email, user.Role,
-		)
+		tokenRepo.DeleteMFAChallenge(ctx, challengeHash)
+		completeLogin(c, user)
+	})
+
+	// Activation de la 2FA (étape 1) : génération du secret et de l'URI de provisioning
+	sugar.Info("Setting up /2fa endpoints...")
+	r.POST("/2fa/setup", requireAuth(jwtService, tokenRepo), forbidImpersonation(), requireVerifiedEmail(emailVerificationPolicy), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, RegisterResponse{
-				Status:  "error",
-				Message: "Failed to generate new tokens",
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+		if user.IsTOTPEnabled() {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "Two-factor authentication is already enabled",
+			})
+			return
+		}
+
+		secret, err := services.GenerateTOTPSecret()
+		if err != nil {
+			sugar.Errorf("Failed to generate TOTP secret: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to start two-factor enrollment",
+			})
+			return
+		}
+		if err := tokenRepo.StorePendingTOTPSecret(ctx, user.ID, secret, pendingTOTPSecretTTL); err != nil {
+			sugar.Errorf("Failed to store pending TOTP secret: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to start two-factor enrollment",
 			})
 			return
 		}
 
-		// Supprimer l'ancien refresh token et stocker le nouveau
-		tokenRepo.DeleteRefreshToken(ctx, claims.UserID, claims.ID)
-		tokenRepo.StoreRefreshToken(ctx, user.ID, tokenID, newRefreshToken, refreshExp)
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"secret":           secret,
+				"provisioning_uri": services.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+				"expires_in":       int64(pendingTOTPSecretTTL.Seconds()),
+			},
+		})
+	})
 
-		sugar.Infof("Token refreshed for user: ID=%d email=%s", user.ID, user.Email)
+	// Activation de la 2FA (étape 2) : confirmation avec un premier code, génération des codes de récupération
+	r.POST("/2fa/confirm", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
 
-		// Préparer les données utilisateur pour la réponse
-		userData := gin.H{
-			"id":        user.ID,
-			"company":   user.Company,
-			"firstname": user.Firstname,
-			"lastname":  user.Lastname,
-			"email":     user.Email,
-			"role":      user.Role,
-			"is_active": user.IsActive,
+		var req TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
 		}
 
-		tokenResponse := TokenResponse{
-			AccessToken:  accessToken,
-			RefreshToken: newRefreshToken,
-			TokenType:    "Bearer",
-			ExpiresIn:    accessExp,
-			User:         userData,
+		secret, err := tokenRepo.GetPendingTOTPSecret(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "No pending two-factor enrollment",
+			})
+			return
 		}
 
-		sugar.Infof("New tokens generated for user ID=%d", user.ID)
+		step, ok := services.ValidateTOTP(secret, req.Code, time.Now())
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Code de vérification incorrect",
+			})
+			return
+		}
 
-		c.JSON(http.StatusOK, RegisterResponse{
-			Status:  "success",
-			Message: "Tokens refreshed successfully",
-			Data:    tokenResponse,
-		})
-	})
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
 
-	// Endpoint de validation de token (utilisé par la gateway)
-	sugar.Info("Setting up /validate endpoint...")
-	r.POST("/validate", func(c *gin.Context) {
-		// Extraire le token de l'en-tête Authorization
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		now := time.Now()
+		user.TOTPSecret = secret
+		user.TOTPEnabledAt = &now
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to enable 2FA for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"status":  "error",
-				"message": "Authorization header required",
+				"message": "Failed to enable two-factor authentication",
 			})
 			return
 		}
+		tokenRepo.DeletePendingTOTPSecret(ctx, user.ID)
+		tokenRepo.MarkTOTPStepUsed(ctx, user.ID, step)
 
-		// Vérifier le format "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		codes, err := newRecoveryCodes(user.ID)
+		if err != nil {
+			sugar.Errorf("Failed to generate recovery codes for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"status":  "error",
-				"message": "Invalid authorization header format",
+				"message": "Two-factor authentication enabled but recovery codes could not be generated",
 			})
 			return
 		}
 
-		token := parts[1]
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionTwoFactorEnabled,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
 
-		// Valider le token
-		claims, err := jwtService.ValidateAccessToken(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Two-factor authentication enabled",
+			"data": gin.H{
+				"recovery_codes": codes,
+			},
+		})
+	})
+
+	// Régénération des codes de récupération (invalide les anciens)
+	r.POST("/2fa/recovery-codes", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"status":  "error",
-				"message": "Invalid token: " + err.Error(),
+				"message": "Invalid request: " + err.Error(),
 			})
 			return
 		}
 
-		// Vérifier si le token est blacklisté (logout forcé, etc.)
-		if tokenRepo.IsTokenBlacklisted(ctx, claims.ID) {
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil || !user.IsTOTPEnabled() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Two-factor authentication is not enabled",
+			})
+			return
+		}
+		if !verifySecondFactor(user, req.Code) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"status":  "error",
-				"message": "Token has been revoked",
+				"message": "Code de vérification incorrect",
 			})
 			return
 		}
 
-		// Token valide → renvoyer les infos utiles
+		codes, err := newRecoveryCodes(user.ID)
+		if err != nil {
+			sugar.Errorf("Failed to generate recovery codes for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to generate recovery codes",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionRecoveryCodesRenewed,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
 		c.JSON(http.StatusOK, gin.H{
 			"status": "success",
 			"data": gin.H{
-				"user_id":  claims.UserID,
-				"email":    claims.Email,
-				"role":     claims.Role,
-				"token_id": claims.ID, // JTI
+				"recovery_codes": codes,
 			},
 		})
 	})
 
-	// // Logout endpoint
-	// sugar.Info("Setting up /logout endpoint...")
-	// r.POST("/logout", func(c *gin.Context) {
-	// 	// Extraire le token de l'en-tête Authorization
-	// 	authHeader := c.GetHeader("Authorization")
-	// 	if authHeader == "" {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Authorization header required",
-	// 		})
-	// 		return
-	// 	}
-
-	// 	// Vérifier le format "Bearer <token>"
-	// 	parts := strings.SplitN(authHeader, " ", 2)
-	// 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Invalid authorization header format",
-	// 		})
-	// 		return
-	// 	}
-
-	// 	token := parts[1]
-
-	// 	// Valider le token pour extraire les informations
-	// 	claims, err := jwtService.ValidateAccessToken(token)
-	// 	if err != nil {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Invalid token: " + err.Error(),
-	// 		})
-	// 		return
-	// 	}
-
-	// 	// Supprimer le refresh token associé
-	// 	if err := tokenRepo.DeleteRefreshToken(ctx, claims.UserID, claims.TokenID); err != nil {
-	// 		sugar.Errorf("Failed to delete refresh token: %v", err)
-	// 	}
-
-	// 	// Ajouter l'access token à la liste noire
-	// 	expiry, err := jwtService.GetTokenExpiry(token)
-	// 	if err != nil {
-	// 		sugar.Errorf("Failed to get token expiry: %v", err)
-	// 	} else {
-	// 		if err := tokenRepo.BlacklistToken(ctx, claims.ID, expiry); err != nil {
-	// 			sugar.Errorf("Failed to blacklist token: %v", err)
-	// 		}
-	// 	}
-
-	// 	sugar.Infof("User logout: ID=%d email=%s", claims.UserID, claims.Email)
-
-	// 	c.JSON(http.StatusOK, gin.H{
-	// 		"status":  "success",
-	// 		"message": "Logged out successfully",
-	// 	})
-	// })
-
-	// // Profile endpoint
-	// sugar.Info("Setting up /profile endpoint...")
-	// r.GET("/profile", func(c *gin.Context) {
-	// 	// Extraire le token de l'en-tête Authorization
-	// 	authHeader := c.GetHeader("Authorization")
-	// 	if authHeader == "" {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Authorization header required",
-	// 		})
-	// 		return
-	// 	}
-
-	// 	// Vérifier le format "Bearer <token>"
-	// 	parts := strings.SplitN(authHeader, " ", 2)
-	// 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Invalid authorization header format",
-	// 		})
-	// 		return
-	// 	}
-
-	// 	token := parts[1]
-
-	// 	// Valider le token
-	// 	claims, err := jwtService.ValidateAccessToken(token)
-	// 	if err != nil {
-	// 		c.JSON(http.StatusUnauthorized, gin.H{
-	// 			"status":  "error",
-	// 			"message": "Invalid token: " + err.Error(),
-	// 		})
-	// 		return
-	// 	}
-
-	// 	// Récupérer l'utilisateur
-	// 	user, err := userRepo.GetByID(ctx, claims.UserID)
-	// 	if err != nil {
-	// 		c.JSON(http.StatusNotFound, gin.H{
-	// 			"status":  "error",
-	// 			"message": "User not found",
-	// 		})
-	// 		return
-	// 	}
-
-	// 	// Préparer les données utilisateur pour la réponse
-	// 	userData := gin.H{
-	// 		"id":         user.ID,
-	// 		"company":    user.Company,
-	// 		"firstname":  user.Firstname,
-	// 		"lastname":   user.Lastname,
-	// 		"email":      user.Email,
-	// 		"role":       user.Role,
-	// 		"is_active":  user.IsActive,
-	// 		"last_login": user.LastLogin,
-	// 		"created_at": user.CreatedAt.Format(time.RFC3339),
-	// 		"updated_at": user.UpdatedAt.Format(time.RFC3339),
-	// 	}
-
-	// 	c.JSON(http.StatusOK, gin.H{
-	// 		"status": "success",
-	// 		"data":   userData,
-	// 	})
-	// })
+	// Désactivation de la 2FA : mot de passe + code requis
+	r.POST("/2fa/disable", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
 
-	port := getEnv("AUTH_SERVICE_PORT", "8081")
-	sugar.Infof("Auth-service started on port %s", port)
+		var req DisableTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
 
-	if err := r.Run(":" + port); err != nil {
-		sugar.Fatalf("Server startup error: %v", err)
-	}
-}
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil || !user.IsTOTPEnabled() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Two-factor authentication is not enabled",
+			})
+			return
+		}
 
-// getEnv récupère une variable d'environnement avec une valeur par défaut
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+		if err := passwordHasher.Verify(req.Password, user.Password); err != nil || !verifySecondFactor(user, req.Code) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Mot de passe ou code de vérification incorrect",
+			})
+			return
+		}
 
-// Remplacez cette partie dans votre main.go
-func initRedis(redisURL string, sugar *zap.SugaredLogger) *redis.Client {
-	// Si vous avez une URL Redis complète avec mot de passe
-	if strings.HasPrefix(redisURL, "redis://") {
-		opt, err := redis.ParseURL(redisURL)
-		if err != nil {
-			sugar.Fatalf("Failed to parse Redis URL: %v", err)
+		user.TOTPSecret = ""
+		user.TOTPEnabledAt = nil
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to disable 2FA for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to disable two-factor authentication",
+			})
+			return
+		}
+		if err := userRepo.ReplaceRecoveryCodes(ctx, user.ID, nil); err != nil {
+			sugar.Errorf("Failed to delete recovery codes for user ID=%d: %v", user.ID, err)
 		}
-		return redis.NewClient(opt)
-	}
-	sugar.Info("Using manual Redis configuration from environment variables")
-	sugar.Infof("Redis Addr: %s", getEnv("REDIS_ADDR", "localhost:6379"))
-	sugar.Infof("Redis DB: %d", getIntEnv("REDIS_DB", 0))
-	sugar.Infof("Redis Pool Size: %d", getIntEnv("REDIS_POOL_SIZE", 10))
 
-	// Configuration manuelle avec mot de passe
-	client := redis.NewClient(&redis.Options{
-		Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
-		Password: getEnv("REDIS_PASSWORD", ""), // Mot de passe
-		DB:       getIntEnv("REDIS_DB", 0),     // Base de données
-		PoolSize: getIntEnv("REDIS_POOL_SIZE", 10),
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionTwoFactorDisabled,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Two-factor authentication disabled",
+		})
 	})
 
-	return client
-}
+	// Refresh token endpoint
+	sugar.Info("Setting up /refresh endpoint...")
+	// rotateRefreshToken valide un refresh token et le remplace par une nouvelle paire de tokens
+	// de la même famille. La réutilisation d'un token déjà consommé révoque toute la famille.
+	// clientID est le client OpenID Connect authentifié, vide pour l'application : un refresh
+	// token n'est accepté que par le client auquel il a été délivré.
+	rotateRefreshToken := func(c *gin.Context, refreshToken, clientID string) (*model.User, *issuedTokens, error) {
+		// Valider le refresh token (jamais journalisé : c'est un secret)
+		claims, err := jwtService.ValidateRefreshToken(refreshToken)
+		if err != nil {
+			auditLog.RecordRequest(c, audit.Event{
+				Action:   audit.ActionRefresh,
+				Outcome:  audit.OutcomeFailure,
+				Metadata: map[string]any{"reason": "invalid_refresh_token"},
+			})
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "Invalid refresh token: " + err.Error()}
+		}
 
-// Fonction helper pour les entiers
-func getIntEnv(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+		// Le client et les scopes accordés sont ceux enregistrés avec la session ; le token
+		// (signé) doit les porter aussi
+		var previous *model.Session
+		if session, err := tokenRepo.GetSession(ctx, claims.UserID, claims.ID); err == nil {
+			previous = session
 		}
-	}
-	return defaultValue
+		if claims.ClientID != clientID || (previous != nil && (previous.ClientID != claims.ClientID || previous.Scope != claims.Scope)) {
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:  claims.UserID,
+				Action:   audit.ActionRefresh,
+				Outcome:  audit.OutcomeFailure,
+				Metadata: map[string]any{"reason": "client_mismatch", "session_id": claims.ID},
+			})
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "Refresh token was not issued to this client"}
+		}
+
+		// Récupérer l'utilisateur pour vérifier qu'il est toujours actif
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "User not found"}
+		}
+
+		if !user.IsActive {
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "User account is disabled"}
+		}
+
+		// Générer de nouveaux tokens
+		// Les anciens refresh tokens sans famille démarrent une nouvelle famille
+		var accessToken, newRefreshToken, tokenID string
+		var accessExp, refreshExp int64
+		if clientID != "" {
+			// Les tokens d'un client restent limités aux scopes accordés lors de l'autorisation
+			accessToken, newRefreshToken, tokenID, accessExp, refreshExp, err = jwtService.GenerateClientTokenPair(
+				user.ID, clientID, claims.Scope, claims.FamilyID,
+			)
+		} else {
+			// Les permissions sont rechargées : un changement de rôle prend effet au prochain refresh
+			subject, serr := tokenSubject(ctx, userRepo, user, emailVerificationPolicy)
+			if serr != nil {
+				sugar.Errorf("Failed to load user permissions: %v", serr)
+				return nil, nil, &refreshError{Status: http.StatusInternalServerError, Message: "Failed to generate new tokens"}
+			}
+			accessToken, newRefreshToken, tokenID, accessExp, refreshExp, err = jwtService.GenerateTokenPairInFamily(
+				subject, claims.FamilyID,
+			)
+		}
+		if err != nil {
+			return nil, nil, &refreshError{Status: http.StatusInternalServerError, Message: "Failed to generate new tokens"}
+		}
+		familyID := claims.FamilyID
+		if familyID == "" {
+			familyID = tokenID
+		}
+
+		// La session survit à la rotation : on conserve l'appareil, la date de création et le client
+		session := newSession(c, tokenID, refreshExp)
+		session.ClientID, session.Scope = claims.ClientID, claims.Scope
+		if previous != nil {
+			session.CreatedAt = previous.CreatedAt
+			if session.UserAgent == "" {
+				session.UserAgent = previous.UserAgent
+			}
+		}
+
+		// Consommer l'ancien refresh token en une seule étape atomique. Un token déjà consommé
+		// signifie qu'il a probablement été volé → révoquer toute la famille
+		err = tokenRepo.RotateRefreshToken(ctx, claims.UserID, claims.FamilyID, claims.ID, refreshToken, tokenID, refreshExp)
+		switch {
+		case errors.Is(err, database.ErrRefreshTokenReused):
+			accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+			if err := tokenRepo.RevokeTokenFamily(ctx, claims.UserID, claims.FamilyID, accessExpiry); err != nil {
+				sugar.Errorf("Failed to revoke token family %s: %v", claims.FamilyID, err)
+			}
+
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    claims.UserID,
+				Action:     audit.ActionRefreshReuse,
+				TargetType: audit.TargetSession,
+				TargetID:   claims.FamilyID,
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"session_id": claims.ID},
+			})
+
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "Refresh token reuse detected, session revoked"}
+		case errors.Is(err, database.ErrRefreshTokenNotFound):
+			// Token révoqué, expiré (supprimé de Redis) ou ne correspondant pas au token stocké
+			return nil, nil, &refreshError{Status: http.StatusUnauthorized, Message: "Refresh token not found or invalid"}
+		case err != nil:
+			sugar.Errorf("Failed to rotate refresh token: %v", err)
+			return nil, nil, &refreshError{Status: http.StatusInternalServerError, Message: "Failed to generate new tokens"}
+		}
+
+		// Stocker le nouveau refresh token : sans lui, la session serait perdue
+		if err := tokenRepo.StoreRefreshToken(ctx, user.ID, tokenID, newRefreshToken, refreshExp); err != nil {
+			sugar.Errorf("Failed to store refresh token: %v", err)
+			return nil, nil, &refreshError{Status: http.StatusInternalServerError, Message: "Failed to generate new tokens"}
+		}
+		if err := tokenRepo.StoreSession(ctx, user.ID, session); err != nil {
+			sugar.Errorf("Failed to store session: %v", err)
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionRefresh,
+			TargetType: audit.TargetSession,
+			TargetID:   familyID,
+			Metadata:   map[string]any{"session_id": tokenID},
+		})
+
+		return user, &issuedTokens{
+			AccessToken:  accessToken,
+			RefreshToken: newRefreshToken,
+			TokenID:      tokenID,
+			FamilyID:     familyID,
+			AccessExp:    accessExp,
+			RefreshExp:   refreshExp,
+			Scope:        claims.Scope,
+		}, nil
+	}
+
+	r.POST("/refresh", func(c *gin.Context) {
+
+		var req RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Refresh token required",
+			})
+			return
+		}
+
+		user, tokens, err := rotateRefreshToken(c, req.RefreshToken, "")
+		if err != nil {
+			var rerr *refreshError
+			if !errors.As(err, &rerr) {
+				rerr = &refreshError{Status: http.StatusInternalServerError, Message: "Failed to generate new tokens"}
+			}
+			c.JSON(rerr.Status, RegisterResponse{
+				Status:  "error",
+				Message: rerr.Message,
+			})
+			return
+		}
+
+		// Préparer les données utilisateur pour la réponse
+		userData := gin.H{
+			"id":              user.ID,
+			"company":         user.Company,
+			"firstname":       user.Firstname,
+			"lastname":        user.Lastname,
+			"email":           user.Email,
+			"role":            user.Role,
+			"is_active":       user.IsActive,
+			"email_verified":  user.IsEmailVerified(),
+			"organisation_id": user.OrganisationID,
+		}
+
+		tokenResponse := TokenResponse{
+			AccessToken:  tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    tokens.AccessExp,
+			User:         userData,
+		}
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Tokens refreshed successfully",
+			Data:    tokenResponse,
+		})
+	})
+
+	// validatePersonalAccessToken valide un token d'API pour /validate et répond avec les mêmes
+	// informations que pour un access token JWT
+	validatePersonalAccessToken := func(c *gin.Context, token string) {
+		invalid := func(message string) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": message,
+			})
+		}
+
+		pat, err := userRepo.FindPersonalAccessToken(ctx, services.HashOpaqueToken(token))
+		if err != nil {
+			invalid("Invalid token: unknown, revoked or expired API token")
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, pat.UserID)
+		if err != nil || !user.IsActive {
+			invalid("Invalid token: user account is inactive")
+			return
+		}
+
+		// Le token n'agit que dans l'organisation de sa création, tant que l'utilisateur en est membre
+		var orgID uint
+		orgRole := ""
+		if pat.OrganisationID != nil {
+			membership, err := userRepo.FindMembership(ctx, *pat.OrganisationID, user.ID)
+			if err != nil {
+				invalid("Invalid token: organisation membership revoked")
+				return
+			}
+			orgID, orgRole = *pat.OrganisationID, membership.Role
+		}
+
+		permissions, err := userRepo.ListUserPermissions(ctx, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to load user permissions",
+			})
+			return
+		}
+		if emailVerificationPolicy == EmailVerificationLimited && !user.IsEmailVerified() {
+			permissions = readOnlyScopes(permissions)
+		}
+
+		if err := userRepo.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+			sugar.Errorf("Failed to touch API token ID=%d: %v", pat.ID, err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"user_id":        user.ID,
+				"email":          user.Email,
+				"role":           user.Role,
+				"token_id":       fmt.Sprintf("pat_%d", pat.ID),
+				"token_type":     "api_token",
+				"email_verified": user.IsEmailVerified(),
+				"scopes":         services.EffectiveScopes(pat.Scopes, permissions),
+				"org_id":         orgID,
+				"org_role":       orgRole,
+			},
+		})
+	}
+
+	// Endpoint de validation de token (utilisé par la gateway)
+	sugar.Info("Setting up /validate endpoint...")
+	r.POST("/validate", func(c *gin.Context) {
+		// Extraire le token de l'en-tête Authorization
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Authorization header required",
+			})
+			return
+		}
+
+		// Vérifier le format "Bearer <token>"
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid authorization header format",
+			})
+			return
+		}
+
+		token := parts[1]
+
+		// Token d'API des intégrations (préfixe igp_), accepté à côté des JWT
+		if services.IsPersonalAccessToken(token) {
+			validatePersonalAccessToken(c, token)
+			return
+		}
+
+		// Valider le token
+		claims, err := jwtService.ValidateAccessToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid token: " + err.Error(),
+			})
+			return
+		}
+
+		// Vérifier si le token est blacklisté (logout forcé, etc.)
+		if isAccessTokenRevoked(ctx, tokenRepo, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Token has been revoked",
+			})
+			return
+		}
+
+		data := gin.H{
+			"user_id":        claims.UserID,
+			"email":          claims.Email,
+			"role":           claims.Role,
+			"token_id":       claims.ID, // JTI
+			"token_type":     claims.TokenType,
+			"email_verified": claims.EmailVerified,
+			"scopes":         claims.Scopes(),
+			"org_id":         claims.OrgID,
+		}
+
+		// Rôle dans l'organisation active, pour les politiques d'accès de la gateway
+		if claims.OrgID != 0 {
+			if membership, err := userRepo.FindMembership(ctx, claims.OrgID, claims.UserID); err == nil {
+				data["org_role"] = membership.Role
+			}
+		}
+
+		if claims.IsImpersonated() {
+			// Token d'impersonation : pas de session à mettre à jour
+			data["impersonated"] = true
+			data["actor_user_id"] = claims.Actor.UserID
+			data["actor_email"] = claims.Actor.Email
+			// Journalisé par le middleware de traçabilité des impersonations
+			c.Set("claims", claims)
+		} else if err := tokenRepo.TouchSession(ctx, claims.UserID, claims.ID); err != nil {
+			// Mettre à jour la date de dernière utilisation de la session
+			sugar.Errorf("Failed to touch session: %v", err)
+		}
+
+		// Token valide → renvoyer les infos utiles
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   data,
+		})
+	})
+
+	// Logout endpoint : révoque la session courante
+	sugar.Info("Setting up /logout endpoint...")
+	r.POST("/logout", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		// Supprimer le refresh token associé (même JTI que l'access token)
+		if err := tokenRepo.DeleteRefreshToken(ctx, claims.UserID, claims.ID); err != nil {
+			sugar.Errorf("Failed to delete refresh token: %v", err)
+		}
+
+		// Ajouter l'access token à la liste noire jusqu'à son expiration
+		if err := tokenRepo.BlacklistToken(ctx, claims.ID, claims.ExpiresAt.Unix()); err != nil {
+			sugar.Errorf("Failed to blacklist token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to revoke token",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionLogout,
+			TargetType: audit.TargetSession,
+			TargetID:   claims.ID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Logged out successfully",
+		})
+	})
+
+	// Logout all devices : révoque toutes les sessions de l'utilisateur
+	sugar.Info("Setting up /logout/all endpoint...")
+	r.POST("/logout/all", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		// L'access token courant est révoqué même s'il n'a plus de refresh token en Redis
+		if err := tokenRepo.BlacklistToken(ctx, claims.ID, claims.ExpiresAt.Unix()); err != nil {
+			sugar.Errorf("Failed to blacklist token: %v", err)
+		}
+
+		accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+		if err := revokeUserSessions(ctx, tokenRepo, claims.UserID, accessExpiry); err != nil {
+			sugar.Errorf("Failed to revoke sessions for user ID=%d: %v", claims.UserID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to revoke sessions",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionLogoutAll,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(claims.UserID),
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Logged out from all devices successfully",
+		})
+	})
+
+	// Demande de reset de mot de passe : envoie un lien à usage unique par email
+	sugar.Info("Setting up /password/forgot endpoint...")
+	r.POST("/password/forgot", func(c *gin.Context) {
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		// Réponse identique que le compte existe ou non (pas d'énumération des emails)
+		response := RegisterResponse{
+			Status:  "success",
+			Message: "Si un compte existe pour cet email, un lien de réinitialisation a été envoyé",
+		}
+
+		user, err := userRepo.FindByEmail(ctx, req.Email)
+		if err != nil || !user.IsActive {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		token, tokenHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate password reset token: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to generate password reset token",
+			})
+			return
+		}
+
+		if err := tokenRepo.StorePasswordResetToken(ctx, user.ID, tokenHash, passwordResetTTL); err != nil {
+			sugar.Errorf("Failed to store password reset token: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to generate password reset token",
+			})
+			return
+		}
+
+		resetURL := appBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+		if err := mailSender.Send(ctx, mail.Message{
+			To:      user.Email,
+			Subject: "Réinitialisation de votre mot de passe",
+			Body: fmt.Sprintf("Bonjour %s,\n\nPour réinitialiser votre mot de passe, ouvrez ce lien (valable %s) :\n%s\n\n"+
+				"Si vous n'êtes pas à l'origine de cette demande, ignorez cet email.",
+				user.Firstname, passwordResetTTL, resetURL),
+		}); err != nil {
+			sugar.Errorf("Failed to send password reset email to user ID=%d: %v", user.ID, err)
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorEmail: user.Email,
+			Action:     audit.ActionPasswordResetRequest,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+		c.JSON(http.StatusOK, response)
+	})
+
+	sugar.Info("Setting up /password/validate endpoint...")
+	// POST /password/validate : évalue un mot de passe (formulaire d'inscription), sans rien enregistrer
+	r.POST("/password/validate", func(c *gin.Context) {
+		var req ValidatePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		check, err := passwordPolicy.Check(req.Password, services.PasswordOwner{
+			Email:     req.Email,
+			Firstname: req.Firstname,
+			Lastname:  req.Lastname,
+		})
+		if err != nil {
+			sugar.Errorf("Password policy: %v", err)
+		}
+
+		policy := passwordPolicy.Config()
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Password evaluated",
+			Data: gin.H{
+				"valid":      check.Valid,
+				"score":      check.Score,
+				"violations": check.Violations,
+				"policy": gin.H{
+					"min_length":            policy.MinLength,
+					"max_length":            policy.MaxLength,
+					"require_lowercase":     policy.RequireLower,
+					"require_uppercase":     policy.RequireUpper,
+					"require_digit":         policy.RequireDigit,
+					"require_symbol":        policy.RequireSymbol,
+					"forbid_personal_info":  policy.ForbidPersonalInfo,
+					"breached_check_active": breachedPasswords != nil,
+				},
+			},
+		})
+	})
+
+	// Reset du mot de passe avec le token reçu par email
+	sugar.Info("Setting up /password/reset endpoint...")
+	r.POST("/password/reset", func(c *gin.Context) {
+		var req ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		invalidToken := func() {
+			auditLog.RecordRequest(c, audit.Event{
+				Action:   audit.ActionPasswordReset,
+				Outcome:  audit.OutcomeFailure,
+				Metadata: map[string]any{"reason": "invalid_reset_token"},
+			})
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid or expired reset token",
+			})
+		}
+
+		// Le token n'est consommé qu'une fois le nouveau mot de passe accepté par la politique :
+		// un mot de passe refusé n'invalide pas le lien reçu par email
+		tokenHash := services.HashOpaqueToken(req.Token)
+		userID, err := tokenRepo.GetPasswordResetToken(ctx, tokenHash)
+		if err != nil {
+			invalidToken()
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, userID)
+		if err != nil || !user.IsActive {
+			invalidToken()
+			return
+		}
+
+		if !enforcePasswordPolicy(c, req.Password, services.PasswordOwner{
+			Email:     user.Email,
+			Firstname: user.Firstname,
+			Lastname:  user.Lastname,
+		}) {
+			return
+		}
+
+		// Usage unique : le token est consommé avant la mise à jour du mot de passe
+		if consumedID, err := tokenRepo.ConsumePasswordResetToken(ctx, tokenHash); err != nil || consumedID != user.ID {
+			invalidToken()
+			return
+		}
+
+		hashedPassword, err := passwordHasher.Hash(req.Password)
+		if err != nil {
+			sugar.Errorf("Failed to hash password: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Internal server error during password hashing",
+			})
+			return
+		}
+
+		user.Password = hashedPassword
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to update password for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to update password",
+			})
+			return
+		}
+
+		// Un reset invalide toutes les sessions existantes
+		accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+		if err := revokeUserSessions(ctx, tokenRepo, user.ID, accessExpiry); err != nil {
+			sugar.Errorf("Failed to revoke sessions for user ID=%d: %v", user.ID, err)
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionPasswordReset,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Password has been reset successfully",
+		})
+	})
+
+	// Vérification de l'adresse email avec le token reçu par email
+	sugar.Info("Setting up /verify-email endpoint...")
+	r.POST("/verify-email", func(c *gin.Context) {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		userID, err := tokenRepo.ConsumeEmailVerificationToken(ctx, services.HashOpaqueToken(req.Token))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid or expired verification token",
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid or expired verification token",
+			})
+			return
+		}
+
+		if !user.IsEmailVerified() {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			if err := userRepo.Update(ctx, user); err != nil {
+				sugar.Errorf("Failed to mark email as verified for user ID=%d: %v", user.ID, err)
+				c.JSON(http.StatusInternalServerError, RegisterResponse{
+					Status:  "error",
+					Message: "Failed to verify email",
+				})
+				return
+			}
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionEmailVerified,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Email verified successfully",
+		})
+	})
+
+	// Renvoyer l'email de vérification
+	sugar.Info("Setting up /resend-verification endpoint...")
+	r.POST("/resend-verification", func(c *gin.Context) {
+		var req ResendVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		// Réponse identique que le compte existe ou non (pas d'énumération des emails)
+		user, err := userRepo.FindByEmail(ctx, req.Email)
+		if err == nil && user.IsActive && !user.IsEmailVerified() {
+			if err := sendVerificationEmail(user); err != nil {
+				sugar.Errorf("Failed to send verification email to user ID=%d: %v", user.ID, err)
+			}
+		}
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Si un compte non vérifié existe pour cet email, un nouveau lien a été envoyé",
+		})
+	})
+
+	// Déverrouillage manuel d'un compte par un administrateur
+	sugar.Info("Setting up /users/:id/unlock endpoint...")
+	r.POST("/users/:id/unlock", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid user ID",
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		if err := loginGuard.UnlockAccount(ctx, user.Email); err != nil {
+			sugar.Errorf("Failed to unlock user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to unlock account",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionUserUnlocked,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Account unlocked successfully",
+		})
+	})
+
+	// Impersonation d'un utilisateur par le support : access token court portant le claim "act",
+	// sans refresh token ni session
+	sugar.Info("Setting up /users/:id/impersonate endpoint...")
+	r.POST("/users/:id/impersonate", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersImpersonate), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req ImpersonateRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "Invalid request: " + err.Error(),
+				})
+				return
+			}
+		}
+
+		if id == claims.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Cannot impersonate yourself",
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		// Un administrateur ne peut pas en impersonner un autre (pas d'escalade via le support) :
+		// le rôle admin comme toute permission d'administration accordée par RBAC excluent la cible
+		permissions, err := userRepo.ListUserPermissions(ctx, user)
+		if err != nil {
+			sugar.Errorf("Failed to load permissions for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to generate token",
+			})
+			return
+		}
+		if user.Role == "admin" || slices.ContainsFunc(permissions, func(permission string) bool {
+			return slices.Contains(adminPermissions, permission)
+		}) {
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     audit.ActionImpersonationStarted,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"reason": req.Reason},
+			})
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Administrators cannot be impersonated",
+			})
+			return
+		}
+
+		if !user.IsActive {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "User account is deactivated",
+			})
+			return
+		}
+
+		subject, err := tokenSubject(ctx, userRepo, user, emailVerificationPolicy)
+		if err != nil {
+			sugar.Errorf("Failed to build token subject for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to generate token",
+			})
+			return
+		}
+
+		accessToken, tokenID, expiresAt, err := jwtService.GenerateImpersonationToken(subject, services.ActorClaim{
+			UserID: claims.UserID,
+			Email:  claims.Email,
+		}, impersonationTTL)
+		if err != nil {
+			sugar.Errorf("Failed to generate impersonation token for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to generate token",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionImpersonationStarted,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata: map[string]any{
+				"reason":     req.Reason,
+				"token_id":   tokenID,
+				"expires_at": expiresAt,
+			},
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"access_token":      accessToken,
+				"token_type":        "Bearer",
+				"expires_in":        expiresAt - time.Now().Unix(),
+				"impersonated_user": userResponse(user),
+				"actor": gin.H{
+					"user_id": claims.UserID,
+					"email":   claims.Email,
+				},
+			},
+		})
+	})
+
+	// Administration RBAC : permissions
+	sugar.Info("Setting up /permissions endpoints...")
+	r.GET("/permissions", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesRead), forbidImpersonation(), func(c *gin.Context) {
+		permissions, err := userRepo.ListPermissions(ctx)
+		if err != nil {
+			respondRBACError(c, err, "Failed to list permissions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   permissions,
+		})
+	})
+
+	r.POST("/permissions", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		var req PermissionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+		if !permissionNamePattern.MatchString(req.Name) {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Permission name must look like resource:action",
+			})
+			return
+		}
+
+		permission := &model.Permission{Name: req.Name, Description: req.Description}
+		if err := userRepo.CreatePermission(ctx, permission); err != nil {
+			respondRBACError(c, err, "Failed to create permission")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionPermissionCreated,
+			TargetType: audit.TargetPermission,
+			TargetID:   permission.Name,
+		})
+
+		c.JSON(http.StatusCreated, RegisterResponse{
+			Status:  "success",
+			Message: "Permission created successfully",
+			Data:    permission,
+		})
+	})
+
+	r.DELETE("/permissions/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		if err := userRepo.DeletePermission(ctx, id); err != nil {
+			respondRBACError(c, err, "Failed to delete permission")
+			return
+		}
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionPermissionDeleted,
+			TargetType: audit.TargetPermission,
+			TargetID:   c.Param("id"),
+		})
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Permission deleted successfully",
+		})
+	})
+
+	// Administration RBAC : rôles
+	sugar.Info("Setting up /roles endpoints...")
+	r.GET("/roles", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesRead), forbidImpersonation(), func(c *gin.Context) {
+		roles, err := userRepo.ListRoles(ctx)
+		if err != nil {
+			respondRBACError(c, err, "Failed to list roles")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   roles,
+		})
+	})
+
+	r.GET("/roles/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesRead), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		role, err := userRepo.FindRoleByID(ctx, id)
+		if err != nil {
+			respondRBACError(c, err, "Failed to get role")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   role,
+		})
+	})
+
+	r.POST("/roles", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		var req RoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		role := &model.Role{Name: req.Name, Description: req.Description}
+		if err := userRepo.CreateRole(ctx, role, req.Permissions); err != nil {
+			respondRBACError(c, err, "Failed to create role")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionRoleCreated,
+			TargetType: audit.TargetRole,
+			TargetID:   role.Name,
+			Metadata:   map[string]any{"permissions": req.Permissions},
+		})
+
+		c.JSON(http.StatusCreated, RegisterResponse{
+			Status:  "success",
+			Message: "Role created successfully",
+			Data:    role,
+		})
+	})
+
+	r.PUT("/roles/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		var req RoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		// Les permissions sont remplacées : les tokens existants gardent leurs scopes jusqu'au prochain refresh
+		role := &model.Role{ID: id, Name: req.Name, Description: req.Description}
+		if err := userRepo.UpdateRole(ctx, role, req.Permissions); err != nil {
+			respondRBACError(c, err, "Failed to update role")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionRoleUpdated,
+			TargetType: audit.TargetRole,
+			TargetID:   role.Name,
+			Metadata:   map[string]any{"permissions": req.Permissions},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Role updated successfully",
+			Data:    role,
+		})
+	})
+
+	r.DELETE("/roles/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		if err := userRepo.DeleteRole(ctx, id); err != nil {
+			respondRBACError(c, err, "Failed to delete role")
+			return
+		}
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionRoleDeleted,
+			TargetType: audit.TargetRole,
+			TargetID:   c.Param("id"),
+		})
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Role deleted successfully",
+		})
+	})
+
+	// Administration RBAC : rôles d'un utilisateur
+	sugar.Info("Setting up /users/:id/roles endpoints...")
+	r.GET("/users/:id/roles", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesRead), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		roles, err := userRepo.ListUserRoles(ctx, user.ID)
+		if err != nil {
+			respondRBACError(c, err, "Failed to list user roles")
+			return
+		}
+		permissions, err := userRepo.ListUserPermissions(ctx, user)
+		if err != nil {
+			respondRBACError(c, err, "Failed to list user permissions")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"user_id":     user.ID,
+				"role":        user.Role, // rôle principal (users.role)
+				"roles":       roles,
+				"permissions": permissions,
+			},
+		})
+	})
+
+	r.PUT("/users/:id/roles", requireAuth(jwtService, tokenRepo), requirePermission(PermissionRolesWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		var req UserRolesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		if err := userRepo.SetUserRoles(ctx, user.ID, req.RoleIDs); err != nil {
+			respondRBACError(c, err, "Failed to update user roles")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionUserRolesChanged,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"role_ids": req.RoleIDs},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "User roles updated successfully",
+		})
+	})
+
+	// Profil de l'utilisateur connecté
+	sugar.Info("Setting up /users/me endpoints...")
+	r.GET("/users/me", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   userResponse(user),
+		})
+	})
+
+	r.PUT("/users/me", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req UpdateProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		if req.Company != "" {
+			user.Company = req.Company
+		}
+		if req.Lastname != "" {
+			user.Lastname = req.Lastname
+		}
+		if req.Firstname != "" {
+			user.Firstname = req.Firstname
+		}
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to update profile of user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to update profile",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Profile updated successfully",
+			Data:    userResponse(user),
+		})
+	})
+
+	// Changement de mot de passe : toutes les sessions sont révoquées, y compris la courante
+	r.PUT("/users/me/password", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		if err := passwordHasher.Verify(req.CurrentPassword, user.Password); err != nil {
+			c.JSON(http.StatusUnauthorized, RegisterResponse{
+				Status:  "error",
+				Message: "Current password is incorrect",
+			})
+			return
+		}
+
+		if !enforcePasswordPolicy(c, req.NewPassword, services.PasswordOwner{
+			Email:     user.Email,
+			Firstname: user.Firstname,
+			Lastname:  user.Lastname,
+		}) {
+			return
+		}
+
+		hashedPassword, err := passwordHasher.Hash(req.NewPassword)
+		if err != nil {
+			sugar.Errorf("Failed to hash password: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Internal server error during password hashing",
+			})
+			return
+		}
+
+		user.Password = hashedPassword
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to update password of user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to change password",
+			})
+			return
+		}
+
+		accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+		if err := revokeUserSessions(ctx, tokenRepo, user.ID, accessExpiry); err != nil {
+			sugar.Errorf("Failed to revoke sessions of user ID=%d: %v", user.ID, err)
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionPasswordChanged,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Password changed successfully, please log in again",
+		})
+	})
+
+	// Tokens d'API (personal access tokens) pour les intégrations sans connexion interactive.
+	// Ils ne sont acceptés que par /validate (gateway) : la gestion des tokens exige une session.
+	r.GET("/users/me/tokens", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		tokens, err := userRepo.ListPersonalAccessTokens(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list API tokens",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   tokens,
+		})
+	})
+
+	r.POST("/users/me/tokens", requireAuth(jwtService, tokenRepo), forbidImpersonation(), requireVerifiedEmail(emailVerificationPolicy), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req PersonalAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		ttl := apiTokenDefaultTTL
+		if req.ExpiresInDays > 0 {
+			ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		}
+		if ttl > apiTokenMaxTTL {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("API tokens cannot be valid for more than %d days", int(apiTokenMaxTTL.Hours()/24)),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		// Un token ne peut porter que des permissions dont l'utilisateur dispose
+		permissions, err := userRepo.ListUserPermissions(ctx, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to load user permissions",
+			})
+			return
+		}
+		scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+		if granted := services.EffectiveScopes(scopes, permissions); len(granted) != len(scopes) {
+			c.JSON(http.StatusForbidden, RegisterResponse{
+				Status:  "error",
+				Message: "API token scopes must be a subset of your permissions",
+				Data:    gin.H{"allowed_scopes": permissions},
+			})
+			return
+		}
+
+		count, err := userRepo.CountActivePersonalAccessTokens(ctx, user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to create API token",
+			})
+			return
+		}
+		if count >= apiTokenMaxPerUser {
+			c.JSON(http.StatusConflict, RegisterResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Too many active API tokens (maximum %d), revoke an existing one first", apiTokenMaxPerUser),
+			})
+			return
+		}
+
+		token, tokenHash, prefix, err := services.GeneratePersonalAccessToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate API token: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to create API token",
+			})
+			return
+		}
+
+		pat := &model.PersonalAccessToken{
+			UserID:         user.ID,
+			Name:           req.Name,
+			Prefix:         prefix,
+			TokenHash:      tokenHash,
+			Scopes:         scopes,
+			OrganisationID: user.OrganisationID,
+			ExpiresAt:      time.Now().Add(ttl),
+		}
+		if err := userRepo.CreatePersonalAccessToken(ctx, pat); err != nil {
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to create API token",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionTokenCreated,
+			TargetType: audit.TargetAPIToken,
+			TargetID:   audit.UserTarget(pat.ID),
+			Metadata: map[string]any{
+				"name":       pat.Name,
+				"scopes":     pat.Scopes,
+				"expires_at": pat.ExpiresAt.Format(time.RFC3339),
+			},
+		})
+
+		// Le token en clair n'est retourné qu'une seule fois
+		c.JSON(http.StatusCreated, RegisterResponse{
+			Status:  "success",
+			Message: "API token created, copy it now: it will not be shown again",
+			Data: gin.H{
+				"token":      token,
+				"api_token":  pat,
+				"token_type": "Bearer",
+			},
+		})
+	})
+
+	r.DELETE("/users/me/tokens/:id", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := userRepo.RevokePersonalAccessToken(ctx, claims.UserID, id); err != nil {
+			respondError(c, err, "Failed to revoke API token")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionTokenRevoked,
+			TargetType: audit.TargetAPIToken,
+			TargetID:   c.Param("id"),
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "API token revoked successfully",
+		})
+	})
+
+	// Identités externes (Google, Microsoft, ...) liées au compte
+	r.GET("/users/me/identities", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		identities, err := userRepo.ListUserIdentities(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list linked identities",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   identities,
+		})
+	})
+
+	r.DELETE("/users/me/identities/:id", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := userRepo.DeleteUserIdentity(ctx, claims.UserID, id); err != nil {
+			respondError(c, err, "Failed to unlink identity")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionIdentityUnlinked,
+			TargetType: audit.TargetIdentity,
+			TargetID:   c.Param("id"),
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Identity unlinked successfully",
+		})
+	})
+
+	// Administration des utilisateurs
+	sugar.Info("Setting up /users endpoints...")
+	r.GET("/users", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersRead), forbidImpersonation(), func(c *gin.Context) {
+		filter := database.UserFilter{
+			Role:    c.Query("role"),
+			Company: c.Query("company"),
+		}
+		if value := c.Query("is_active"); value != "" {
+			isActive, err := strconv.ParseBool(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "Invalid is_active filter",
+				})
+				return
+			}
+			filter.IsActive = &isActive
+		}
+		if value := c.Query("organisation_id"); value != "" {
+			orgID, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "Invalid organisation_id filter",
+				})
+				return
+			}
+			id := uint(orgID)
+			filter.OrganisationID = &id
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUserPageSize)))
+		if err != nil || limit <= 0 || limit > maxUserPageSize {
+			limit = defaultUserPageSize
+		}
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		users, total, err := userRepo.List(ctx, filter, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list users",
+			})
+			return
+		}
+
+		data := make([]gin.H, 0, len(users))
+		for _, user := range users {
+			data = append(data, userResponse(user))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   data,
+			"pagination": gin.H{
+				"total":  total,
+				"limit":  limit,
+				"offset": offset,
+			},
+		})
+	})
+
+	r.GET("/users/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersRead), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   userResponse(user),
+		})
+	})
+
+	r.PUT("/users/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersWrite), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req UpdateUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		roleChanged := req.Role != "" && req.Role != user.Role
+		if roleChanged && user.ID == claims.UserID {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "You cannot change your own role",
+			})
+			return
+		}
+
+		if req.Company != "" {
+			user.Company = req.Company
+		}
+		if req.Lastname != "" {
+			user.Lastname = req.Lastname
+		}
+		if req.Firstname != "" {
+			user.Firstname = req.Firstname
+		}
+		if req.Role != "" {
+			user.Role = req.Role
+		}
+		if err := userRepo.Update(ctx, user); err != nil {
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to update user",
+			})
+			return
+		}
+
+		// Les scopes du token dépendent du rôle : les sessions existantes sont révoquées
+		if roleChanged {
+			accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+			if err := revokeUserSessions(ctx, tokenRepo, user.ID, accessExpiry); err != nil {
+				sugar.Errorf("Failed to revoke sessions of user ID=%d: %v", user.ID, err)
+			}
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionUserUpdated,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"role": user.Role, "role_changed": roleChanged},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "User updated successfully",
+			Data:    userResponse(user),
+		})
+	})
+
+	r.DELETE("/users/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersWrite), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+		if id == claims.UserID {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "You cannot delete your own account",
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		// Révoquer avant la suppression : les tokens émis restent valides sans cela
+		accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+		if err := revokeUserSessions(ctx, tokenRepo, user.ID, accessExpiry); err != nil {
+			sugar.Errorf("Failed to revoke sessions of user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to revoke user sessions",
+			})
+			return
+		}
+
+		if err := userRepo.Delete(ctx, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to delete user",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionUserDeleted,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"email": user.Email},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "User deleted successfully",
+		})
+	})
+
+	// Activation / désactivation : la désactivation révoque immédiatement toutes les sessions
+	setUserActive := func(active bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			claims := c.MustGet("claims").(*services.AccessClaims)
+			id, ok := parseIDParam(c)
+			if !ok {
+				return
+			}
+			if !active && id == claims.UserID {
+				c.JSON(http.StatusConflict, gin.H{
+					"status":  "error",
+					"message": "You cannot deactivate your own account",
+				})
+				return
+			}
+
+			user, err := userRepo.FindByID(ctx, id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"status":  "error",
+					"message": "User not found",
+				})
+				return
+			}
+
+			user.IsActive = active
+			if err := userRepo.Update(ctx, user); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"status":  "error",
+					"message": "Failed to update user",
+				})
+				return
+			}
+
+			action := audit.ActionUserActivated
+			if !active {
+				action = audit.ActionUserDeactivated
+				accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+				if err := revokeUserSessions(ctx, tokenRepo, user.ID, accessExpiry); err != nil {
+					sugar.Errorf("Failed to revoke sessions of user ID=%d: %v", user.ID, err)
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"status":  "error",
+						"message": "User deactivated but sessions could not be revoked",
+					})
+					return
+				}
+			}
+
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     action,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+			})
+
+			c.JSON(http.StatusOK, RegisterResponse{
+				Status:  "success",
+				Message: "User updated successfully",
+				Data:    userResponse(user),
+			})
+		}
+	}
+	r.POST("/users/:id/activate", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersWrite), forbidImpersonation(), setUserActive(true))
+	r.POST("/users/:id/deactivate", requireAuth(jwtService, tokenRepo), requirePermission(PermissionUsersWrite), forbidImpersonation(), setUserActive(false))
+
+	// Organisations : appartenance de l'utilisateur et organisation active
+	sugar.Info("Setting up /organisations endpoints...")
+	r.GET("/organisations", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		memberships, err := userRepo.ListUserOrganisations(ctx, claims.UserID)
+		if err != nil {
+			respondError(c, err, "Failed to list organisations")
+			return
+		}
+
+		organisations := make([]gin.H, 0, len(memberships))
+		for _, m := range memberships {
+			organisations = append(organisations, gin.H{
+				"id":      m.OrganisationID,
+				"name":    m.Organisation.Name,
+				"role":    m.Role,
+				"current": m.OrganisationID == claims.OrgID,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   organisations,
+		})
+	})
+
+	// Changement d'organisation active : pris en compte dans le token au prochain /refresh
+	r.POST("/organisations/switch", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req SwitchOrganisationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		if _, err := userRepo.FindMembership(ctx, req.OrganisationID, claims.UserID); err != nil {
+			respondError(c, err, "Failed to switch organisation")
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+		user.OrganisationID = &req.OrganisationID
+		if err := userRepo.Update(ctx, user); err != nil {
+			sugar.Errorf("Failed to switch organisation for user ID=%d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to switch organisation",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionOrganisationSwitched,
+			TargetType: audit.TargetOrganisation,
+			TargetID:   audit.UserTarget(req.OrganisationID),
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Active organisation changed, refresh your tokens to apply it",
+			Data:    gin.H{"organisation_id": req.OrganisationID},
+		})
+	})
+
+	r.GET("/organisations/current", requireAuth(jwtService, tokenRepo), requireOrgRole(userRepo), func(c *gin.Context) {
+		membership := c.MustGet("membership").(*model.OrganisationMember)
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"id":         membership.Organisation.ID,
+				"name":       membership.Organisation.Name,
+				"role":       membership.Role,
+				"created_at": membership.Organisation.CreatedAt.Format(time.RFC3339),
+			},
+		})
+	})
+
+	r.GET("/organisations/current/members", requireAuth(jwtService, tokenRepo), requireOrgRole(userRepo), func(c *gin.Context) {
+		membership := c.MustGet("membership").(*model.OrganisationMember)
+
+		members, err := userRepo.ListOrganisationMembers(ctx, membership.OrganisationID)
+		if err != nil {
+			respondError(c, err, "Failed to list members")
+			return
+		}
+
+		data := make([]gin.H, 0, len(members))
+		for _, m := range members {
+			if m.User == nil {
+				continue
+			}
+			data = append(data, gin.H{
+				"user_id":   m.UserID,
+				"email":     m.User.Email,
+				"firstname": m.User.Firstname,
+				"lastname":  m.User.Lastname,
+				"role":      m.Role,
+				"joined_at": m.CreatedAt.Format(time.RFC3339),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   data,
+		})
+	})
+
+	// Retrait d'un membre : ses sessions sont révoquées (son token porte encore l'org_id)
+	r.DELETE("/organisations/current/members/:id", requireAuth(jwtService, tokenRepo),
+		requireOrgRole(userRepo, model.OrganisationRoleOwner, model.OrganisationRoleAdmin), func(c *gin.Context) {
+			membership := c.MustGet("membership").(*model.OrganisationMember)
+			id, ok := parseIDParam(c)
+			if !ok {
+				return
+			}
+
+			if err := userRepo.RemoveOrganisationMember(ctx, membership.OrganisationID, id); err != nil {
+				respondError(c, err, "Failed to remove member")
+				return
+			}
+
+			accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+			if err := revokeUserSessions(ctx, tokenRepo, id, accessExpiry); err != nil {
+				sugar.Errorf("Failed to revoke sessions of user ID=%d: %v", id, err)
+			}
+
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     audit.ActionMemberRemoved,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(id),
+				Metadata:   map[string]any{"organisation_id": membership.OrganisationID},
+			})
+
+			c.JSON(http.StatusOK, RegisterResponse{
+				Status:  "success",
+				Message: "Member removed successfully",
+			})
+		})
+
+	// Invitations par email dans l'organisation active
+	sugar.Info("Setting up /organisations/current/invitations endpoints...")
+	r.POST("/organisations/current/invitations", requireAuth(jwtService, tokenRepo),
+		requireOrgRole(userRepo, model.OrganisationRoleOwner, model.OrganisationRoleAdmin), func(c *gin.Context) {
+			membership := c.MustGet("membership").(*model.OrganisationMember)
+			claims := c.MustGet("claims").(*services.AccessClaims)
+
+			var req InvitationRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, RegisterResponse{
+					Status:  "error",
+					Message: "Invalid request: " + err.Error(),
+				})
+				return
+			}
+			if req.Role == "" {
+				req.Role = model.OrganisationRoleMember
+			}
+
+			token, tokenHash, err := services.GenerateOpaqueToken()
+			if err != nil {
+				sugar.Errorf("Failed to generate invitation token: %v", err)
+				c.JSON(http.StatusInternalServerError, RegisterResponse{
+					Status:  "error",
+					Message: "Failed to create invitation",
+				})
+				return
+			}
+
+			invitation := &model.OrganisationInvitation{
+				OrganisationID: membership.OrganisationID,
+				Email:          req.Email,
+				Role:           req.Role,
+				TokenHash:      tokenHash,
+				InvitedBy:      &claims.UserID,
+				ExpiresAt:      time.Now().Add(invitationTTL),
+			}
+			if err := userRepo.CreateInvitation(ctx, invitation); err != nil {
+				respondError(c, err, "Failed to create invitation")
+				return
+			}
+
+			if err := sendInvitationEmail(membership.Organisation, claims, invitation.Email, token); err != nil {
+				sugar.Errorf("Failed to send invitation ID=%d: %v", invitation.ID, err)
+			}
+
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     audit.ActionInvitationSent,
+				TargetType: audit.TargetInvitation,
+				TargetID:   audit.UserTarget(invitation.ID),
+				Metadata: map[string]any{
+					"organisation_id": invitation.OrganisationID,
+					"email":           invitation.Email,
+					"role":            invitation.Role,
+				},
+			})
+
+			c.JSON(http.StatusCreated, RegisterResponse{
+				Status:  "success",
+				Message: "Invitation sent successfully",
+				Data:    invitation,
+			})
+		})
+
+	r.GET("/organisations/current/invitations", requireAuth(jwtService, tokenRepo),
+		requireOrgRole(userRepo, model.OrganisationRoleOwner, model.OrganisationRoleAdmin), func(c *gin.Context) {
+			membership := c.MustGet("membership").(*model.OrganisationMember)
+
+			invitations, err := userRepo.ListPendingInvitations(ctx, membership.OrganisationID)
+			if err != nil {
+				respondError(c, err, "Failed to list invitations")
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"status": "success",
+				"data":   invitations,
+			})
+		})
+
+	r.DELETE("/organisations/current/invitations/:id", requireAuth(jwtService, tokenRepo),
+		requireOrgRole(userRepo, model.OrganisationRoleOwner, model.OrganisationRoleAdmin), func(c *gin.Context) {
+			membership := c.MustGet("membership").(*model.OrganisationMember)
+			id, ok := parseIDParam(c)
+			if !ok {
+				return
+			}
+
+			if err := userRepo.DeleteInvitation(ctx, membership.OrganisationID, id); err != nil {
+				respondError(c, err, "Failed to revoke invitation")
+				return
+			}
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     audit.ActionInvitationRevoked,
+				TargetType: audit.TargetInvitation,
+				TargetID:   audit.UserTarget(id),
+				Metadata:   map[string]any{"organisation_id": membership.OrganisationID},
+			})
+
+			c.JSON(http.StatusOK, RegisterResponse{
+				Status:  "success",
+				Message: "Invitation revoked successfully",
+			})
+		})
+
+	// Acceptation d'une invitation par un utilisateur déjà inscrit
+	r.POST("/organisations/invitations/accept", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+
+		invitation, err := userRepo.AcceptInvitation(ctx, services.HashOpaqueToken(req.Token), user)
+		if err != nil {
+			respondError(c, err, "Failed to accept invitation")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionInvitationAccepted,
+			TargetType: audit.TargetInvitation,
+			TargetID:   audit.UserTarget(invitation.ID),
+			Metadata:   map[string]any{"organisation_id": invitation.OrganisationID},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Invitation accepted, refresh your tokens to switch to the new organisation",
+			Data:    gin.H{"organisation_id": invitation.OrganisationID},
+		})
+	})
+
+	// Sessions endpoint : liste des appareils connectés
+	sugar.Info("Setting up /sessions endpoints...")
+	r.GET("/sessions", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		sessions, err := tokenRepo.ListSessions(ctx, claims.UserID)
+		if err != nil {
+			sugar.Errorf("Failed to list sessions for user ID=%d: %v", claims.UserID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list sessions",
+			})
+			return
+		}
+
+		sessionsData := make([]gin.H, 0, len(sessions))
+		for _, session := range sessions {
+			sessionsData = append(sessionsData, gin.H{
+				"id":           session.ID,
+				"user_agent":   session.UserAgent,
+				"ip":           session.IP,
+				"created_at":   session.CreatedAt.Format(time.RFC3339),
+				"last_used_at": session.LastUsedAt.Format(time.RFC3339),
+				"expires_at":   session.ExpiresAt.Format(time.RFC3339),
+				"current":      session.ID == claims.ID,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   sessionsData,
+		})
+	})
+
+	// Fermer une session spécifique
+	r.DELETE("/sessions/:id", requireAuth(jwtService, tokenRepo), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		sessionID := c.Param("id")
+
+		// Vérifier que la session appartient bien à l'utilisateur
+		if _, err := tokenRepo.GetSession(ctx, claims.UserID, sessionID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Session not found",
+			})
+			return
+		}
+
+		if err := tokenRepo.DeleteRefreshToken(ctx, claims.UserID, sessionID); err != nil {
+			sugar.Errorf("Failed to delete refresh token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to revoke session",
+			})
+			return
+		}
+
+		// L'access token de la session partage son JTI : le blacklister jusqu'à expiration
+		accessExpiry := time.Now().Add(jwtService.GetAccessTokenTTL()).Unix()
+		if err := tokenRepo.BlacklistToken(ctx, sessionID, accessExpiry); err != nil {
+			sugar.Errorf("Failed to blacklist token: %v", err)
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionSessionRevoked,
+			TargetType: audit.TargetSession,
+			TargetID:   sessionID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Session revoked successfully",
+		})
+	})
+
+	// ==================== OPENID CONNECT (FOURNISSEUR) ====================
+
+	// validateAuthorizeRequest vérifie une demande d'autorisation : client connu et redirect_uri
+	// enregistrée (sinon l'erreur ne peut pas être renvoyée au client), puis response_type,
+	// scopes et PKCE (erreurs renvoyées sur la redirect_uri)
+	validateAuthorizeRequest := func(req AuthorizeRequest) (*model.OAuthClient, *oauthError) {
+		client, err := userRepo.FindOAuthClient(ctx, req.ClientID)
+		if err != nil {
+			return nil, &oauthError{Code: "invalid_client", Description: "Unknown client_id"}
+		}
+		if !client.AllowsRedirectURI(req.RedirectURI) {
+			return nil, &oauthError{Code: "invalid_request", Description: "redirect_uri is not registered for this client"}
+		}
+		if req.ResponseType != "code" {
+			return nil, &oauthError{Code: "unsupported_response_type", Description: "Only response_type=code is supported", Redirect: true}
+		}
+		scopes := strings.Fields(req.Scope)
+		if !slices.Contains(scopes, services.ScopeOpenID) {
+			return nil, &oauthError{Code: "invalid_scope", Description: "The openid scope is required", Redirect: true}
+		}
+		for _, scope := range scopes {
+			if !client.AllowsScope(scope) {
+				return nil, &oauthError{Code: "invalid_scope", Description: "Scope not allowed: " + scope, Redirect: true}
+			}
+		}
+		if req.CodeChallenge == "" || req.CodeChallengeMethod != services.PKCEMethodS256 {
+			return nil, &oauthError{Code: "invalid_request", Description: "PKCE with code_challenge_method=S256 is required", Redirect: true}
+		}
+		return client, nil
+	}
+
+	// GET /.well-known/openid-configuration : document de découverte
+	r.GET("/.well-known/openid-configuration", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.JSON(http.StatusOK, jwtService.OIDCDiscovery(oidcIssuer))
+	})
+
+	// GET /authorize : point d'entrée navigateur. Après validation, l'utilisateur est envoyé sur la
+	// page de consentement /oauth/authorize de l'application Angular : elle passe par la connexion
+	// si besoin, puis appelle POST /authorize avec la décision de l'utilisateur.
+	r.GET("/authorize", func(c *gin.Context) {
+		var req AuthorizeRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: err.Error()})
+			return
+		}
+
+		if _, oerr := validateAuthorizeRequest(req); oerr != nil {
+			if oerr.Redirect {
+				c.Redirect(http.StatusFound, oauthRedirectURL(req.RedirectURI, url.Values{
+					"error":             {oerr.Code},
+					"error_description": {oerr.Description},
+					"state":             {req.State},
+				}))
+				return
+			}
+			respondOAuthError(c, http.StatusBadRequest, oerr)
+			return
+		}
+
+		c.Redirect(http.StatusFound, appBaseURL+"/oauth/authorize?"+c.Request.URL.RawQuery)
+	})
+
+	// POST /authorize : appelé par la page de consentement pour l'utilisateur connecté ; émet un
+	// code d'autorisation à usage unique, ou l'erreur access_denied si l'utilisateur refuse, et
+	// retourne l'URL de retour vers le client
+	r.POST("/authorize", requireAuth(jwtService, tokenRepo), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req AuthorizeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: err.Error()})
+			return
+		}
+
+		client, oerr := validateAuthorizeRequest(req)
+		if oerr != nil {
+			respondOAuthError(c, http.StatusBadRequest, oerr)
+			return
+		}
+
+		if req.Deny {
+			auditLog.RecordRequest(c, audit.Event{
+				Action:     audit.ActionOAuthAuthorized,
+				TargetType: audit.TargetOAuthClient,
+				TargetID:   client.ClientID,
+				Outcome:    audit.OutcomeDenied,
+				Metadata:   map[string]any{"scope": req.Scope},
+			})
+
+			c.JSON(http.StatusOK, RegisterResponse{
+				Status:  "success",
+				Message: "Authorization denied",
+				Data: gin.H{
+					"redirect_to": oauthRedirectURL(req.RedirectURI, url.Values{
+						"error":             {"access_denied"},
+						"error_description": {"The user denied the request"},
+						"state":             {req.State},
+					}),
+				},
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil || !user.IsActive {
+			respondOAuthError(c, http.StatusForbidden, &oauthError{Code: "access_denied", Description: "User account is disabled"})
+			return
+		}
+
+		// auth_time : date de la connexion à l'origine de la session
+		authTime := claims.IssuedAt.Time
+		if session, err := tokenRepo.GetSession(ctx, claims.UserID, claims.ID); err == nil {
+			authTime = session.CreatedAt
+		}
+
+		code, codeHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate authorization code: %v", err)
+			respondOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: "Failed to issue authorization code"})
+			return
+		}
+		if err := tokenRepo.StoreAuthorizationCode(ctx, codeHash, &model.AuthorizationCode{
+			ClientID:            client.ClientID,
+			UserID:              user.ID,
+			RedirectURI:         req.RedirectURI,
+			Scope:               strings.Join(strings.Fields(req.Scope), " "),
+			Nonce:               req.Nonce,
+			CodeChallenge:       req.CodeChallenge,
+			CodeChallengeMethod: req.CodeChallengeMethod,
+			AuthTime:            authTime,
+		}, oidcCodeTTL); err != nil {
+			sugar.Errorf("Failed to store authorization code: %v", err)
+			respondOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: "Failed to issue authorization code"})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionOAuthAuthorized,
+			TargetType: audit.TargetOAuthClient,
+			TargetID:   client.ClientID,
+			Metadata:   map[string]any{"scope": req.Scope},
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "Authorization granted",
+			Data: gin.H{
+				"redirect_to": oauthRedirectURL(req.RedirectURI, url.Values{
+					"code":  {code},
+					"state": {req.State},
+				}),
+			},
+		})
+	})
+
+	// POST /token : échange d'un code d'autorisation (PKCE) ou d'un refresh token (RFC 6749)
+	r.POST("/token", func(c *gin.Context) {
+		// Les réponses contiennent des tokens : elles ne doivent jamais être mises en cache
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		// Authentification du client : HTTP Basic ou paramètres du formulaire
+		clientID, clientSecret, basicAuth := c.Request.BasicAuth()
+		if !basicAuth {
+			clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+		}
+		client, err := userRepo.FindOAuthClient(ctx, clientID)
+		if err == nil && client.IsConfidential() && !services.VerifyClientSecret(clientSecret, client.SecretHash) {
+			err = errors.New("invalid client secret")
+		}
+		if err != nil {
+			if basicAuth {
+				c.Header("WWW-Authenticate", `Basic realm="token"`)
+			}
+			respondOAuthError(c, http.StatusUnauthorized, &oauthError{Code: "invalid_client", Description: "Client authentication failed"})
+			return
+		}
+
+		switch c.PostForm("grant_type") {
+		case "authorization_code":
+			code, err := tokenRepo.ConsumeAuthorizationCode(ctx, services.HashOpaqueToken(c.PostForm("code")))
+			if err != nil || code.ClientID != client.ClientID || code.RedirectURI != c.PostForm("redirect_uri") {
+				respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "Invalid or expired authorization code"})
+				return
+			}
+			if !services.VerifyPKCE(c.PostForm("code_verifier"), code.CodeChallenge, code.CodeChallengeMethod) {
+				respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "PKCE verification failed"})
+				return
+			}
+
+			user, err := userRepo.FindByID(ctx, code.UserID)
+			if err != nil || !user.IsActive {
+				respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "User account is disabled"})
+				return
+			}
+
+			tokens, err := startClientSession(c, user, client.ClientID, code.Scope)
+			if err != nil {
+				sugar.Errorf("Failed to start session for user ID=%d: %v", user.ID, err)
+				respondOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: "Failed to issue tokens"})
+				return
+			}
+
+			scopes := strings.Fields(code.Scope)
+			idToken, err := jwtService.GenerateIDToken(oidcIssuer, client.ClientID, code.Nonce, code.AuthTime, services.OIDCUserClaims(user, scopes))
+			if err != nil {
+				sugar.Errorf("Failed to generate ID token: %v", err)
+				respondOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: "Failed to issue tokens"})
+				return
+			}
+
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionOAuthTokenIssued,
+				TargetType: audit.TargetOAuthClient,
+				TargetID:   client.ClientID,
+				Metadata:   map[string]any{"grant_type": "authorization_code", "session_id": tokens.TokenID},
+			})
+
+			response := gin.H{
+				"access_token": tokens.AccessToken,
+				"token_type":   "Bearer",
+				"expires_in":   tokens.AccessExp - time.Now().Unix(),
+				"id_token":     idToken,
+				"scope":        code.Scope,
+			}
+			if tokens.RefreshToken != "" {
+				response["refresh_token"] = tokens.RefreshToken
+			}
+			c.JSON(http.StatusOK, response)
+
+		case "refresh_token":
+			_, tokens, err := rotateRefreshToken(c, c.PostForm("refresh_token"), client.ClientID)
+			if err != nil {
+				var rerr *refreshError
+				if errors.As(err, &rerr) && rerr.Status < http.StatusInternalServerError {
+					respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: rerr.Message})
+					return
+				}
+				respondOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: "Failed to issue tokens"})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"access_token":  tokens.AccessToken,
+				"token_type":    "Bearer",
+				"expires_in":    tokens.AccessExp - time.Now().Unix(),
+				"refresh_token": tokens.RefreshToken,
+				"scope":         tokens.Scope,
+			})
+
+		default:
+			respondOAuthError(c, http.StatusBadRequest, &oauthError{Code: "unsupported_grant_type", Description: "Supported grant types: authorization_code, refresh_token"})
+		}
+	})
+
+	// GET|POST /userinfo : claims OpenID Connect de l'utilisateur du token. Le token d'un client
+	// ne donne accès qu'aux claims des scopes accordés ; celui de l'application au profil et à l'email.
+	userinfo := func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		scopes := []string{services.ScopeProfile, services.ScopeEmail}
+		if claims.ClientID != "" {
+			scopes = claims.Scopes()
+			if !slices.Contains(scopes, services.ScopeOpenID) {
+				c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+				respondOAuthError(c, http.StatusForbidden, &oauthError{Code: "insufficient_scope", Description: "The openid scope is required"})
+				return
+			}
+		}
+
+		user, err := userRepo.FindByID(ctx, claims.UserID)
+		if err != nil || !user.IsActive {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondOAuthError(c, http.StatusUnauthorized, &oauthError{Code: "invalid_token", Description: "User not found or disabled"})
+			return
+		}
+
+		c.JSON(http.StatusOK, services.OIDCUserClaims(user, scopes))
+	}
+	r.GET("/userinfo", requireUserinfoToken(jwtService, tokenRepo), userinfo)
+	r.POST("/userinfo", requireUserinfoToken(jwtService, tokenRepo), userinfo)
+
+	// GET /oauth/clients : liste des clients enregistrés
+	r.GET("/oauth/clients", requireAuth(jwtService, tokenRepo), requirePermission(PermissionClientsRead), forbidImpersonation(), func(c *gin.Context) {
+		clients, err := userRepo.ListOAuthClients(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list OAuth clients",
+			})
+			return
+		}
+
+		data := make([]gin.H, 0, len(clients))
+		for _, client := range clients {
+			data = append(data, oauthClientResponse(client))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   data,
+		})
+	})
+
+	// POST /oauth/clients : enregistre une application (le secret d'un client confidentiel n'est affiché qu'une fois)
+	r.POST("/oauth/clients", requireAuth(jwtService, tokenRepo), requirePermission(PermissionClientsWrite), forbidImpersonation(), func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+
+		var req OAuthClientRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+		for _, uri := range req.RedirectURIs {
+			if err := validateRedirectURI(uri); err != nil {
+				c.JSON(http.StatusBadRequest, RegisterResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("Invalid redirect URI %q: %v", uri, err),
+				})
+				return
+			}
+		}
+		scopes := req.Scopes
+		if len(scopes) == 0 {
+			scopes = services.SupportedOIDCScopes
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(services.SupportedOIDCScopes, scope) {
+				c.JSON(http.StatusBadRequest, RegisterResponse{
+					Status:  "error",
+					Message: "Unsupported scope: " + scope,
+				})
+				return
+			}
+		}
+		if !slices.Contains(scopes, services.ScopeOpenID) {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "The openid scope is required",
+			})
+			return
+		}
+
+		clientID, _, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate client ID: %v", err)
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to create OAuth client",
+			})
+			return
+		}
+		client := &model.OAuthClient{
+			ClientID:     clientID[:32],
+			Name:         strings.TrimSpace(req.Name),
+			RedirectURIs: req.RedirectURIs,
+			Scopes:       scopes,
+			CreatedBy:    &claims.UserID,
+		}
+
+		var secret string
+		if req.Confidential {
+			secret, client.SecretHash, err = services.GenerateOpaqueToken()
+			if err != nil {
+				sugar.Errorf("Failed to generate client secret: %v", err)
+				c.JSON(http.StatusInternalServerError, RegisterResponse{
+					Status:  "error",
+					Message: "Failed to create OAuth client",
+				})
+				return
+			}
+		}
+
+		if err := userRepo.CreateOAuthClient(ctx, client); err != nil {
+			c.JSON(http.StatusInternalServerError, RegisterResponse{
+				Status:  "error",
+				Message: "Failed to create OAuth client",
+			})
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionOAuthClientCreated,
+			TargetType: audit.TargetOAuthClient,
+			TargetID:   client.ClientID,
+			Metadata:   map[string]any{"name": client.Name, "confidential": req.Confidential},
+		})
+
+		data := oauthClientResponse(client)
+		if secret != "" {
+			data["client_secret"] = secret
+		}
+		c.JSON(http.StatusCreated, RegisterResponse{
+			Status:  "success",
+			Message: "OAuth client created successfully",
+			Data:    data,
+		})
+	})
+
+	// DELETE /oauth/clients/:id : supprime un client (les codes en cours deviennent inutilisables)
+	r.DELETE("/oauth/clients/:id", requireAuth(jwtService, tokenRepo), requirePermission(PermissionClientsWrite), forbidImpersonation(), func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := userRepo.DeleteOAuthClient(ctx, id); err != nil {
+			respondError(c, err, "Failed to delete OAuth client")
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			Action:     audit.ActionOAuthClientDeleted,
+			TargetType: audit.TargetOAuthClient,
+			TargetID:   c.Param("id"),
+		})
+
+		c.JSON(http.StatusOK, RegisterResponse{
+			Status:  "success",
+			Message: "OAuth client deleted successfully",
+		})
+	})
+
+	// ==================== FOURNISSEURS D'IDENTITÉ EXTERNES ====================
+
+	// ssoErrorRedirect renvoie le navigateur vers la page de connexion du front avec un code d'erreur
+	ssoErrorRedirect := func(c *gin.Context, code string) {
+		c.Redirect(http.StatusFound, appBaseURL+"/login?"+url.Values{"sso_error": {code}}.Encode())
+	}
+
+	// provisionExternalUser crée à la volée le compte d'une identité externe inconnue et le rattache
+	// à une organisation : celle d'une invitation en attente pour cet email, sinon celle configurée
+	// pour le fournisseur, sinon une nouvelle agence. L'email a été vérifié par le fournisseur.
+	provisionExternalUser := func(c *gin.Context, provider *services.ExternalProvider, identity *services.ExternalIdentity) (*model.User, error) {
+		firstname, lastname := identity.GivenName, identity.FamilyName
+		if firstname == "" && lastname == "" {
+			firstname, lastname, _ = strings.Cut(strings.TrimSpace(identity.Name), " ")
+		}
+		if firstname == "" {
+			firstname, _, _ = strings.Cut(identity.Email, "@")
+		}
+
+		// Mot de passe aléatoire jamais communiqué : la connexion par mot de passe passe par un reset
+		randomPassword, _, err := services.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword, err := passwordHasher.Hash(randomPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		user := &model.User{
+			Company:         strings.TrimSpace(firstname + " " + lastname),
+			Lastname:        lastname,
+			Firstname:       firstname,
+			Email:           identity.Email,
+			Password:        hashedPassword,
+			Role:            "user",
+			IsActive:        true,
+			EmailVerifiedAt: &now,
+		}
+
+		invitation, err := userRepo.FindPendingInvitationByEmail(ctx, identity.Email)
+		if err != nil && !errors.Is(err, autherrors.ErrInvitationNotFound) {
+			return nil, err
+		}
+		organisationID := provider.Config().OrganisationID
+		if invitation != nil {
+			organisationID = invitation.OrganisationID
+		}
+		if organisationID != 0 {
+			org, err := userRepo.FindOrganisationByID(ctx, organisationID)
+			if err != nil {
+				return nil, err
+			}
+			user.Company = org.Name
+		}
+
+		if err := userRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case invitation != nil:
+			if _, err := userRepo.AcceptInvitation(ctx, invitation.TokenHash, user); err != nil {
+				return nil, err
+			}
+		case organisationID != 0:
+			if err := userRepo.JoinOrganisation(ctx, organisationID, user, model.OrganisationRoleMember); err != nil {
+				return nil, err
+			}
+		default:
+			org := &model.Organisation{Name: user.Company}
+			if err := userRepo.CreateOrganisation(ctx, org, user.ID); err != nil {
+				return nil, err
+			}
+			user.OrganisationID = &org.ID
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionRegister,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata: map[string]any{
+				"provider":        provider.Config().Name,
+				"invited":         invitation != nil,
+				"organisation_id": user.OrganisationID,
+			},
+		})
+		return user, nil
+	}
+
+	// GET /sso/providers : fournisseurs d'identité proposés sur la page de connexion
+	r.GET("/sso/providers", func(c *gin.Context) {
+		providers := make([]gin.H, 0)
+		for _, provider := range ssoProviders.List() {
+			config := provider.Config()
+			providers = append(providers, gin.H{
+				"name":         config.Name,
+				"display_name": config.DisplayName,
+				"login_url":    oidcIssuer + "/sso/" + config.Name + "/login",
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   providers,
+		})
+	})
+
+	// GET /sso/:provider/login : redirige le navigateur vers le fournisseur. Le state, le nonce
+	// et le code_verifier PKCE sont conservés dans Redis jusqu'au retour sur le callback.
+	r.GET("/sso/:provider/login", func(c *gin.Context) {
+		provider, found := ssoProviders.Get(c.Param("provider"))
+		if !found {
+			ssoErrorRedirect(c, "unknown_provider")
+			return
+		}
+
+		state, stateHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate SSO state: %v", err)
+			ssoErrorRedirect(c, "server_error")
+			return
+		}
+		nonce, _, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate SSO nonce: %v", err)
+			ssoErrorRedirect(c, "server_error")
+			return
+		}
+		verifier, challenge, err := services.GeneratePKCE()
+		if err != nil {
+			sugar.Errorf("Failed to generate PKCE verifier: %v", err)
+			ssoErrorRedirect(c, "server_error")
+			return
+		}
+
+		authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+		if err != nil {
+			sugar.Errorf("SSO provider %s unavailable: %v", provider.Config().Name, err)
+			ssoErrorRedirect(c, "provider_unavailable")
+			return
+		}
+
+		ssoState := &model.SSOState{
+			Provider:     provider.Config().Name,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			CreatedAt:    time.Now(),
+		}
+		if returnTo := c.Query("return_to"); isLocalPath(returnTo) {
+			ssoState.ReturnTo = returnTo
+		}
+		if err := tokenRepo.StoreSSOState(ctx, stateHash, ssoState, ssoStateTTL); err != nil {
+			sugar.Errorf("Failed to store SSO state: %v", err)
+			ssoErrorRedirect(c, "server_error")
+			return
+		}
+
+		c.Redirect(http.StatusFound, authURL)
+	})
+
+	// GET /sso/:provider/callback : retour du fournisseur. L'identité vérifiée est résolue vers un
+	// compte (identité déjà liée, sinon compte existant avec le même email, vérifié chez le
+	// fournisseur et localement, sinon création),
+	// puis le front reçoit un code à usage unique à échanger contre les tokens sur /sso/complete :
+	// les tokens ne transitent jamais dans une URL.
+	r.GET("/sso/:provider/callback", func(c *gin.Context) {
+		providerName := c.Param("provider")
+		provider, found := ssoProviders.Get(providerName)
+		if !found {
+			ssoErrorRedirect(c, "unknown_provider")
+			return
+		}
+
+		// fail audite l'échec de la connexion externe et renvoie le navigateur vers le front
+		fail := func(code string, email string, user *model.User) {
+			event := audit.Event{
+				ActorEmail: email,
+				Action:     audit.ActionSSOAuthenticated,
+				Outcome:    audit.OutcomeFailure,
+				Metadata:   map[string]any{"provider": providerName, "reason": code},
+			}
+			if user != nil {
+				event.ActorID, event.ActorEmail = user.ID, user.Email
+				event.TargetType, event.TargetID = audit.TargetUser, audit.UserTarget(user.ID)
+				event.Outcome = audit.OutcomeDenied
+			}
+			auditLog.RecordRequest(c, event)
+			ssoErrorRedirect(c, code)
+		}
+
+		ssoState, err := tokenRepo.ConsumeSSOState(ctx, services.HashOpaqueToken(c.Query("state")))
+		if err != nil || ssoState.Provider != providerName {
+			fail("invalid_state", "", nil)
+			return
+		}
+
+		// Refus de l'utilisateur ou erreur chez le fournisseur
+		if providerError := c.Query("error"); providerError != "" {
+			sugar.Warnf("SSO provider %s returned an error: %s (%s)", providerName, providerError, c.Query("error_description"))
+			if providerError == "access_denied" {
+				fail("access_denied", "", nil)
+			} else {
+				fail("provider_error", "", nil)
+			}
+			return
+		}
+
+		identity, err := provider.Exchange(ctx, c.Query("code"), ssoState.CodeVerifier, ssoState.Nonce)
+		if err != nil {
+			sugar.Warnf("SSO login with %s failed: %v", providerName, err)
+			if errors.Is(err, services.ErrExternalIdentityInvalid) {
+				fail("invalid_identity", "", nil)
+			} else {
+				fail("provider_unavailable", "", nil)
+			}
+			return
+		}
+		if identity.Email != "" && !provider.AllowsEmail(identity.Email) {
+			fail("domain_not_allowed", identity.Email, nil)
+			return
+		}
+
+		var user *model.User
+		linked, err := userRepo.FindUserIdentity(ctx, providerName, identity.Subject)
+		switch {
+		case err == nil:
+			// Identité déjà liée : le subject fait foi, même si l'email a changé chez le fournisseur
+			user, err = userRepo.FindByID(ctx, linked.UserID)
+			if err != nil {
+				fail("server_error", identity.Email, nil)
+				return
+			}
+			if err := userRepo.TouchUserIdentity(ctx, linked.ID, identity.Email); err != nil {
+				sugar.Errorf("Failed to update identity ID=%d: %v", linked.ID, err)
+			}
+
+		case errors.Is(err, autherrors.ErrIdentityNotFound):
+			// Sans email vérifié par le fournisseur, impossible de lier ou de créer un compte
+			if !identity.EmailVerified {
+				fail("email_not_verified", identity.Email, nil)
+				return
+			}
+
+			existing, err := userRepo.FindByEmail(ctx, identity.Email)
+			var notFound *autherrors.UserNotFoundError
+			if errors.As(err, &notFound) {
+				existing, err = nil, nil
+			}
+			if err != nil {
+				fail("server_error", identity.Email, nil)
+				return
+			}
+
+			switch services.ResolveExternalAccount(identity, existing) {
+			case services.ExternalAccountRejectUnverifiedIdentity:
+				fail("email_not_verified", identity.Email, nil)
+				return
+			case services.ExternalAccountRejectUnverifiedAccount:
+				// Le titulaire du compte local doit d'abord vérifier son email
+				fail("account_not_verified", identity.Email, existing)
+				return
+			case services.ExternalAccountLink:
+				user = existing
+			case services.ExternalAccountProvision:
+				user, err = provisionExternalUser(c, provider, identity)
+				if err != nil {
+					sugar.Errorf("Failed to provision user from %s identity: %v", providerName, err)
+					fail("server_error", identity.Email, nil)
+					return
+				}
+			}
+
+			now := time.Now()
+			if err := userRepo.LinkUserIdentity(ctx, &model.UserIdentity{
+				UserID:      user.ID,
+				Provider:    providerName,
+				Subject:     identity.Subject,
+				Email:       identity.Email,
+				LastLoginAt: &now,
+			}); err != nil {
+				fail("server_error", identity.Email, user)
+				return
+			}
+			auditLog.RecordRequest(c, audit.Event{
+				ActorID:    user.ID,
+				ActorEmail: user.Email,
+				Action:     audit.ActionIdentityLinked,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.UserTarget(user.ID),
+				Metadata:   map[string]any{"provider": providerName},
+			})
+
+		default:
+			fail("server_error", identity.Email, nil)
+			return
+		}
+
+		if !user.IsActive {
+			fail("account_disabled", identity.Email, user)
+			return
+		}
+
+		code, codeHash, err := services.GenerateOpaqueToken()
+		if err != nil {
+			sugar.Errorf("Failed to generate SSO login code: %v", err)
+			fail("server_error", identity.Email, user)
+			return
+		}
+		if err := tokenRepo.StoreSSOLoginCode(ctx, user.ID, codeHash, ssoLoginCodeTTL); err != nil {
+			sugar.Errorf("Failed to store SSO login code: %v", err)
+			fail("server_error", identity.Email, user)
+			return
+		}
+
+		auditLog.RecordRequest(c, audit.Event{
+			ActorID:    user.ID,
+			ActorEmail: user.Email,
+			Action:     audit.ActionSSOAuthenticated,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.UserTarget(user.ID),
+			Metadata:   map[string]any{"provider": providerName},
+		})
+
+		params := url.Values{"code": {code}}
+		if ssoState.ReturnTo != "" {
+			params.Set("return_to", ssoState.ReturnTo)
+		}
+		c.Redirect(http.StatusFound, appBaseURL+"/sso/callback?"+params.Encode())
+	})
+
+	// POST /sso/complete : échange le code remis au front contre les tokens (même réponse que /login).
+	// La 2FA reste exigée : l'authentification du fournisseur ne remplace pas le second facteur local.
+	r.POST("/sso/complete", func(c *gin.Context) {
+		var req SSOCompleteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+
+		userID, err := tokenRepo.ConsumeSSOLoginCode(ctx, services.HashOpaqueToken(req.Code))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid or expired login code",
+			})
+			return
+		}
+
+		user, err := userRepo.FindByID(ctx, userID)
+		if err != nil || !user.IsActive {
+			c.JSON(http.StatusUnauthorized, RegisterResponse{
+				Status:  "error",
+				Message: "Invalid or expired login code",
+			})
+			return
+		}
+
+		if user.IsTOTPEnabled() {
+			startMFAChallenge(c, user)
+			return
+		}
+
+		completeLogin(c, user)
+	})
+
+	// ==================== JOURNAL D'AUDIT ====================
+
+	// GET /audit-logs : consultation filtrée et paginée ; ?format=csv (ou Accept: text/csv) exporte en CSV
+	r.GET("/audit-logs", requireAuth(jwtService, tokenRepo), requirePermission(PermissionAuditRead), forbidImpersonation(), func(c *gin.Context) {
+		filter, err := parseAuditLogFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if c.Query("format") == "csv" || strings.Contains(c.GetHeader("Accept"), "text/csv") {
+			exportAuditLogsCSV(c, userRepo, filter)
+			auditLog.RecordRequest(c, audit.Event{
+				Action:   audit.ActionAuditLogsExported,
+				Metadata: map[string]any{"query": c.Request.URL.RawQuery},
+			})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAuditPageSize)))
+		if err != nil || limit <= 0 || limit > maxAuditPageSize {
+			limit = defaultAuditPageSize
+		}
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		entries, total, err := userRepo.ListAuditLogs(ctx, filter, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to list audit logs",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   entries,
+			"pagination": gin.H{
+				"total":  total,
+				"limit":  limit,
+				"offset": offset,
+			},
+		})
+	})
+
+	port := getEnv("AUTH_SERVICE_PORT", "8081")
+	sugar.Infof("Auth-service started on port %s", port)
+
+	if err := r.Run(":" + port); err != nil {
+		sugar.Fatalf("Server startup error: %v", err)
+	}
+}
+
+// getEnv récupère une variable d'environnement avec une valeur par défaut
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getDurationEnv récupère une durée (format time.ParseDuration) avec une valeur par défaut
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// Remplacez cette partie dans votre main.go
+func initRedis(redisURL string, sugar *zap.SugaredLogger) *redis.Client {
+	// Si vous avez une URL Redis complète avec mot de passe
+	if strings.HasPrefix(redisURL, "redis://") {
+		opt, err := redis.ParseURL(redisURL)
+		if err != nil {
+			sugar.Fatalf("Failed to parse Redis URL: %v", err)
+		}
+		return redis.NewClient(opt)
+	}
+	sugar.Info("Using manual Redis configuration from environment variables")
+	sugar.Infof("Redis Addr: %s", getEnv("REDIS_ADDR", "localhost:6379"))
+	sugar.Infof("Redis DB: %d", getIntEnv("REDIS_DB", 0))
+	sugar.Infof("Redis Pool Size: %d", getIntEnv("REDIS_POOL_SIZE", 10))
+
+	// Configuration manuelle avec mot de passe
+	client := redis.NewClient(&redis.Options{
+		Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
+		Password: getEnv("REDIS_PASSWORD", ""), // Mot de passe
+		DB:       getIntEnv("REDIS_DB", 0),     // Base de données
+		PoolSize: getIntEnv("REDIS_POOL_SIZE", 10),
+	})
+
+	return client
+}
+
+// Fonction helper pour les entiers
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+// getBoolEnv récupère une variable d'environnement booléenne avec une valeur par défaut
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// requireAuth vérifie l'access token Bearer et place ses claims dans le contexte Gin
+// sous les clés "claims" (*services.AccessClaims) et "access_token" (string)
+func requireAuth(jwtService *services.JWTService, tokenRepo database.TokenRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Extraire le token de l'en-tête Authorization
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Authorization header required",
+			})
+			return
+		}
+
+		// Vérifier le format "Bearer <token>"
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid authorization header format",
+			})
+			return
+		}
+
+		claims, err := jwtService.ValidateAccessToken(parts[1])
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid token: " + err.Error(),
+			})
+			return
+		}
+
+		// Un token révoqué (logout) ne doit plus donner accès aux endpoints protégés
+		if isAccessTokenRevoked(c.Request.Context(), tokenRepo, claims) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Token has been revoked",
+			})
+			return
+		}
+
+		c.Set("claims", claims)
+		c.Set("access_token", parts[1])
+		c.Next()
+	}
+}
+
+// requireUserinfoToken authentifie l'endpoint /userinfo : il accepte le token délivré à un client
+// OpenID Connect comme celui de l'application. Les erreurs suivent RFC 6750 (WWW-Authenticate).
+func requireUserinfoToken(jwtService *services.JWTService, tokenRepo database.TokenRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			respondOAuthError(c, http.StatusUnauthorized, &oauthError{Code: "invalid_token", Description: "Bearer token required"})
+			return
+		}
+
+		claims, err := jwtService.ValidateClientAccessToken(parts[1])
+		if err != nil {
+			claims, err = jwtService.ValidateAccessToken(parts[1])
+		}
+		if err != nil || isAccessTokenRevoked(c.Request.Context(), tokenRepo, claims) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondOAuthError(c, http.StatusUnauthorized, &oauthError{Code: "invalid_token", Description: "Invalid or revoked access token"})
+			return
+		}
+
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// tokenSubject construit le sujet des tokens JWT à partir d'un utilisateur.
+// Les permissions effectives (rôle principal + rôles attribués) deviennent les scopes du token.
+func tokenSubject(ctx context.Context, userRepo repository.UserRepository, user *model.User, emailPolicy string) (services.TokenSubject, error) {
+	scopes, err := userRepo.ListUserPermissions(ctx, user)
+	if err != nil {
+		return services.TokenSubject{}, err
+	}
+	// Email non vérifié (politique "limited") : les permissions d'écriture sont retenues jusqu'à
+	// la vérification, puis rendues au refresh suivant (les permissions y sont rechargées)
+	if emailPolicy == EmailVerificationLimited && !user.IsEmailVerified() {
+		scopes = readOnlyScopes(scopes)
+	}
+	var orgID uint
+	if user.OrganisationID != nil {
+		orgID = *user.OrganisationID
+	}
+	return services.TokenSubject{
+		UserID:        user.ID,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.IsEmailVerified(),
+		Scopes:        scopes,
+		OrgID:         orgID,
+	}, nil
+}
+
+// readOnlyScopes ne conserve que les permissions de consultation ("properties:read"...)
+func readOnlyScopes(scopes []string) []string {
+	readOnly := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if strings.HasSuffix(scope, ":read") {
+			readOnly = append(readOnly, scope)
+		}
+	}
+	return readOnly
+}
+
+// newSession construit les métadonnées de session à partir de la requête courante
+func newSession(c *gin.Context, tokenID string, refreshExp int64) *model.Session {
+	now := time.Now()
+	return &model.Session{
+		ID:         tokenID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  time.Unix(refreshExp, 0),
+	}
+}
+
+// requireRole restreint un endpoint aux utilisateurs ayant l'un des rôles indiqués.
+// Doit être chaîné après requireAuth.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Insufficient permissions",
+		})
+	}
+}
+
+// forbidImpersonation refuse les opérations sensibles (mot de passe, 2FA, tokens d'API,
+// autorisations OAuth...) aux tokens d'impersonation. Doit être chaîné après requireAuth.
+func forbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		if claims.IsImpersonated() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Not allowed while impersonating a user",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePermission restreint un endpoint aux tokens portant l'une des permissions indiquées (scopes).
+// Doit être chaîné après requireAuth.
+func requirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		for _, permission := range permissions {
+			if claims.HasScope(permission) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Insufficient permissions",
+		})
+	}
+}
+
+// parseAuditLogFilter lit les filtres de GET /audit-logs (dates au format RFC 3339)
+func parseAuditLogFilter(c *gin.Context) (database.AuditLogFilter, error) {
+	filter := database.AuditLogFilter{
+		Action:     c.Query("action"),
+		Outcome:    c.Query("outcome"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		IP:         c.Query("ip"),
+	}
+	if value := c.Query("actor_id"); value != "" {
+		actorID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid actor_id filter")
+		}
+		id := uint(actorID)
+		filter.ActorID = &id
+	}
+	if value := c.Query("impersonator_id"); value != "" {
+		impersonatorID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid impersonator_id filter")
+		}
+		id := uint(impersonatorID)
+		filter.ImpersonatorID = &id
+	}
+	for key, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s filter, expected RFC 3339 date", key)
+		}
+		*target = &t
+	}
+	return filter, nil
+}
+
+// exportAuditLogsCSV écrit les événements filtrés en CSV par lots, sans tout charger en mémoire.
+// L'export est plafonné à maxAuditExportRows lignes.
+func exportAuditLogsCSV(c *gin.Context, userRepo repository.UserRepository, filter database.AuditLogFilter) {
+	ctx := c.Request.Context()
+	filename := fmt.Sprintf("audit-logs-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "occurred_at", "actor_id", "actor_email", "impersonator_id", "action", "target_type", "target_id", "ip", "user_agent", "outcome", "metadata"})
+
+	for offset := 0; offset < maxAuditExportRows; offset += auditExportBatchSize {
+		entries, _, err := userRepo.ListAuditLogs(ctx, filter, auditExportBatchSize, offset)
+		if err != nil {
+			// Les en-têtes sont déjà envoyés : l'export est tronqué
+			break
+		}
+		for _, entry := range entries {
+			actorID := ""
+			if entry.ActorID != nil {
+				actorID = strconv.FormatUint(uint64(*entry.ActorID), 10)
+			}
+			impersonatorID := ""
+			if entry.ImpersonatorID != nil {
+				impersonatorID = strconv.FormatUint(uint64(*entry.ImpersonatorID), 10)
+			}
+			metadata := ""
+			if len(entry.Metadata) > 0 {
+				if raw, err := json.Marshal(entry.Metadata); err == nil {
+					metadata = string(raw)
+				}
+			}
+			_ = w.Write(csvRecord(
+				strconv.FormatUint(entry.ID, 10),
+				entry.OccurredAt.UTC().Format(time.RFC3339),
+				actorID,
+				entry.ActorEmail,
+				impersonatorID,
+				entry.Action,
+				entry.TargetType,
+				entry.TargetID,
+				entry.IP,
+				entry.UserAgent,
+				entry.Outcome,
+				metadata,
+			))
+		}
+		w.Flush()
+		if len(entries) < auditExportBatchSize {
+			break
+		}
+	}
+	w.Flush()
+}
+
+// csvRecord neutralise les cellules qu'un tableur interpréterait comme une formule : l'email
+// d'une connexion échouée ou le User-Agent sont fournis par n'importe quel client
+func csvRecord(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+// oauthError est une erreur OAuth2 (RFC 6749 §5.2). Redirect indique qu'elle peut être
+// renvoyée au client sur sa redirect_uri.
+type oauthError struct {
+	Code        string
+	Description string
+	Redirect    bool
+}
+
+// respondOAuthError répond avec le format d'erreur OAuth2
+func respondOAuthError(c *gin.Context, status int, err *oauthError) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":             err.Code,
+		"error_description": err.Description,
+	})
+}
+
+// oauthRedirectURL ajoute des paramètres (code, state, error...) à une redirect_uri enregistrée
+func oauthRedirectURL(redirectURI string, params url.Values) string {
+	for key, values := range params {
+		if len(values) == 0 || values[0] == "" {
+			params.Del(key)
+		}
+	}
+	separator := "?"
+	if strings.Contains(redirectURI, "?") {
+		separator = "&"
+	}
+	return redirectURI + separator + params.Encode()
+}
+
+// validateRedirectURI vérifie une redirect_uri à l'enregistrement d'un client : URL absolue
+// sans fragment, HTTPS obligatoire sauf en local ; les schémas privés des applications mobiles
+// (ex. com.immogestion.app:/callback) sont acceptés
+func validateRedirectURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "" || parsed.Fragment != "" {
+		return errors.New("must be an absolute URI without fragment")
+	}
+	if parsed.Scheme == "http" && parsed.Hostname() != "localhost" && parsed.Hostname() != "127.0.0.1" {
+		return errors.New("http is only allowed for localhost")
+	}
+	if (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// oauthClientResponse construit la représentation JSON d'un client OpenID Connect (sans secret)
+func oauthClientResponse(client *model.OAuthClient) gin.H {
+	return gin.H{
+		"id":            client.ID,
+		"client_id":     client.ClientID,
+		"name":          client.Name,
+		"redirect_uris": client.RedirectURIs,
+		"scopes":        client.Scopes,
+		"confidential":  client.IsConfidential(),
+		"created_at":    client.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// isLocalPath vérifie qu'une destination de retour est un chemin du front (pas de redirection ouverte)
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.ContainsAny(path, "\\\r\n")
+}
+
+// userResponse construit la représentation JSON d'un utilisateur (sans secrets)
+func userResponse(user *model.User) gin.H {
+	data := gin.H{
+		"id":              user.ID,
+		"company":         user.Company,
+		"firstname":       user.Firstname,
+		"lastname":        user.Lastname,
+		"email":           user.Email,
+		"role":            user.Role,
+		"is_active":       user.IsActive,
+		"email_verified":  user.IsEmailVerified(),
+		"totp_enabled":    user.IsTOTPEnabled(),
+		"organisation_id": user.OrganisationID,
+		"created_at":      user.CreatedAt.Format(time.RFC3339),
+		"updated_at":      user.UpdatedAt.Format(time.RFC3339),
+		"last_login":      nil,
+	}
+	if user.LastLoginAt != nil {
+		data["last_login"] = user.LastLoginAt.Format(time.RFC3339)
+	}
+	return data
+}
+
+// parseIDParam lit le paramètre de route ":id" ; répond 400 s'il est invalide
+func parseIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondError traduit une erreur du repository en réponse HTTP (table autherrors.HTTPStatus).
+// message est renvoyé pour les erreurs techniques, dont le détail n'est jamais exposé au client.
+func respondError(c *gin.Context, err error, message string) {
+	status, known, ok := autherrors.HTTPStatus(err)
+	if ok {
+		message = known
+	}
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+	})
+}
+
+// respondRBACError traduit les erreurs du repository RBAC en réponse HTTP
+func respondRBACError(c *gin.Context, err error, message string) {
+	// Une permission inconnue dans le corps d'une requête de rôle est une erreur de saisie
+	if errors.Is(err, autherrors.ErrPermissionNotFound) && c.Request.Method != http.MethodDelete {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "unknown permission in request",
+		})
+		return
+	}
+	respondError(c, err, message)
+}
+
+// requireOrgRole restreint un endpoint aux membres de l'organisation active du token
+// (claim "org_id") ayant l'un des rôles indiqués ; sans rôle, tout membre est accepté.
+// L'appartenance est relue en base et placée dans le contexte Gin sous la clé "membership".
+// Doit être chaîné après requireAuth.
+func requireOrgRole(userRepo repository.UserRepository, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		if claims.OrgID == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "No active organisation",
+			})
+			return
+		}
+
+		membership, err := userRepo.FindMembership(c.Request.Context(), claims.OrgID, claims.UserID)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, autherrors.ErrNotOrganisationMember) {
+				status = http.StatusForbidden
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"status":  "error",
+				"message": "Not a member of the active organisation",
+			})
+			return
+		}
+
+		if len(roles) > 0 {
+			allowed := false
+			for _, role := range roles {
+				if membership.Role == role {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"status":  "error",
+					"message": "Insufficient organisation role",
+				})
+				return
+			}
+		}
+
+		c.Set("membership", membership)
+		c.Next()
+	}
+}
+
+// requireVerifiedEmail restreint un endpoint aux utilisateurs ayant vérifié leur email
+// (politique "limited"). Doit être chaîné après requireAuth.
+func requireVerifiedEmail(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*services.AccessClaims)
+		if policy != EmailVerificationOff && !claims.EmailVerified {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Email address must be verified",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAccessTokenRevoked vérifie si un access token a été révoqué, individuellement (logout)
+// ou avec tous les tokens de l'utilisateur (revokeUserSessions)
+func isAccessTokenRevoked(ctx context.Context, tokenRepo database.TokenRepository, claims *services.AccessClaims) bool {
+	if tokenRepo.IsTokenBlacklisted(ctx, claims.ID) {
+		return true
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if tokenRepo.IsUserTokenRevoked(ctx, claims.UserID, issuedAt) {
+		return true
+	}
+	// Un token d'impersonation tombe aussi quand les sessions de l'administrateur sont révoquées
+	return claims.IsImpersonated() && tokenRepo.IsUserTokenRevoked(ctx, claims.Actor.UserID, issuedAt)
+}
+
+// revokeUserSessions révoque toutes les sessions d'un utilisateur :
+// chaque JTI actif est blacklisté (l'access token partage le JTI du refresh token)
+// puis tous les refresh tokens sont supprimés via DeleteAllUserTokens.
+// accessExpiry borne la durée de la blacklist à la durée de vie maximale d'un access token.
+func revokeUserSessions(ctx context.Context, tokenRepo database.TokenRepository, userID uint, accessExpiry int64) error {
+	// Couvre aussi les access tokens dont le refresh token a déjà tourné ou été supprimé
+	if err := tokenRepo.RevokeUserTokensIssuedBefore(ctx, userID, time.Now(), accessExpiry); err != nil {
+		return err
+	}
+
+	tokenIDs, err := tokenRepo.GetAllUserTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list user tokens: %w", err)
+	}
+
+	for _, tokenID := range tokenIDs {
+		if err := tokenRepo.BlacklistToken(ctx, tokenID, accessExpiry); err != nil {
+			return fmt.Errorf("failed to blacklist token %s: %w", tokenID, err)
+		}
+	}
+
+	return tokenRepo.DeleteAllUserTokens(ctx, userID)
 }
@@ -0,0 +1,230 @@
+package audit
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	model "api/services/auth/internal/models"
+	"api/services/auth/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Résultats d'un événement
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Actions journalisées
+const (
+	ActionRegister             = "auth.register"
+	ActionLogin                = "auth.login"
+	ActionLoginLockout         = "auth.login_lockout"
+	ActionLoginTwoFactor       = "auth.login_2fa"
+	ActionRefresh              = "auth.refresh"
+	ActionRefreshReuse         = "auth.refresh_reuse"
+	ActionLogout               = "auth.logout"
+	ActionLogoutAll            = "auth.logout_all"
+	ActionSessionRevoked       = "session.revoked"
+	ActionPasswordResetRequest = "password.reset_requested"
+	ActionPasswordReset        = "password.reset"
+	ActionPasswordChanged      = "password.changed"
+	ActionEmailVerified        = "email.verified"
+	ActionTwoFactorEnabled     = "2fa.enabled"
+	ActionTwoFactorDisabled    = "2fa.disabled"
+	ActionRecoveryCodesRenewed = "2fa.recovery_codes_regenerated"
+	ActionUserUpdated          = "user.updated"
+	ActionUserDeleted          = "user.deleted"
+	ActionUserActivated        = "user.activated"
+	ActionUserDeactivated      = "user.deactivated"
+	ActionUserUnlocked         = "user.unlocked"
+	ActionUserRolesChanged     = "user.roles_changed"
+	ActionRoleCreated          = "role.created"
+	ActionRoleUpdated          = "role.updated"
+	ActionRoleDeleted          = "role.deleted"
+	ActionPermissionCreated    = "permission.created"
+	ActionPermissionDeleted    = "permission.deleted"
+	ActionOrganisationSwitched = "organisation.switched"
+	ActionMemberRemoved        = "organisation.member_removed"
+	ActionInvitationSent       = "organisation.invitation_sent"
+	ActionInvitationRevoked    = "organisation.invitation_revoked"
+	ActionInvitationAccepted   = "organisation.invitation_accepted"
+	ActionAuditLogsExported    = "audit.exported"
+	ActionOAuthAuthorized      = "oauth.authorized"
+	ActionOAuthTokenIssued     = "oauth.token_issued"
+	ActionOAuthClientCreated   = "oauth.client_created"
+	ActionOAuthClientDeleted   = "oauth.client_deleted"
+	ActionSSOAuthenticated     = "auth.sso_authenticated"
+	ActionIdentityLinked       = "identity.linked"
+	ActionIdentityUnlinked     = "identity.unlinked"
+	ActionTokenCreated         = "api_token.created"
+	ActionTokenRevoked         = "api_token.revoked"
+	ActionImpersonationStarted = "impersonation.started"
+	ActionImpersonatedRequest  = "impersonation.request"
+)
+
+// Types de cibles
+const (
+	TargetUser         = "user"
+	TargetSession      = "session"
+	TargetRole         = "role"
+	TargetPermission   = "permission"
+	TargetOrganisation = "organisation"
+	TargetInvitation   = "invitation"
+	TargetOAuthClient  = "oauth_client"
+	TargetIdentity     = "identity"
+	TargetAPIToken     = "api_token"
+)
+
+// Store persiste les événements d'audit (implémenté par repository.UserRepository)
+type Store interface {
+	CreateAuditLog(ctx context.Context, entry *model.AuditLog) error
+}
+
+// Event décrit un événement de sécurité. L'acteur, l'IP et le user agent sont
+// complétés à partir de la requête par RecordRequest.
+type Event struct {
+	ActorID    uint
+	ActorEmail string
+	// ImpersonatorID est l'administrateur réel derrière un token d'impersonation
+	ImpersonatorID uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	Outcome        string
+	Metadata       map[string]any
+}
+
+// Logger écrit les événements d'audit en base et dans les logs structurés
+type Logger struct {
+	store Store
+	sugar *zap.SugaredLogger
+}
+
+// NewLogger crée un journal d'audit
+func NewLogger(store Store, sugar *zap.SugaredLogger) *Logger {
+	return &Logger{store: store, sugar: sugar}
+}
+
+// RecordRequest journalise un événement lié à une requête HTTP : IP, user agent et,
+// si requireAuth a été exécuté, l'acteur authentifié (et l'administrateur qui l'impersonne)
+// sont renseignés automatiquement.
+func (l *Logger) RecordRequest(c *gin.Context, event Event) {
+	if value, ok := c.Get("claims"); ok {
+		if claims, ok := value.(*services.AccessClaims); ok {
+			if event.ActorID == 0 {
+				event.ActorID = claims.UserID
+				if event.ActorEmail == "" {
+					event.ActorEmail = claims.Email
+				}
+			}
+			if event.ImpersonatorID == 0 && claims.IsImpersonated() {
+				event.ImpersonatorID = claims.Actor.UserID
+			}
+		}
+	}
+
+	// L'écriture ne doit pas être annulée si le client ferme la connexion
+	l.record(context.WithoutCancel(c.Request.Context()), event, c.ClientIP(), c.Request.UserAgent())
+}
+
+// Record journalise un événement hors requête HTTP
+func (l *Logger) Record(ctx context.Context, event Event) {
+	l.record(ctx, event, "", "")
+}
+
+func (l *Logger) record(ctx context.Context, event Event, ip, userAgent string) {
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+
+	entry := &model.AuditLog{
+		OccurredAt: time.Now().UTC(),
+		ActorEmail: event.ActorEmail,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         ip,
+		UserAgent:  truncate(userAgent, 512),
+		Outcome:    event.Outcome,
+		Metadata:   Redact(event.Metadata),
+	}
+	if event.ActorID != 0 {
+		actorID := event.ActorID
+		entry.ActorID = &actorID
+	}
+	if event.ImpersonatorID != 0 {
+		impersonatorID := event.ImpersonatorID
+		entry.ImpersonatorID = &impersonatorID
+	}
+
+	l.sugar.Infow("audit",
+		"action", entry.Action,
+		"outcome", entry.Outcome,
+		"actor_id", event.ActorID,
+		"impersonator_id", event.ImpersonatorID,
+		"target_type", entry.TargetType,
+		"target_id", entry.TargetID,
+		"ip", entry.IP,
+	)
+
+	// Un échec d'écriture de l'audit ne doit pas faire échouer l'opération métier
+	if err := l.store.CreateAuditLog(ctx, entry); err != nil {
+		l.sugar.Errorw("Failed to persist audit event", "action", entry.Action, "error", err)
+	}
+}
+
+// UserTarget formate l'ID d'un utilisateur comme cible
+func UserTarget(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// sensitiveKeyPattern repère les clés de métadonnées susceptibles de contenir un secret
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|code|authorization|cookie|otp)`)
+
+// jwtPattern repère une valeur qui ressemble à un JWT
+var jwtPattern = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+
+// redacted remplace les valeurs secrètes
+const redacted = "[REDACTED]"
+
+// Redact retourne une copie des métadonnées sans secrets : les clés sensibles et
+// les valeurs ressemblant à un JWT ou à un token d'API sont masquées.
+func Redact(metadata map[string]any) map[string]any {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		if sensitiveKeyPattern.MatchString(key) {
+			out[key] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if jwtPattern.MatchString(v) || services.IsPersonalAccessToken(v) {
+				out[key] = redacted
+			} else {
+				out[key] = truncate(v, 256)
+			}
+		case map[string]any:
+			out[key] = Redact(v)
+		default:
+			out[key] = v
+		}
+	}
+	return out
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return strings.ToValidUTF8(value[:max], "")
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "api/services/auth/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter regroupe les critères de recherche du journal d'audit (champs vides = pas de filtre).
+type AuditLogFilter struct {
+	ActorID *uint
+	// ImpersonatorID restreint aux événements d'une impersonation par cet administrateur
+	ImpersonatorID *uint
+	Action         string
+	Outcome        string
+	TargetType     string
+	TargetID       string
+	IP             string
+	From           *time.Time
+	To             *time.Time
+}
+
+// CreateAuditLog ajoute un événement au journal d'audit (INSERT uniquement : la table est append-only).
+func (g *GORM) CreateAuditLog(ctx context.Context, entry *model.AuditLog, sugar *zap.SugaredLogger) error {
+	if err := g.db.WithContext(ctx).Create(entry).Error; err != nil {
+		sugar.Errorf("Failed to write audit log (action=%s): %v", entry.Action, err)
+		return fmt.Errorf("failed to write audit log (action=%s): %w", entry.Action, err)
+	}
+	return nil
+}
+
+// ListAuditLogs retourne les événements du journal d'audit, du plus récent au plus ancien,
+// ainsi que le nombre total de résultats.
+func (g *GORM) ListAuditLogs(ctx context.Context, filter AuditLogFilter, limit, offset int, sugar *zap.SugaredLogger) ([]*model.AuditLog, int64, error) {
+	query := g.db.WithContext(ctx).Model(&model.AuditLog{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.ImpersonatorID != nil {
+		query = query.Where("impersonator_id = ?", *filter.ImpersonatorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.From != nil {
+		query = query.Where("occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("occurred_at < ?", *filter.To)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		sugar.Errorf("Failed to count audit logs: %v", err)
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	var entries []*model.AuditLog
+	err := query.Order("occurred_at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	if err != nil {
+		sugar.Errorf("Failed to list audit logs (limit=%d, offset=%d): %v", limit, offset, err)
+		return nil, 0, fmt.Errorf("failed to list audit logs (limit=%d, offset=%d): %w", limit, offset, err)
+	}
+	return entries, total, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"api/services/auth/internal/errors"
@@ -15,7 +16,6 @@ import (
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
@@ -51,6 +51,7 @@ func GormOpen(ctx context.Context, debugSQL bool, sugar *zap.SugaredLogger) (*GO
 		PrepareStmt:     true,                                  // Prepared statements pour sécurité et perf
 		CreateBatchSize: 1000,                                  // Optimisé pour insertions batch (e.g., users en masse)
 		Logger:          logger.Default.LogMode(logger.Silent), // Logging GORM silencieux par défaut (activez avec Debug si besoin)
+		TranslateError:  true,                                  // Codes PostgreSQL traduits en erreurs GORM (23505 → gorm.ErrDuplicatedKey)
 	})
 	if err != nil {
 		sugar.Errorf("Failed to open PostgreSQL connection: %v", err)           // Log direct avec sugar
@@ -95,25 +96,26 @@ func GormOpen(ctx context.Context, debugSQL bool, sugar *zap.SugaredLogger) (*GO
 		}, nil
 }
 
-// Create implémente Create pour un User (avec gestion de doublons via OnConflict).
+// Create implémente Create pour un User. Un email déjà utilisé (index unique LOWER(email),
+// code PostgreSQL 23505) retourne errors.ErrUserAlreadyExists.
 func (g *GORM) Create(ctx context.Context, user *model.User) error {
-	res := g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(user)
-
-	if res.Error != nil {
-		g.sugar.Errorf("Failed to create user %s: %v", user.Email, res.Error)
-		return fmt.Errorf("failed to create user %s: %w", user.Email, res.Error)
-	}
-	if res.RowsAffected == 0 {
-		g.sugar.Warnf("User with email %s already exists, no row inserted", user.Email)
-		return fmt.Errorf("user with email %s already exists", user.Email)
+	err := g.db.WithContext(ctx).Create(user).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrDuplicatedKey) {
+			g.sugar.Warnf("User with email %s already exists, no row inserted", user.Email)
+			return fmt.Errorf("user with email %s: %w", user.Email, errors.ErrUserAlreadyExists)
+		}
+		g.sugar.Errorf("Failed to create user %s: %v", user.Email, err)
+		return fmt.Errorf("failed to create user %s: %w", user.Email, err)
 	}
 	return nil
 }
 
 // FindByEmail implémente FindByEmail (avec wrapping et logging).
+// La recherche ignore la casse, comme l'index unique LOWER(email).
 func (g *GORM) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := g.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := g.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).First(&user).Error
 	if err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) { // Assurez-vous que "errors" est le standard (alias si conflit)
 			g.sugar.Debugf("User not found for email: %s", email) // Debug car cas attendu (e.g., login)
@@ -144,16 +146,51 @@ func (g *GORM) FindByID(ctx context.Context, id uint, sugar *zap.SugaredLogger)
 	return &user, nil
 }
 
-// List implémente List (avec pagination, logging).
-func (g *GORM) List(ctx context.Context, limit, offset int, sugar *zap.SugaredLogger) ([]*model.User, error) {
+// UserFilter regroupe les critères de recherche des utilisateurs (champs vides = pas de filtre).
+type UserFilter struct {
+	Role           string
+	IsActive       *bool
+	Company        string // recherche partielle, insensible à la casse
+	OrganisationID *uint
+}
+
+// List implémente List (avec filtres, pagination, logging) et retourne le nombre total de résultats.
+func (g *GORM) List(ctx context.Context, filter UserFilter, limit, offset int, sugar *zap.SugaredLogger) ([]*model.User, int64, error) {
+	query := g.db.WithContext(ctx).Model(&model.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.Company != "" {
+		query = query.Where("company ILIKE ?", "%"+escapeLike(filter.Company)+"%")
+	}
+	if filter.OrganisationID != nil {
+		query = query.Where("organisation_id = ?", *filter.OrganisationID)
+	}
+	// Nouvelle session : la requête filtrée est réutilisée pour le comptage et la page
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		sugar.Errorf("Failed to count users: %v", err)
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
 	var users []*model.User
-	err := g.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&users).Error
+	err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error
 	if err != nil {
 		sugar.Errorf("Failed to list users (limit=%d, offset=%d): %v", limit, offset, err)
-		return nil, fmt.Errorf("failed to list users (limit=%d, offset=%d): %w", limit, offset, err)
+		return nil, 0, fmt.Errorf("failed to list users (limit=%d, offset=%d): %w", limit, offset, err)
 	}
-	sugar.Infof("Listed %d users (offset=%d)", len(users), offset)
-	return users, nil
+	sugar.Infof("Listed %d users (offset=%d, total=%d)", len(users), offset, total)
+	return users, total, nil
+}
+
+// escapeLike échappe les jokers LIKE (%, _) d'une saisie utilisateur
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 // Update implémente Update (e.g., pour last_login_at).
@@ -174,7 +211,7 @@ func (g *GORM) Delete(ctx context.Context, id uint, sugar *zap.SugaredLogger) er
 		sugar.Errorf("Failed to delete user ID=%d: %v", id, err)
 		return fmt.Errorf("failed to delete user ID=%d: %w", id, err)
 	}
-	sugar.Warnf("User deleted: ID=%d") // Warn car suppression
+	sugar.Warnf("User deleted: ID=%d", id) // Warn car suppression
 	return nil
 }
 
@@ -198,3 +235,39 @@ func (g *GORM) Ping(ctx context.Context, sugar *zap.SugaredLogger) error {
 func (g *GORM) IsNotFound(err error) bool {
 	return stderrors.Is(err, gorm.ErrRecordNotFound)
 }
+
+// ReplaceRecoveryCodes remplace les codes de récupération 2FA d'un utilisateur (transaction).
+func (g *GORM) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+		codes := make([]model.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to replace recovery codes for user ID=%d: %v", userID, err)
+		return fmt.Errorf("failed to replace recovery codes for user ID=%d: %w", userID, err)
+	}
+	sugar.Infof("Recovery codes replaced for user ID=%d (%d codes)", userID, len(codeHashes))
+	return nil
+}
+
+// ConsumeRecoveryCode marque un code de récupération comme utilisé.
+// Retourne false si le code n'existe pas ou a déjà été utilisé.
+func (g *GORM) ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string, sugar *zap.SugaredLogger) (bool, error) {
+	res := g.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		sugar.Errorf("Failed to consume recovery code for user ID=%d: %v", userID, res.Error)
+		return false, fmt.Errorf("failed to consume recovery code for user ID=%d: %w", userID, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api/services/auth/internal/errors"
+	model "api/services/auth/internal/models"
+
+	stderrors "errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindUserIdentity retourne l'identité externe d'un fournisseur par son subject.
+func (g *GORM) FindUserIdentity(ctx context.Context, provider, subject string, sugar *zap.SugaredLogger) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := g.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			sugar.Debugf("External identity not found: provider=%s", provider)
+			return nil, fmt.Errorf("identity %s: %w", provider, errors.ErrIdentityNotFound)
+		}
+		sugar.Errorf("Database error finding external identity (provider=%s): %v", provider, err)
+		return nil, fmt.Errorf("database error finding external identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// LinkUserIdentity rattache une identité externe à un utilisateur.
+func (g *GORM) LinkUserIdentity(ctx context.Context, identity *model.UserIdentity, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+	if res.Error != nil {
+		sugar.Errorf("Failed to link %s identity to user ID=%d: %v", identity.Provider, identity.UserID, res.Error)
+		return fmt.Errorf("failed to link %s identity to user ID=%d: %w", identity.Provider, identity.UserID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("identity %s: %w", identity.Provider, errors.ErrIdentityAlreadyLinked)
+	}
+	sugar.Infof("External identity linked: ID=%d, provider=%s, user ID=%d", identity.ID, identity.Provider, identity.UserID)
+	return nil
+}
+
+// TouchUserIdentity enregistre une connexion via l'identité externe et met à jour son email.
+func (g *GORM) TouchUserIdentity(ctx context.Context, id uint, email string, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Model(&model.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]any{"last_login_at": time.Now(), "email": email}).Error
+	if err != nil {
+		sugar.Errorf("Failed to update external identity ID=%d: %v", id, err)
+		return fmt.Errorf("failed to update external identity ID=%d: %w", id, err)
+	}
+	return nil
+}
+
+// ListUserIdentities retourne les identités externes liées à un utilisateur.
+func (g *GORM) ListUserIdentities(ctx context.Context, userID uint, sugar *zap.SugaredLogger) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	if err := g.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		sugar.Errorf("Failed to list external identities of user ID=%d: %v", userID, err)
+		return nil, fmt.Errorf("failed to list external identities of user ID=%d: %w", userID, err)
+	}
+	return identities, nil
+}
+
+// DeleteUserIdentity délie une identité externe d'un utilisateur.
+func (g *GORM) DeleteUserIdentity(ctx context.Context, userID, id uint, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserIdentity{}, id)
+	if res.Error != nil {
+		sugar.Errorf("Failed to unlink external identity ID=%d: %v", id, res.Error)
+		return fmt.Errorf("failed to unlink external identity ID=%d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("identity ID=%d: %w", id, errors.ErrIdentityNotFound)
+	}
+	sugar.Warnf("External identity unlinked: ID=%d (user ID=%d)", id, userID)
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginAttemptRepository définit l'interface des compteurs d'échecs de connexion et des verrous
+type LoginAttemptRepository interface {
+	// RegisterFailure incrémente le compteur d'échecs de key sur une fenêtre glissante et retourne le total
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+
+	// ResetFailures remet à zéro le compteur d'échecs de key
+	ResetFailures(ctx context.Context, key string) error
+
+	// Lock verrouille key pendant duration
+	Lock(ctx context.Context, key string, duration time.Duration) error
+
+	// LockTTL retourne la durée de verrouillage restante de key (0 si non verrouillé)
+	LockTTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Unlock supprime le verrou et le compteur d'échecs de key
+	Unlock(ctx context.Context, key string) error
+}
+
+// RedisLoginAttemptRepository implémente LoginAttemptRepository avec Redis
+type RedisLoginAttemptRepository struct {
+	client *redis.Client
+}
+
+// NewRedisLoginAttemptRepository crée une nouvelle instance de RedisLoginAttemptRepository
+func NewRedisLoginAttemptRepository(client *redis.Client) LoginAttemptRepository {
+	return &RedisLoginAttemptRepository{
+		client: client,
+	}
+}
+
+// RegisterFailure incrémente le compteur d'échecs
+// Format: "login_failures:{key}" -> nombre d'échecs (TTL = fenêtre, prolongée à chaque échec)
+func (r *RedisLoginAttemptRepository) RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failuresKey := r.getFailuresKey(key)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, failuresKey)
+	pipe.Expire(ctx, failuresKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("erreur lors de l'enregistrement de l'échec de connexion: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// ResetFailures supprime le compteur d'échecs
+func (r *RedisLoginAttemptRepository) ResetFailures(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.getFailuresKey(key)).Err(); err != nil {
+		return fmt.Errorf("erreur lors de la remise à zéro des échecs de connexion: %w", err)
+	}
+	return nil
+}
+
+// Lock pose un verrou temporaire
+// Format: "login_lock:{key}" -> "locked" (TTL = durée du verrouillage)
+func (r *RedisLoginAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	if err := r.client.Set(ctx, r.getLockKey(key), "locked", duration).Err(); err != nil {
+		return fmt.Errorf("erreur lors du verrouillage: %w", err)
+	}
+	return nil
+}
+
+// LockTTL retourne la durée de verrouillage restante
+func (r *RedisLoginAttemptRepository) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.TTL(ctx, r.getLockKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("erreur lors de la vérification du verrou: %w", err)
+	}
+	// -2 : clé absente, -1 : pas d'expiration (ne devrait pas arriver)
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Unlock supprime le verrou et le compteur d'échecs
+func (r *RedisLoginAttemptRepository) Unlock(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.getLockKey(key), r.getFailuresKey(key)).Err(); err != nil {
+		return fmt.Errorf("erreur lors du déverrouillage: %w", err)
+	}
+	return nil
+}
+
+// getFailuresKey génère la clé Redis du compteur d'échecs
+// Format: "login_failures:{key}"
+func (r *RedisLoginAttemptRepository) getFailuresKey(key string) string {
+	return fmt.Sprintf("login_failures:%s", key)
+}
+
+// getLockKey génère la clé Redis du verrou
+// Format: "login_lock:{key}"
+func (r *RedisLoginAttemptRepository) getLockKey(key string) string {
+	return fmt.Sprintf("login_lock:%s", key)
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"api/services/auth/internal/errors"
+	model "api/services/auth/internal/models"
+
+	stderrors "errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateOAuthClient enregistre un client OpenID Connect.
+func (g *GORM) CreateOAuthClient(ctx context.Context, client *model.OAuthClient, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(client)
+	if res.Error != nil {
+		sugar.Errorf("Failed to create OAuth client %s: %v", client.ClientID, res.Error)
+		return fmt.Errorf("failed to create OAuth client %s: %w", client.ClientID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("OAuth client %s: %w", client.ClientID, errors.ErrOAuthClientAlreadyExists)
+	}
+	sugar.Infof("OAuth client created: ID=%d, client_id=%s", client.ID, client.ClientID)
+	return nil
+}
+
+// ListOAuthClients retourne les clients OpenID Connect enregistrés.
+func (g *GORM) ListOAuthClients(ctx context.Context, sugar *zap.SugaredLogger) ([]*model.OAuthClient, error) {
+	var clients []*model.OAuthClient
+	if err := g.db.WithContext(ctx).Order("name").Find(&clients).Error; err != nil {
+		sugar.Errorf("Failed to list OAuth clients: %v", err)
+		return nil, fmt.Errorf("failed to list OAuth clients: %w", err)
+	}
+	return clients, nil
+}
+
+// FindOAuthClient retourne un client OpenID Connect par son client_id.
+func (g *GORM) FindOAuthClient(ctx context.Context, clientID string, sugar *zap.SugaredLogger) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := g.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			sugar.Debugf("OAuth client not found: %s", clientID)
+			return nil, fmt.Errorf("OAuth client %s: %w", clientID, errors.ErrOAuthClientNotFound)
+		}
+		sugar.Errorf("Database error finding OAuth client %s: %v", clientID, err)
+		return nil, fmt.Errorf("database error finding OAuth client %s: %w", clientID, err)
+	}
+	return &client, nil
+}
+
+// DeleteOAuthClient supprime un client OpenID Connect.
+func (g *GORM) DeleteOAuthClient(ctx context.Context, id uint, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Delete(&model.OAuthClient{}, id)
+	if res.Error != nil {
+		sugar.Errorf("Failed to delete OAuth client ID=%d: %v", id, res.Error)
+		return fmt.Errorf("failed to delete OAuth client ID=%d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("OAuth client ID=%d: %w", id, errors.ErrOAuthClientNotFound)
+	}
+	sugar.Warnf("OAuth client deleted: ID=%d", id)
+	return nil
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"api/services/auth/internal/errors"
+	model "api/services/auth/internal/models"
+
+	stderrors "errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateOrganisation crée une organisation dont ownerID devient propriétaire
+// et l'organisation active (transaction).
+func (g *GORM) CreateOrganisation(ctx context.Context, org *model.Organisation, ownerID uint, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		member := &model.OrganisationMember{
+			OrganisationID: org.ID,
+			UserID:         ownerID,
+			Role:           model.OrganisationRoleOwner,
+		}
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", ownerID).Update("organisation_id", org.ID).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to create organisation %s for user ID=%d: %v", org.Name, ownerID, err)
+		return fmt.Errorf("failed to create organisation %s: %w", org.Name, err)
+	}
+	sugar.Infof("Organisation created: ID=%d, name=%s, owner ID=%d", org.ID, org.Name, ownerID)
+	return nil
+}
+
+// FindOrganisationByID retourne une organisation.
+func (g *GORM) FindOrganisationByID(ctx context.Context, id uint, sugar *zap.SugaredLogger) (*model.Organisation, error) {
+	var org model.Organisation
+	err := g.db.WithContext(ctx).First(&org, id).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			sugar.Debugf("Organisation not found for ID: %d", id)
+			return nil, fmt.Errorf("organisation ID=%d: %w", id, errors.ErrOrganisationNotFound)
+		}
+		sugar.Errorf("Database error finding organisation ID=%d: %v", id, err)
+		return nil, fmt.Errorf("database error finding organisation ID=%d: %w", id, err)
+	}
+	return &org, nil
+}
+
+// FindMembership retourne l'appartenance d'un utilisateur à une organisation.
+func (g *GORM) FindMembership(ctx context.Context, orgID, userID uint, sugar *zap.SugaredLogger) (*model.OrganisationMember, error) {
+	var member model.OrganisationMember
+	err := g.db.WithContext(ctx).
+		Preload("Organisation").
+		Where("organisation_id = ? AND user_id = ?", orgID, userID).
+		First(&member).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user ID=%d, organisation ID=%d: %w", userID, orgID, errors.ErrNotOrganisationMember)
+		}
+		sugar.Errorf("Database error finding membership (user ID=%d, organisation ID=%d): %v", userID, orgID, err)
+		return nil, fmt.Errorf("database error finding membership: %w", err)
+	}
+	return &member, nil
+}
+
+// ListUserOrganisations retourne les organisations dont l'utilisateur est membre.
+func (g *GORM) ListUserOrganisations(ctx context.Context, userID uint, sugar *zap.SugaredLogger) ([]*model.OrganisationMember, error) {
+	var members []*model.OrganisationMember
+	err := g.db.WithContext(ctx).
+		Preload("Organisation").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&members).Error
+	if err != nil {
+		sugar.Errorf("Failed to list organisations for user ID=%d: %v", userID, err)
+		return nil, fmt.Errorf("failed to list organisations for user ID=%d: %w", userID, err)
+	}
+	return members, nil
+}
+
+// ListOrganisationMembers retourne les membres d'une organisation (utilisateurs préchargés).
+func (g *GORM) ListOrganisationMembers(ctx context.Context, orgID uint, sugar *zap.SugaredLogger) ([]*model.OrganisationMember, error) {
+	var members []*model.OrganisationMember
+	err := g.db.WithContext(ctx).
+		Preload("User").
+		Where("organisation_id = ?", orgID).
+		Order("created_at").
+		Find(&members).Error
+	if err != nil {
+		sugar.Errorf("Failed to list members of organisation ID=%d: %v", orgID, err)
+		return nil, fmt.Errorf("failed to list members of organisation ID=%d: %w", orgID, err)
+	}
+	return members, nil
+}
+
+// RemoveOrganisationMember retire un membre d'une organisation (transaction).
+// Le dernier propriétaire ne peut pas être retiré. Si c'était l'organisation active
+// de l'utilisateur, une autre de ses organisations devient active (ou aucune).
+func (g *GORM) RemoveOrganisationMember(ctx context.Context, orgID, userID uint, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var member model.OrganisationMember
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("organisation_id = ? AND user_id = ?", orgID, userID).
+			First(&member).Error
+		if err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrNotOrganisationMember
+			}
+			return err
+		}
+
+		if member.Role == model.OrganisationRoleOwner {
+			var owners int64
+			if err := tx.Model(&model.OrganisationMember{}).
+				Where("organisation_id = ? AND role = ?", orgID, model.OrganisationRoleOwner).
+				Count(&owners).Error; err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return errors.ErrLastOrganisationOwner
+			}
+		}
+
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+
+		// Basculer l'organisation active si nécessaire
+		var next *uint
+		var other model.OrganisationMember
+		err = tx.Where("user_id = ?", userID).Order("created_at").First(&other).Error
+		if err == nil {
+			next = &other.OrganisationID
+		} else if !stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Model(&model.User{}).
+			Where("id = ? AND organisation_id = ?", userID, orgID).
+			Update("organisation_id", next).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to remove user ID=%d from organisation ID=%d: %v", userID, orgID, err)
+		return fmt.Errorf("failed to remove user ID=%d from organisation ID=%d: %w", userID, orgID, err)
+	}
+	sugar.Warnf("User ID=%d removed from organisation ID=%d", userID, orgID)
+	return nil
+}
+
+// CreateInvitation enregistre une invitation à rejoindre une organisation.
+func (g *GORM) CreateInvitation(ctx context.Context, invitation *model.OrganisationInvitation, sugar *zap.SugaredLogger) error {
+	invitation.Email = strings.ToLower(strings.TrimSpace(invitation.Email))
+	if err := g.db.WithContext(ctx).Create(invitation).Error; err != nil {
+		sugar.Errorf("Failed to create invitation for %s (organisation ID=%d): %v", invitation.Email, invitation.OrganisationID, err)
+		return fmt.Errorf("failed to create invitation for %s: %w", invitation.Email, err)
+	}
+	sugar.Infof("Invitation created: ID=%d, organisation ID=%d, email=%s", invitation.ID, invitation.OrganisationID, invitation.Email)
+	return nil
+}
+
+// ListPendingInvitations retourne les invitations non acceptées et non expirées d'une organisation.
+func (g *GORM) ListPendingInvitations(ctx context.Context, orgID uint, sugar *zap.SugaredLogger) ([]*model.OrganisationInvitation, error) {
+	var invitations []*model.OrganisationInvitation
+	err := g.db.WithContext(ctx).
+		Where("organisation_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		sugar.Errorf("Failed to list invitations of organisation ID=%d: %v", orgID, err)
+		return nil, fmt.Errorf("failed to list invitations of organisation ID=%d: %w", orgID, err)
+	}
+	return invitations, nil
+}
+
+// DeleteInvitation révoque une invitation d'une organisation.
+func (g *GORM) DeleteInvitation(ctx context.Context, orgID, id uint, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).
+		Where("organisation_id = ? AND accepted_at IS NULL", orgID).
+		Delete(&model.OrganisationInvitation{}, id)
+	if res.Error != nil {
+		sugar.Errorf("Failed to delete invitation ID=%d: %v", id, res.Error)
+		return fmt.Errorf("failed to delete invitation ID=%d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("invitation ID=%d: %w", id, errors.ErrInvitationNotFound)
+	}
+	sugar.Infof("Invitation deleted: ID=%d (organisation ID=%d)", id, orgID)
+	return nil
+}
+
+// FindPendingInvitation retourne l'invitation valide correspondant au hash du token.
+func (g *GORM) FindPendingInvitation(ctx context.Context, tokenHash string, sugar *zap.SugaredLogger) (*model.OrganisationInvitation, error) {
+	var invitation model.OrganisationInvitation
+	err := g.db.WithContext(ctx).
+		Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrInvitationNotFound
+		}
+		sugar.Errorf("Database error finding invitation: %v", err)
+		return nil, fmt.Errorf("database error finding invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// FindPendingInvitationByEmail retourne l'invitation valide la plus récente adressée à un email.
+func (g *GORM) FindPendingInvitationByEmail(ctx context.Context, email string, sugar *zap.SugaredLogger) (*model.OrganisationInvitation, error) {
+	var invitation model.OrganisationInvitation
+	err := g.db.WithContext(ctx).
+		Where("email = ? AND accepted_at IS NULL AND expires_at > ?", strings.ToLower(strings.TrimSpace(email)), time.Now()).
+		Order("created_at DESC").
+		First(&invitation).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrInvitationNotFound
+		}
+		sugar.Errorf("Database error finding invitation for %s: %v", email, err)
+		return nil, fmt.Errorf("database error finding invitation for %s: %w", email, err)
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation fait entrer l'utilisateur dans l'organisation de l'invitation (transaction) :
+// l'invitation est marquée acceptée et l'organisation devient l'organisation active.
+// L'email de l'utilisateur doit être celui de l'invitation.
+func (g *GORM) AcceptInvitation(ctx context.Context, tokenHash string, user *model.User, sugar *zap.SugaredLogger) (*model.OrganisationInvitation, error) {
+	var invitation model.OrganisationInvitation
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+			First(&invitation).Error
+		if err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrInvitationNotFound
+			}
+			return err
+		}
+		if !strings.EqualFold(invitation.Email, user.Email) {
+			return errors.ErrInvitationEmailMismatch
+		}
+
+		member := &model.OrganisationMember{
+			OrganisationID: invitation.OrganisationID,
+			UserID:         user.ID,
+			Role:           invitation.Role,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(member).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&invitation).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Update("organisation_id", invitation.OrganisationID).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to accept invitation for user ID=%d: %v", user.ID, err)
+		return nil, fmt.Errorf("failed to accept invitation for user ID=%d: %w", user.ID, err)
+	}
+
+	user.OrganisationID = &invitation.OrganisationID
+	sugar.Infof("Invitation ID=%d accepted: user ID=%d joined organisation ID=%d", invitation.ID, user.ID, invitation.OrganisationID)
+	return &invitation, nil
+}
+
+// JoinOrganisation ajoute l'utilisateur à une organisation existante avec le rôle donné
+// (transaction) ; elle devient son organisation active.
+func (g *GORM) JoinOrganisation(ctx context.Context, orgID uint, user *model.User, role string, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&model.Organisation{}, orgID).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrOrganisationNotFound
+			}
+			return err
+		}
+		member := &model.OrganisationMember{
+			OrganisationID: orgID,
+			UserID:         user.ID,
+			Role:           role,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(member).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Update("organisation_id", orgID).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to add user ID=%d to organisation ID=%d: %v", user.ID, orgID, err)
+		return fmt.Errorf("failed to add user ID=%d to organisation ID=%d: %w", user.ID, orgID, err)
+	}
+
+	user.OrganisationID = &orgID
+	sugar.Infof("User ID=%d joined organisation ID=%d as %s", user.ID, orgID, role)
+	return nil
+}
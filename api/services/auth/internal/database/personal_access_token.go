@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api/services/auth/internal/errors"
+	model "api/services/auth/internal/models"
+
+	stderrors "errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// personalAccessTokenTouchInterval limite les écritures de last_used_at (un token peut être utilisé à chaque requête)
+const personalAccessTokenTouchInterval = time.Minute
+
+// CreatePersonalAccessToken enregistre un token d'API.
+func (g *GORM) CreatePersonalAccessToken(ctx context.Context, token *model.PersonalAccessToken, sugar *zap.SugaredLogger) error {
+	if err := g.db.WithContext(ctx).Create(token).Error; err != nil {
+		sugar.Errorf("Failed to create personal access token for user ID=%d: %v", token.UserID, err)
+		return fmt.Errorf("failed to create personal access token for user ID=%d: %w", token.UserID, err)
+	}
+	sugar.Infof("Personal access token created: ID=%d, user ID=%d, prefix=%s", token.ID, token.UserID, token.Prefix)
+	return nil
+}
+
+// ListPersonalAccessTokens retourne les tokens d'API non révoqués d'un utilisateur (expirés compris).
+func (g *GORM) ListPersonalAccessTokens(ctx context.Context, userID uint, sugar *zap.SugaredLogger) ([]*model.PersonalAccessToken, error) {
+	var tokens []*model.PersonalAccessToken
+	err := g.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		sugar.Errorf("Failed to list personal access tokens of user ID=%d: %v", userID, err)
+		return nil, fmt.Errorf("failed to list personal access tokens of user ID=%d: %w", userID, err)
+	}
+	return tokens, nil
+}
+
+// CountActivePersonalAccessTokens compte les tokens d'API utilisables d'un utilisateur.
+func (g *GORM) CountActivePersonalAccessTokens(ctx context.Context, userID uint, sugar *zap.SugaredLogger) (int64, error) {
+	var count int64
+	err := g.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		sugar.Errorf("Failed to count personal access tokens of user ID=%d: %v", userID, err)
+		return 0, fmt.Errorf("failed to count personal access tokens of user ID=%d: %w", userID, err)
+	}
+	return count, nil
+}
+
+// FindPersonalAccessToken retourne le token d'API valide (non révoqué, non expiré) correspondant au hash.
+func (g *GORM) FindPersonalAccessToken(ctx context.Context, tokenHash string, sugar *zap.SugaredLogger) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	err := g.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrPersonalAccessTokenNotFound
+		}
+		sugar.Errorf("Database error finding personal access token: %v", err)
+		return nil, fmt.Errorf("database error finding personal access token: %w", err)
+	}
+	return &token, nil
+}
+
+// TouchPersonalAccessToken met à jour la date de dernière utilisation (au plus une fois par minute).
+func (g *GORM) TouchPersonalAccessToken(ctx context.Context, id uint, sugar *zap.SugaredLogger) error {
+	now := time.Now()
+	err := g.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-personalAccessTokenTouchInterval)).
+		Update("last_used_at", now).Error
+	if err != nil {
+		sugar.Errorf("Failed to update personal access token ID=%d: %v", id, err)
+		return fmt.Errorf("failed to update personal access token ID=%d: %w", id, err)
+	}
+	return nil
+}
+
+// RevokePersonalAccessToken révoque un token d'API de l'utilisateur (conservé pour l'historique).
+func (g *GORM) RevokePersonalAccessToken(ctx context.Context, userID, id uint, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		sugar.Errorf("Failed to revoke personal access token ID=%d: %v", id, res.Error)
+		return fmt.Errorf("failed to revoke personal access token ID=%d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("personal access token ID=%d: %w", id, errors.ErrPersonalAccessTokenNotFound)
+	}
+	sugar.Warnf("Personal access token revoked: ID=%d (user ID=%d)", id, userID)
+	return nil
+}
@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"api/services/auth/internal/errors"
+	model "api/services/auth/internal/models"
+
+	stderrors "errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListRoles retourne tous les rôles avec leurs permissions.
+func (g *GORM) ListRoles(ctx context.Context, sugar *zap.SugaredLogger) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := g.db.WithContext(ctx).Preload("Permissions", orderByName).Order("name").Find(&roles).Error
+	if err != nil {
+		sugar.Errorf("Failed to list roles: %v", err)
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// FindRoleByID retourne un rôle et ses permissions.
+func (g *GORM) FindRoleByID(ctx context.Context, id uint, sugar *zap.SugaredLogger) (*model.Role, error) {
+	var role model.Role
+	err := g.db.WithContext(ctx).Preload("Permissions", orderByName).First(&role, id).Error
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			sugar.Debugf("Role not found for ID: %d", id)
+			return nil, fmt.Errorf("role ID=%d: %w", id, errors.ErrRoleNotFound)
+		}
+		sugar.Errorf("Database error finding role ID=%d: %v", id, err)
+		return nil, fmt.Errorf("database error finding role ID=%d: %w", id, err)
+	}
+	return &role, nil
+}
+
+// CreateRole crée un rôle avec les permissions indiquées (par nom).
+func (g *GORM) CreateRole(ctx context.Context, role *model.Role, permissionNames []string, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		permissions, err := findPermissionsByName(tx, permissionNames)
+		if err != nil {
+			return err
+		}
+
+		res := tx.Omit("Permissions").Clauses(clause.OnConflict{DoNothing: true}).Create(role)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.ErrRoleAlreadyExists
+		}
+
+		role.Permissions = permissions
+		return tx.Model(role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		sugar.Errorf("Failed to create role %s: %v", role.Name, err)
+		return fmt.Errorf("failed to create role %s: %w", role.Name, err)
+	}
+	sugar.Infof("Role created: ID=%d, name=%s", role.ID, role.Name)
+	return nil
+}
+
+// UpdateRole met à jour le nom, la description et remplace les permissions d'un rôle.
+// Un rôle système ne peut pas être renommé.
+func (g *GORM) UpdateRole(ctx context.Context, role *model.Role, permissionNames []string, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Role
+		if err := tx.First(&current, role.ID).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrRoleNotFound
+			}
+			return err
+		}
+		if current.System && current.Name != role.Name {
+			return errors.ErrSystemRole
+		}
+
+		permissions, err := findPermissionsByName(tx, permissionNames)
+		if err != nil {
+			return err
+		}
+
+		var conflicts int64
+		if err := tx.Model(&model.Role{}).Where("name = ? AND id <> ?", role.Name, role.ID).Count(&conflicts).Error; err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return errors.ErrRoleAlreadyExists
+		}
+
+		if err := tx.Model(&current).Updates(map[string]any{
+			"name":        role.Name,
+			"description": role.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Association("Permissions").Replace(permissions); err != nil {
+			return err
+		}
+
+		*role = current
+		role.Permissions = permissions
+		return nil
+	})
+	if err != nil {
+		sugar.Errorf("Failed to update role ID=%d: %v", role.ID, err)
+		return fmt.Errorf("failed to update role ID=%d: %w", role.ID, err)
+	}
+	sugar.Infof("Role updated: ID=%d, name=%s", role.ID, role.Name)
+	return nil
+}
+
+// DeleteRole supprime un rôle (les associations sont supprimées en cascade).
+// Un rôle système ne peut pas être supprimé.
+func (g *GORM) DeleteRole(ctx context.Context, id uint, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role model.Role
+		if err := tx.First(&role, id).Error; err != nil {
+			if stderrors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrRoleNotFound
+			}
+			return err
+		}
+		if role.System {
+			return errors.ErrSystemRole
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to delete role ID=%d: %v", id, err)
+		return fmt.Errorf("failed to delete role ID=%d: %w", id, err)
+	}
+	sugar.Warnf("Role deleted: ID=%d", id)
+	return nil
+}
+
+// ListPermissions retourne toutes les permissions.
+func (g *GORM) ListPermissions(ctx context.Context, sugar *zap.SugaredLogger) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if err := g.db.WithContext(ctx).Order("name").Find(&permissions).Error; err != nil {
+		sugar.Errorf("Failed to list permissions: %v", err)
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// CreatePermission crée une permission.
+func (g *GORM) CreatePermission(ctx context.Context, permission *model.Permission, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(permission)
+	if res.Error != nil {
+		sugar.Errorf("Failed to create permission %s: %v", permission.Name, res.Error)
+		return fmt.Errorf("failed to create permission %s: %w", permission.Name, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		sugar.Warnf("Permission %s already exists, no row inserted", permission.Name)
+		return fmt.Errorf("permission %s: %w", permission.Name, errors.ErrPermissionAlreadyExists)
+	}
+	sugar.Infof("Permission created: ID=%d, name=%s", permission.ID, permission.Name)
+	return nil
+}
+
+// DeletePermission supprime une permission (retirée de tous les rôles en cascade).
+func (g *GORM) DeletePermission(ctx context.Context, id uint, sugar *zap.SugaredLogger) error {
+	res := g.db.WithContext(ctx).Delete(&model.Permission{}, id)
+	if res.Error != nil {
+		sugar.Errorf("Failed to delete permission ID=%d: %v", id, res.Error)
+		return fmt.Errorf("failed to delete permission ID=%d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("permission ID=%d: %w", id, errors.ErrPermissionNotFound)
+	}
+	sugar.Warnf("Permission deleted: ID=%d", id)
+	return nil
+}
+
+// ListUserRoles retourne les rôles attribués à un utilisateur via user_roles.
+func (g *GORM) ListUserRoles(ctx context.Context, userID uint, sugar *zap.SugaredLogger) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := g.db.WithContext(ctx).
+		Preload("Permissions", orderByName).
+		Joins("JOIN auth.user_roles ur ON ur.role_id = auth.roles.id").
+		Where("ur.user_id = ?", userID).
+		Order("auth.roles.name").
+		Find(&roles).Error
+	if err != nil {
+		sugar.Errorf("Failed to list roles for user ID=%d: %v", userID, err)
+		return nil, fmt.Errorf("failed to list roles for user ID=%d: %w", userID, err)
+	}
+	return roles, nil
+}
+
+// SetUserRoles remplace les rôles attribués à un utilisateur (transaction).
+func (g *GORM) SetUserRoles(ctx context.Context, userID uint, roleIDs []uint, sugar *zap.SugaredLogger) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(roleIDs) > 0 {
+			var found int64
+			if err := tx.Model(&model.Role{}).Where("id IN ?", roleIDs).Count(&found).Error; err != nil {
+				return err
+			}
+			if int(found) != len(uniqueIDs(roleIDs)) {
+				return errors.ErrRoleNotFound
+			}
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(roleIDs) == 0 {
+			return nil
+		}
+
+		userRoles := make([]model.UserRole, 0, len(roleIDs))
+		for _, roleID := range uniqueIDs(roleIDs) {
+			userRoles = append(userRoles, model.UserRole{UserID: userID, RoleID: roleID})
+		}
+		return tx.Create(&userRoles).Error
+	})
+	if err != nil {
+		sugar.Errorf("Failed to set roles for user ID=%d: %v", userID, err)
+		return fmt.Errorf("failed to set roles for user ID=%d: %w", userID, err)
+	}
+	sugar.Infof("Roles updated for user ID=%d (%d roles)", userID, len(roleIDs))
+	return nil
+}
+
+// ListUserPermissions retourne les permissions effectives d'un utilisateur :
+// celles du rôle principal (users.role) et celles des rôles attribués via user_roles.
+func (g *GORM) ListUserPermissions(ctx context.Context, user *model.User, sugar *zap.SugaredLogger) ([]string, error) {
+	var names []string
+	err := g.db.WithContext(ctx).
+		Model(&model.Permission{}).
+		Distinct("auth.permissions.name").
+		Joins("JOIN auth.role_permissions rp ON rp.permission_id = auth.permissions.id").
+		Joins("JOIN auth.roles r ON r.id = rp.role_id").
+		Where("r.name = ? OR r.id IN (?)", user.Role,
+			g.db.Model(&model.UserRole{}).Select("role_id").Where("user_id = ?", user.ID)).
+		Order("auth.permissions.name").
+		Pluck("auth.permissions.name", &names).Error
+	if err != nil {
+		sugar.Errorf("Failed to list permissions for user ID=%d: %v", user.ID, err)
+		return nil, fmt.Errorf("failed to list permissions for user ID=%d: %w", user.ID, err)
+	}
+	return names, nil
+}
+
+// findPermissionsByName charge les permissions par nom ; toute permission inconnue est une erreur.
+func findPermissionsByName(tx *gorm.DB, names []string) ([]model.Permission, error) {
+	permissions := []model.Permission{}
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		known[p.Name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("%s: %w", name, errors.ErrPermissionNotFound)
+		}
+	}
+	return permissions, nil
+}
+
+// orderByName trie les permissions préchargées par nom
+func orderByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
+
+// uniqueIDs dédoublonne une liste d'IDs en conservant l'ordre
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
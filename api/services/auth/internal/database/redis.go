@@ -2,9 +2,14 @@ package database
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	model "api/services/auth/internal/models"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -23,11 +28,103 @@ type TokenRepository interface {
 	// Utile pour "logout all devices"
 	DeleteAllUserTokens(ctx context.Context, userID uint) error
 
+	// GetAllUserTokens retourne tous les tokenID actifs d'un utilisateur
+	GetAllUserTokens(ctx context.Context, userID uint) ([]string, error)
+
 	// IsTokenBlacklisted vérifie si un access token est sur la liste noire
 	IsTokenBlacklisted(ctx context.Context, tokenID string) bool
 
 	// BlacklistToken ajoute un access token à la liste noire avec expiration
 	BlacklistToken(ctx context.Context, tokenID string, expiry int64) error
+
+	// RevokeUserTokensIssuedBefore invalide tous les access tokens d'un utilisateur émis
+	// jusqu'à issuedBefore inclus (désactivation, changement de mot de passe), jusqu'à expiry
+	RevokeUserTokensIssuedBefore(ctx context.Context, userID uint, issuedBefore time.Time, expiry int64) error
+
+	// IsUserTokenRevoked vérifie si un access token émis à issuedAt a été invalidé
+	// par RevokeUserTokensIssuedBefore
+	IsUserTokenRevoked(ctx context.Context, userID uint, issuedAt time.Time) bool
+
+	// StoreSession enregistre les métadonnées d'appareil d'une session
+	StoreSession(ctx context.Context, userID uint, session *model.Session) error
+
+	// GetSession récupère une session par userID et tokenID
+	GetSession(ctx context.Context, userID uint, tokenID string) (*model.Session, error)
+
+	// ListSessions retourne toutes les sessions actives d'un utilisateur
+	ListSessions(ctx context.Context, userID uint) ([]*model.Session, error)
+
+	// TouchSession met à jour la date de dernière utilisation d'une session
+	TouchSession(ctx context.Context, userID uint, tokenID string) error
+
+	// StoreTokenFamily enregistre le refresh token courant (tokenID) d'une famille
+	StoreTokenFamily(ctx context.Context, userID uint, familyID string, tokenID string, expiry int64) error
+
+	// RotateRefreshToken consomme atomiquement le refresh token courant d'une famille et désigne
+	// newTokenID comme nouveau courant. ErrRefreshTokenReused signale un token déjà consommé,
+	// ErrRefreshTokenNotFound un token inconnu, expiré ou révoqué.
+	RotateRefreshToken(ctx context.Context, userID uint, familyID, tokenID, token, newTokenID string, expiry int64) error
+
+	// RevokeTokenFamily révoque la famille entière : le refresh token courant est supprimé
+	// et son access token blacklisté jusqu'à accessExpiry
+	RevokeTokenFamily(ctx context.Context, userID uint, familyID string, accessExpiry int64) error
+
+	// StorePasswordResetToken stocke le hash d'un token de reset de mot de passe avec un TTL
+	StorePasswordResetToken(ctx context.Context, userID uint, tokenHash string, ttl time.Duration) error
+
+	// GetPasswordResetToken retourne l'utilisateur associé à un token de reset sans le consommer
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (uint, error)
+
+	// ConsumePasswordResetToken récupère et supprime atomiquement un token de reset (usage unique)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uint, error)
+
+	// StoreEmailVerificationToken stocke le hash d'un token de vérification d'email avec un TTL
+	StoreEmailVerificationToken(ctx context.Context, userID uint, tokenHash string, ttl time.Duration) error
+
+	// ConsumeEmailVerificationToken récupère et supprime atomiquement un token de vérification
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (uint, error)
+
+	// StorePendingTOTPSecret conserve un secret TOTP en attente de confirmation
+	StorePendingTOTPSecret(ctx context.Context, userID uint, secret string, ttl time.Duration) error
+
+	// GetPendingTOTPSecret récupère le secret TOTP en attente de confirmation
+	GetPendingTOTPSecret(ctx context.Context, userID uint) (string, error)
+
+	// DeletePendingTOTPSecret supprime le secret TOTP en attente
+	DeletePendingTOTPSecret(ctx context.Context, userID uint) error
+
+	// MarkTOTPStepUsed enregistre l'utilisation d'une période TOTP ; retourne false si déjà utilisée (rejeu)
+	MarkTOTPStepUsed(ctx context.Context, userID uint, step int64) (bool, error)
+
+	// StoreMFAChallenge stocke le hash d'un challenge 2FA émis après la vérification du mot de passe
+	StoreMFAChallenge(ctx context.Context, userID uint, challengeHash string, ttl time.Duration) error
+
+	// GetMFAChallenge retourne l'utilisateur associé à un challenge 2FA
+	GetMFAChallenge(ctx context.Context, challengeHash string) (uint, error)
+
+	// RecordMFAChallengeFailure incrémente le nombre d'échecs d'un challenge et retourne le total
+	RecordMFAChallengeFailure(ctx context.Context, challengeHash string) (int64, error)
+
+	// DeleteMFAChallenge supprime un challenge 2FA (réussi ou trop d'échecs)
+	DeleteMFAChallenge(ctx context.Context, challengeHash string) error
+
+	// StoreAuthorizationCode stocke un code d'autorisation OAuth2 (hash) avec un TTL
+	StoreAuthorizationCode(ctx context.Context, codeHash string, code *model.AuthorizationCode, ttl time.Duration) error
+
+	// ConsumeAuthorizationCode récupère et supprime atomiquement un code d'autorisation (usage unique)
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+
+	// StoreSSOState stocke l'état d'une connexion en cours auprès d'un fournisseur externe
+	StoreSSOState(ctx context.Context, stateHash string, state *model.SSOState, ttl time.Duration) error
+
+	// ConsumeSSOState récupère et supprime atomiquement l'état d'une connexion externe (usage unique)
+	ConsumeSSOState(ctx context.Context, stateHash string) (*model.SSOState, error)
+
+	// StoreSSOLoginCode stocke le hash du code remis au front après une connexion externe réussie
+	StoreSSOLoginCode(ctx context.Context, userID uint, codeHash string, ttl time.Duration) error
+
+	// ConsumeSSOLoginCode récupère et supprime atomiquement un code de connexion externe
+	ConsumeSSOLoginCode(ctx context.Context, codeHash string) (uint, error)
 }
 
 // RedisTokenRepository implémente l'interface TokenRepository avec Redis
@@ -91,8 +188,8 @@ func (r *RedisTokenRepository) GetRefreshToken(ctx context.Context, userID uint,
 func (r *RedisTokenRepository) DeleteRefreshToken(ctx context.Context, userID uint, tokenID string) error {
 	key := r.getRefreshTokenKey(userID, tokenID)
 
-	// Supprimer le token lui-même
-	if err := r.client.Del(ctx, key).Err(); err != nil {
+	// Supprimer le token lui-même et les métadonnées de session associées
+	if err := r.client.Del(ctx, key, r.getSessionKey(userID, tokenID)).Err(); err != nil {
 		return fmt.Errorf("erreur lors de la suppression du refresh token: %w", err)
 	}
 
@@ -119,7 +216,7 @@ func (r *RedisTokenRepository) DeleteAllUserTokens(ctx context.Context, userID u
 	// Supprimer chaque token individuellement
 	for _, tokenID := range tokenIDs {
 		key := r.getRefreshTokenKey(userID, tokenID)
-		if err := r.client.Del(ctx, key).Err(); err != nil {
+		if err := r.client.Del(ctx, key, r.getSessionKey(userID, tokenID)).Err(); err != nil {
 			return fmt.Errorf("erreur lors de la suppression du token %s: %w", tokenID, err)
 		}
 	}
@@ -160,6 +257,522 @@ func (r *RedisTokenRepository) BlacklistToken(ctx context.Context, tokenID strin
 	return nil
 }
 
+// RevokeUserTokensIssuedBefore enregistre une date de révocation pour l'utilisateur
+// Structure : user_revoked:{userID} -> timestamp unix en millisecondes ; tout access token émis
+// avant est rejeté, y compris ceux qui n'ont plus de refresh token
+func (r *RedisTokenRepository) RevokeUserTokensIssuedBefore(ctx context.Context, userID uint, issuedBefore time.Time, expiry int64) error {
+	key := r.getUserRevokedKey(userID)
+	duration := time.Until(time.Unix(expiry, 0))
+	if duration <= 0 {
+		return nil
+	}
+
+	if err := r.client.Set(ctx, key, issuedBefore.UnixMilli(), duration).Err(); err != nil {
+		return fmt.Errorf("erreur lors de la révocation des tokens de l'utilisateur: %w", err)
+	}
+	return nil
+}
+
+// IsUserTokenRevoked compare la date d'émission du token à la date de révocation de l'utilisateur
+func (r *RedisTokenRepository) IsUserTokenRevoked(ctx context.Context, userID uint, issuedAt time.Time) bool {
+	value, err := r.client.Get(ctx, r.getUserRevokedKey(userID)).Result()
+	if err != nil {
+		// Pas de révocation (ou erreur Redis : même politique que IsTokenBlacklisted)
+		return false
+	}
+
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return IssuedBeforeRevocation(issuedAt, revokedAt)
+}
+
+// IssuedBeforeRevocation indique si un token émis à issuedAt est couvert par une révocation
+// enregistrée à revokedAt (timestamp unix en millisecondes). Un token émis dans la même
+// milliseconde est rejeté : la révocation l'emporte en cas de doute.
+func IssuedBeforeRevocation(issuedAt time.Time, revokedAt int64) bool {
+	return issuedAt.UnixMilli() <= revokedAt
+}
+
+// StoreSession stocke les métadonnées d'une session dans un hash Redis
+// Structure : session:{userID}:{tokenID} -> {user_agent, ip, created_at, last_used_at, expires_at, client_id, scope}
+// Le hash expire en même temps que le refresh token associé
+func (r *RedisTokenRepository) StoreSession(ctx context.Context, userID uint, session *model.Session) error {
+	key := r.getSessionKey(userID, session.ID)
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"user_agent":   session.UserAgent,
+		"ip":           session.IP,
+		"created_at":   session.CreatedAt.Unix(),
+		"last_used_at": session.LastUsedAt.Unix(),
+		"expires_at":   session.ExpiresAt.Unix(),
+		"client_id":    session.ClientID,
+		"scope":        session.Scope,
+	})
+	pipe.ExpireAt(ctx, key, session.ExpiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("erreur lors du stockage de la session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSession récupère les métadonnées d'une session depuis Redis
+func (r *RedisTokenRepository) GetSession(ctx context.Context, userID uint, tokenID string) (*model.Session, error) {
+	values, err := r.client.HGetAll(ctx, r.getSessionKey(userID, tokenID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la récupération de la session: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("session non trouvée ou expirée")
+	}
+
+	return &model.Session{
+		ID:         tokenID,
+		UserAgent:  values["user_agent"],
+		IP:         values["ip"],
+		CreatedAt:  parseUnix(values["created_at"]),
+		LastUsedAt: parseUnix(values["last_used_at"]),
+		ExpiresAt:  parseUnix(values["expires_at"]),
+		ClientID:   values["client_id"],
+		Scope:      values["scope"],
+	}, nil
+}
+
+// ListSessions retourne les sessions actives d'un utilisateur à partir du set user_tokens
+// Les tokenID dont la session a expiré sont retirés du set au passage
+func (r *RedisTokenRepository) ListSessions(ctx context.Context, userID uint) ([]*model.Session, error) {
+	tokenIDs, err := r.GetAllUserTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la récupération des tokens: %w", err)
+	}
+
+	sessions := make([]*model.Session, 0, len(tokenIDs))
+	for _, tokenID := range tokenIDs {
+		session, err := r.GetSession(ctx, userID, tokenID)
+		if err != nil {
+			// Refresh token expiré : nettoyer le set
+			r.client.SRem(ctx, r.getUserTokensKey(userID), tokenID)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// touchSessionScript met à jour last_used_at uniquement si le hash existe encore : un HSET
+// sur une session expirée entre-temps la recréerait sans TTL
+var touchSessionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HSET", KEYS[1], "last_used_at", ARGV[1])
+end
+return 0
+`)
+
+// TouchSession met à jour last_used_at si la session existe encore
+func (r *RedisTokenRepository) TouchSession(ctx context.Context, userID uint, tokenID string) error {
+	key := r.getSessionKey(userID, tokenID)
+
+	if err := touchSessionScript.Run(ctx, r.client, []string{key}, time.Now().Unix()).Err(); err != nil {
+		return fmt.Errorf("erreur lors de la mise à jour de la session: %w", err)
+	}
+
+	return nil
+}
+
+// StoreTokenFamily stocke le tokenID courant d'une famille de refresh tokens
+// Structure : token_family:{userID}:{familyID} -> tokenID courant
+// Un refresh token de la famille dont le JTI n'est pas le courant a déjà été utilisé (rotation)
+func (r *RedisTokenRepository) StoreTokenFamily(ctx context.Context, userID uint, familyID string, tokenID string, expiry int64) error {
+	key := r.getTokenFamilyKey(userID, familyID)
+	duration := time.Until(time.Unix(expiry, 0))
+
+	if err := r.client.Set(ctx, key, tokenID, duration).Err(); err != nil {
+		return fmt.Errorf("erreur lors du stockage de la famille de tokens: %w", err)
+	}
+
+	return nil
+}
+
+// Erreurs de RotateRefreshToken
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token non trouvé ou expiré")
+	ErrRefreshTokenReused   = errors.New("refresh token déjà utilisé")
+)
+
+// rotateRefreshTokenScript effectue la rotation en une seule étape atomique : deux présentations
+// concurrentes du même refresh token ne peuvent pas réussir toutes les deux.
+// KEYS : refresh token, session et famille de l'ancien token, set user_tokens
+// ARGV : ancien tokenID, token présenté, nouveau tokenID, durée de vie de la famille (ms),
+// "1" si le token appartient à une famille (sinon une nouvelle famille démarre)
+// Retour : 1 rotation effectuée, 0 token inconnu, -1 token déjà consommé
+var rotateRefreshTokenScript = redis.NewScript(`
+if ARGV[5] == "1" then
+	local current = redis.call("GET", KEYS[3])
+	if not current then
+		return 0
+	end
+	if current ~= ARGV[1] then
+		return -1
+	end
+end
+if redis.call("GET", KEYS[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("DEL", KEYS[1], KEYS[2])
+redis.call("SREM", KEYS[4], ARGV[1])
+redis.call("SET", KEYS[3], ARGV[3], "PX", ARGV[4])
+return 1
+`)
+
+// RotateRefreshToken consomme le refresh token tokenID de la famille familyID (vide : ancien token
+// sans famille, newTokenID démarre alors la famille) et enregistre newTokenID comme token courant.
+// Le nouveau refresh token et sa session restent à stocker par l'appelant.
+func (r *RedisTokenRepository) RotateRefreshToken(ctx context.Context, userID uint, familyID, tokenID, token, newTokenID string, expiry int64) error {
+	inFamily := "1"
+	if familyID == "" {
+		familyID, inFamily = newTokenID, "0"
+	}
+	keys := []string{
+		r.getRefreshTokenKey(userID, tokenID),
+		r.getSessionKey(userID, tokenID),
+		r.getTokenFamilyKey(userID, familyID),
+		r.getUserTokensKey(userID),
+	}
+	ttl := time.Until(time.Unix(expiry, 0)).Milliseconds()
+
+	result, err := rotateRefreshTokenScript.Run(ctx, r.client, keys, tokenID, token, newTokenID, ttl, inFamily).Int()
+	if err != nil {
+		return fmt.Errorf("erreur lors de la rotation du refresh token: %w", err)
+	}
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		return ErrRefreshTokenReused
+	default:
+		return ErrRefreshTokenNotFound
+	}
+}
+
+// revokeTokenFamilyScript supprime atomiquement la famille, son refresh token courant, la session
+// associée et son entrée dans user_tokens ; retourne le tokenID révoqué (vide si aucun)
+// KEYS : famille, set user_tokens ; ARGV : préfixes des clés refresh token et session
+var revokeTokenFamilyScript = redis.NewScript(`
+local tokenID = redis.call("GET", KEYS[1])
+redis.call("DEL", KEYS[1])
+if not tokenID or tokenID == "" then
+	return ""
+end
+redis.call("DEL", ARGV[1] .. tokenID, ARGV[2] .. tokenID)
+redis.call("SREM", KEYS[2], tokenID)
+return tokenID
+`)
+
+// RevokeTokenFamily révoque toute une famille de refresh tokens
+// Seul le dernier token de la famille est encore stocké : les précédents ont été supprimés à chaque rotation
+func (r *RedisTokenRepository) RevokeTokenFamily(ctx context.Context, userID uint, familyID string, accessExpiry int64) error {
+	keys := []string{r.getTokenFamilyKey(userID, familyID), r.getUserTokensKey(userID)}
+	tokenID, err := revokeTokenFamilyScript.Run(ctx, r.client, keys,
+		r.getRefreshTokenKey(userID, ""), r.getSessionKey(userID, "")).Text()
+	if err != nil {
+		return fmt.Errorf("erreur lors de la révocation de la famille de tokens: %w", err)
+	}
+
+	// L'access token émis avec le dernier refresh token partage son JTI
+	if tokenID != "" {
+		if err := r.BlacklistToken(ctx, tokenID, accessExpiry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StorePasswordResetToken stocke un token de reset de mot de passe
+// Structure des clés Redis :
+// - password_reset:{tokenHash} -> userID (TTL)
+// - password_reset_user:{userID} -> tokenHash (un seul token actif par utilisateur)
+func (r *RedisTokenRepository) StorePasswordResetToken(ctx context.Context, userID uint, tokenHash string, ttl time.Duration) error {
+	if err := r.storeOneTimeToken(ctx, passwordResetPrefix, userID, tokenHash, ttl); err != nil {
+		return fmt.Errorf("erreur lors du stockage du token de reset: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetToken récupère l'utilisateur associé à un token de reset sans le supprimer
+// (permet de refuser un mot de passe non conforme sans invalider le lien)
+func (r *RedisTokenRepository) GetPasswordResetToken(ctx context.Context, tokenHash string) (uint, error) {
+	value, err := r.client.Get(ctx, r.getOneTimeTokenKey(passwordResetPrefix, tokenHash)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, fmt.Errorf("token de reset invalide: token non trouvé ou expiré")
+		}
+		return 0, err
+	}
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("token corrompu: %w", err)
+	}
+	return uint(userID), nil
+}
+
+// ConsumePasswordResetToken récupère l'utilisateur associé à un token de reset et le supprime
+func (r *RedisTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uint, error) {
+	userID, err := r.consumeOneTimeToken(ctx, passwordResetPrefix, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("token de reset invalide: %w", err)
+	}
+	return userID, nil
+}
+
+// StoreEmailVerificationToken stocke un token de vérification d'email
+// Structure des clés Redis :
+// - email_verification:{tokenHash} -> userID (TTL)
+// - email_verification_user:{userID} -> tokenHash (un seul token actif par utilisateur)
+func (r *RedisTokenRepository) StoreEmailVerificationToken(ctx context.Context, userID uint, tokenHash string, ttl time.Duration) error {
+	if err := r.storeOneTimeToken(ctx, emailVerificationPrefix, userID, tokenHash, ttl); err != nil {
+		return fmt.Errorf("erreur lors du stockage du token de vérification: %w", err)
+	}
+	return nil
+}
+
+// ConsumeEmailVerificationToken récupère l'utilisateur associé à un token de vérification et le supprime
+func (r *RedisTokenRepository) ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (uint, error) {
+	userID, err := r.consumeOneTimeToken(ctx, emailVerificationPrefix, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("token de vérification invalide: %w", err)
+	}
+	return userID, nil
+}
+
+// StorePendingTOTPSecret stocke un secret TOTP en attente de confirmation
+// Format: "totp_pending:{userID}" -> secret (TTL)
+func (r *RedisTokenRepository) StorePendingTOTPSecret(ctx context.Context, userID uint, secret string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, fmt.Sprintf("totp_pending:%d", userID), secret, ttl).Err(); err != nil {
+		return fmt.Errorf("erreur lors du stockage du secret TOTP: %w", err)
+	}
+	return nil
+}
+
+// GetPendingTOTPSecret récupère un secret TOTP en attente de confirmation
+func (r *RedisTokenRepository) GetPendingTOTPSecret(ctx context.Context, userID uint) (string, error) {
+	secret, err := r.client.Get(ctx, fmt.Sprintf("totp_pending:%d", userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", fmt.Errorf("aucune activation 2FA en cours ou activation expirée")
+		}
+		return "", fmt.Errorf("erreur lors de la récupération du secret TOTP: %w", err)
+	}
+	return secret, nil
+}
+
+// DeletePendingTOTPSecret supprime un secret TOTP en attente
+func (r *RedisTokenRepository) DeletePendingTOTPSecret(ctx context.Context, userID uint) error {
+	if err := r.client.Del(ctx, fmt.Sprintf("totp_pending:%d", userID)).Err(); err != nil {
+		return fmt.Errorf("erreur lors de la suppression du secret TOTP: %w", err)
+	}
+	return nil
+}
+
+// MarkTOTPStepUsed empêche la réutilisation d'un code TOTP pendant sa fenêtre de validité
+// Format: "totp_used:{userID}:{step}" (SETNX, expire après la fenêtre de tolérance)
+func (r *RedisTokenRepository) MarkTOTPStepUsed(ctx context.Context, userID uint, step int64) (bool, error) {
+	ok, err := r.client.SetNX(ctx, fmt.Sprintf("totp_used:%d:%d", userID, step), 1, 2*time.Minute).Result()
+	if err != nil {
+		return false, fmt.Errorf("erreur lors de l'enregistrement du code TOTP: %w", err)
+	}
+	return ok, nil
+}
+
+// StoreMFAChallenge stocke un challenge 2FA
+// Structure des clés Redis :
+// - mfa_challenge:{challengeHash} -> userID (TTL)
+// - mfa_challenge:{challengeHash}:failures -> nombre d'échecs (même TTL)
+func (r *RedisTokenRepository) StoreMFAChallenge(ctx context.Context, userID uint, challengeHash string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, r.getMFAChallengeKey(challengeHash), userID, ttl).Err(); err != nil {
+		return fmt.Errorf("erreur lors du stockage du challenge 2FA: %w", err)
+	}
+	return nil
+}
+
+// GetMFAChallenge retourne l'utilisateur associé à un challenge 2FA
+func (r *RedisTokenRepository) GetMFAChallenge(ctx context.Context, challengeHash string) (uint, error) {
+	value, err := r.client.Get(ctx, r.getMFAChallengeKey(challengeHash)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, fmt.Errorf("challenge 2FA non trouvé ou expiré")
+		}
+		return 0, fmt.Errorf("erreur lors de la récupération du challenge 2FA: %w", err)
+	}
+
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("challenge 2FA corrompu: %w", err)
+	}
+	return uint(userID), nil
+}
+
+// RecordMFAChallengeFailure incrémente le compteur d'échecs d'un challenge 2FA
+func (r *RedisTokenRepository) RecordMFAChallengeFailure(ctx context.Context, challengeHash string) (int64, error) {
+	key := r.getMFAChallengeKey(challengeHash)
+	failuresKey := key + ":failures"
+
+	count, err := r.client.Incr(ctx, failuresKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("erreur lors de l'enregistrement de l'échec 2FA: %w", err)
+	}
+
+	// Le compteur expire avec le challenge
+	if ttl, err := r.client.TTL(ctx, key).Result(); err == nil && ttl > 0 {
+		r.client.Expire(ctx, failuresKey, ttl)
+	}
+	return count, nil
+}
+
+// DeleteMFAChallenge supprime un challenge 2FA et son compteur d'échecs
+func (r *RedisTokenRepository) DeleteMFAChallenge(ctx context.Context, challengeHash string) error {
+	key := r.getMFAChallengeKey(challengeHash)
+	if err := r.client.Del(ctx, key, key+":failures").Err(); err != nil {
+		return fmt.Errorf("erreur lors de la suppression du challenge 2FA: %w", err)
+	}
+	return nil
+}
+
+// storeOneTimeToken stocke un token à usage unique et invalide le précédent du même type
+func (r *RedisTokenRepository) storeOneTimeToken(ctx context.Context, prefix string, userID uint, tokenHash string, ttl time.Duration) error {
+	userKey := r.getOneTimeTokenUserKey(prefix, userID)
+
+	// Invalider le token précédent s'il existe encore
+	previousHash, err := r.client.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	if previousHash != "" {
+		pipe.Del(ctx, r.getOneTimeTokenKey(prefix, previousHash))
+	}
+	pipe.Set(ctx, r.getOneTimeTokenKey(prefix, tokenHash), userID, ttl)
+	pipe.Set(ctx, userKey, tokenHash, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// consumeOneTimeToken récupère l'utilisateur associé à un token à usage unique et le supprime
+// GETDEL garantit qu'un token ne peut être utilisé qu'une seule fois
+func (r *RedisTokenRepository) consumeOneTimeToken(ctx context.Context, prefix string, tokenHash string) (uint, error) {
+	value, err := r.client.GetDel(ctx, r.getOneTimeTokenKey(prefix, tokenHash)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, fmt.Errorf("token non trouvé ou expiré")
+		}
+		return 0, err
+	}
+
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("token corrompu: %w", err)
+	}
+
+	r.client.Del(ctx, r.getOneTimeTokenUserKey(prefix, uint(userID)))
+	return uint(userID), nil
+}
+
+// StoreAuthorizationCode stocke un code d'autorisation OAuth2
+// Structure des clés Redis :
+// - oauth_code:{codeHash} -> JSON du code (TTL)
+func (r *RedisTokenRepository) StoreAuthorizationCode(ctx context.Context, codeHash string, code *model.AuthorizationCode, ttl time.Duration) error {
+	data, err := json.Marshal(code)
+	if err != nil {
+		return fmt.Errorf("erreur lors de l'encodage du code d'autorisation: %w", err)
+	}
+	if err := r.client.Set(ctx, r.getAuthorizationCodeKey(codeHash), data, ttl).Err(); err != nil {
+		return fmt.Errorf("erreur lors du stockage du code d'autorisation: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode récupère un code d'autorisation et le supprime
+func (r *RedisTokenRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	data, err := r.client.GetDel(ctx, r.getAuthorizationCodeKey(codeHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("code d'autorisation non trouvé ou expiré")
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération du code d'autorisation: %w", err)
+	}
+
+	var code model.AuthorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, fmt.Errorf("code d'autorisation corrompu: %w", err)
+	}
+	return &code, nil
+}
+
+// StoreSSOState stocke l'état d'une connexion externe
+// Structure des clés Redis :
+// - sso_state:{stateHash} -> JSON de l'état (TTL)
+func (r *RedisTokenRepository) StoreSSOState(ctx context.Context, stateHash string, state *model.SSOState, ttl time.Duration) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("erreur lors de l'encodage de l'état SSO: %w", err)
+	}
+	if err := r.client.Set(ctx, r.getSSOStateKey(stateHash), data, ttl).Err(); err != nil {
+		return fmt.Errorf("erreur lors du stockage de l'état SSO: %w", err)
+	}
+	return nil
+}
+
+// ConsumeSSOState récupère l'état d'une connexion externe et le supprime
+func (r *RedisTokenRepository) ConsumeSSOState(ctx context.Context, stateHash string) (*model.SSOState, error) {
+	data, err := r.client.GetDel(ctx, r.getSSOStateKey(stateHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("état SSO non trouvé ou expiré")
+		}
+		return nil, fmt.Errorf("erreur lors de la récupération de l'état SSO: %w", err)
+	}
+
+	var state model.SSOState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("état SSO corrompu: %w", err)
+	}
+	return &state, nil
+}
+
+// StoreSSOLoginCode stocke un code de connexion externe
+// Structure des clés Redis :
+// - sso_login:{codeHash} -> userID (TTL)
+// - sso_login_user:{userID} -> codeHash (un seul code actif par utilisateur)
+func (r *RedisTokenRepository) StoreSSOLoginCode(ctx context.Context, userID uint, codeHash string, ttl time.Duration) error {
+	if err := r.storeOneTimeToken(ctx, ssoLoginPrefix, userID, codeHash, ttl); err != nil {
+		return fmt.Errorf("erreur lors du stockage du code de connexion SSO: %w", err)
+	}
+	return nil
+}
+
+// ConsumeSSOLoginCode récupère l'utilisateur associé à un code de connexion externe et le supprime
+func (r *RedisTokenRepository) ConsumeSSOLoginCode(ctx context.Context, codeHash string) (uint, error) {
+	userID, err := r.consumeOneTimeToken(ctx, ssoLoginPrefix, codeHash)
+	if err != nil {
+		return 0, fmt.Errorf("code de connexion SSO invalide: %w", err)
+	}
+	return userID, nil
+}
+
+// parseUnix convertit un timestamp Unix stocké en chaîne dans Redis
+func parseUnix(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
 // Méthodes utilitaires pour générer les clés Redis
 
 // getRefreshTokenKey génère la clé Redis pour un refresh token
@@ -174,12 +787,67 @@ func (r *RedisTokenRepository) getUserTokensKey(userID uint) string {
 	return fmt.Sprintf("user_tokens:%d", userID)
 }
 
+// getSessionKey génère la clé Redis pour les métadonnées d'une session
+// Format: "session:{userID}:{tokenID}"
+func (r *RedisTokenRepository) getSessionKey(userID uint, tokenID string) string {
+	return fmt.Sprintf("session:%d:%s", userID, tokenID)
+}
+
+// getTokenFamilyKey génère la clé Redis pour une famille de refresh tokens
+// Format: "token_family:{userID}:{familyID}"
+func (r *RedisTokenRepository) getTokenFamilyKey(userID uint, familyID string) string {
+	return fmt.Sprintf("token_family:%d:%s", userID, familyID)
+}
+
+// Préfixes des tokens à usage unique
+const (
+	passwordResetPrefix     = "password_reset"
+	emailVerificationPrefix = "email_verification"
+	ssoLoginPrefix          = "sso_login"
+)
+
+// getOneTimeTokenKey génère la clé Redis d'un token à usage unique
+// Format: "{prefix}:{tokenHash}"
+func (r *RedisTokenRepository) getOneTimeTokenKey(prefix, tokenHash string) string {
+	return fmt.Sprintf("%s:%s", prefix, tokenHash)
+}
+
+// getOneTimeTokenUserKey génère la clé Redis du token à usage unique actif d'un utilisateur
+// Format: "{prefix}_user:{userID}"
+func (r *RedisTokenRepository) getOneTimeTokenUserKey(prefix string, userID uint) string {
+	return fmt.Sprintf("%s_user:%d", prefix, userID)
+}
+
+// getAuthorizationCodeKey génère la clé Redis d'un code d'autorisation OAuth2
+// Format: "oauth_code:{codeHash}"
+func (r *RedisTokenRepository) getAuthorizationCodeKey(codeHash string) string {
+	return fmt.Sprintf("oauth_code:%s", codeHash)
+}
+
+// getSSOStateKey génère la clé Redis de l'état d'une connexion externe
+// Format: "sso_state:{stateHash}"
+func (r *RedisTokenRepository) getSSOStateKey(stateHash string) string {
+	return fmt.Sprintf("sso_state:%s", stateHash)
+}
+
+// getMFAChallengeKey génère la clé Redis d'un challenge 2FA
+// Format: "mfa_challenge:{challengeHash}"
+func (r *RedisTokenRepository) getMFAChallengeKey(challengeHash string) string {
+	return fmt.Sprintf("mfa_challenge:%s", challengeHash)
+}
+
 // getBlacklistKey génère la clé Redis pour un token blacklisté
 // Format: "blacklist:{tokenID}"
 func (r *RedisTokenRepository) getBlacklistKey(tokenID string) string {
 	return fmt.Sprintf("blacklist:%s", tokenID)
 }
 
+// getUserRevokedKey génère la clé Redis de la date de révocation des tokens d'un utilisateur
+// Format: "user_revoked:{userID}"
+func (r *RedisTokenRepository) getUserRevokedKey(userID uint) string {
+	return fmt.Sprintf("user_revoked:%d", userID)
+}
+
 // Méthodes utilitaires supplémentaires
 
 // GetUserTokenCount retourne le nombre de tokens actifs pour un utilisateur
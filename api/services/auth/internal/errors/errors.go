@@ -3,8 +3,12 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
+// ErrUserNotFound est la cause commune des UserNotFoundError (errors.Is)
+var ErrUserNotFound = errors.New("user not found")
+
 // UserNotFoundError est une erreur custom indiquant qu'un utilisateur n'a pas été trouvé.
 type UserNotFoundError struct {
 	Email string
@@ -12,9 +16,17 @@ type UserNotFoundError struct {
 
 // Error implémente l'interface error.
 func (e *UserNotFoundError) Error() string {
+	if e.Email == "" {
+		return ErrUserNotFound.Error()
+	}
 	return fmt.Sprintf("user not found with email: %s", e.Email)
 }
 
+// Is permet errors.Is(err, ErrUserNotFound) quel que soit l'email recherché.
+func (e *UserNotFoundError) Is(target error) bool {
+	return target == ErrUserNotFound
+}
+
 // NewUserNotFound crée une nouvelle instance d'erreur UserNotFoundError.
 func NewUserNotFound(email string) *UserNotFoundError {
 	return &UserNotFoundError{Email: email}
@@ -23,4 +35,73 @@ func NewUserNotFound(email string) *UserNotFoundError {
 // Autres erreurs custom potentielles pour l'auth (optionnel)
 var ErrUserAlreadyExists = errors.New("user already exists")
 var ErrInvalidCredentials = errors.New("invalid email or password")
-var ErrUserInactive = errors.New("user account is inactive")
\ No newline at end of file
+var ErrUserInactive = errors.New("user account is inactive")
+
+// Erreurs RBAC (rôles et permissions)
+var ErrRoleNotFound = errors.New("role not found")
+var ErrRoleAlreadyExists = errors.New("role already exists")
+var ErrSystemRole = errors.New("system roles cannot be renamed or deleted")
+var ErrPermissionNotFound = errors.New("permission not found")
+var ErrPermissionAlreadyExists = errors.New("permission already exists")
+
+// Erreurs organisations (agences)
+var ErrOrganisationNotFound = errors.New("organisation not found")
+var ErrNotOrganisationMember = errors.New("user is not a member of this organisation")
+var ErrLastOrganisationOwner = errors.New("the last owner cannot leave the organisation")
+var ErrInvitationNotFound = errors.New("invitation not found or expired")
+var ErrInvitationEmailMismatch = errors.New("invitation was sent to another email address")
+
+// Erreurs OpenID Connect (clients OAuth)
+var ErrOAuthClientNotFound = errors.New("OAuth client not found")
+var ErrOAuthClientAlreadyExists = errors.New("OAuth client already exists")
+
+// Erreurs des identités externes (connexion Google, Microsoft, ...)
+var ErrIdentityNotFound = errors.New("external identity not found")
+var ErrIdentityAlreadyLinked = errors.New("external identity is already linked to an account")
+
+// Erreurs des tokens d'API (personal access tokens)
+var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found, revoked or expired")
+
+// httpErrors associe chaque erreur métier à son code HTTP. Le message renvoyé au client est
+// celui de l'erreur sentinelle, jamais celui de l'erreur enveloppante (SQL, email recherché...).
+var httpErrors = []struct {
+	err    error
+	status int
+}{
+	{ErrUserNotFound, http.StatusNotFound},
+	{ErrUserAlreadyExists, http.StatusConflict},
+	{ErrInvalidCredentials, http.StatusUnauthorized},
+	{ErrUserInactive, http.StatusForbidden},
+
+	{ErrRoleNotFound, http.StatusNotFound},
+	{ErrPermissionNotFound, http.StatusNotFound},
+	{ErrRoleAlreadyExists, http.StatusConflict},
+	{ErrPermissionAlreadyExists, http.StatusConflict},
+	{ErrSystemRole, http.StatusConflict},
+
+	{ErrOrganisationNotFound, http.StatusNotFound},
+	{ErrNotOrganisationMember, http.StatusNotFound},
+	{ErrInvitationNotFound, http.StatusNotFound},
+	{ErrInvitationEmailMismatch, http.StatusForbidden},
+	{ErrLastOrganisationOwner, http.StatusConflict},
+
+	{ErrOAuthClientNotFound, http.StatusNotFound},
+	{ErrOAuthClientAlreadyExists, http.StatusConflict},
+
+	{ErrIdentityNotFound, http.StatusNotFound},
+	{ErrIdentityAlreadyLinked, http.StatusConflict},
+
+	{ErrPersonalAccessTokenNotFound, http.StatusNotFound},
+}
+
+// HTTPStatus traduit une erreur du repository en code HTTP et en message présentable au client.
+// known vaut false pour une erreur technique (base indisponible...) : 500 et message vide,
+// l'appelant fournit alors son propre message générique.
+func HTTPStatus(err error) (status int, message string, known bool) {
+	for _, mapping := range httpErrors {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.err.Error(), true
+		}
+	}
+	return http.StatusInternalServerError, "", false
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message représente un email transactionnel (reset de mot de passe, vérification, ...)
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender définit l'interface d'envoi d'emails.
+// Les implémentations locales (log, fichier) permettent de développer sans serveur SMTP.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender écrit les emails dans les logs (développement uniquement)
+type LogSender struct {
+	sugar *zap.SugaredLogger
+}
+
+// NewLogSender crée un Sender qui écrit les emails dans les logs
+func NewLogSender(sugar *zap.SugaredLogger) *LogSender {
+	return &LogSender{sugar: sugar}
+}
+
+// Send implémente Sender.
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.sugar.Infow("Email sent (log sender)",
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
+
+// FileSender écrit chaque email dans un fichier .eml d'un répertoire "outbox"
+type FileSender struct {
+	dir string
+}
+
+// NewFileSender crée un Sender qui écrit les emails dans dir (créé si besoin)
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create mail outbox %s: %w", dir, err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+// Send implémente Sender.
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), sanitizeFilename(msg.To))
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		msg.To, msg.Subject, time.Now().Format(time.RFC1123Z), msg.Body)
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o640); err != nil {
+		return fmt.Errorf("failed to write email to outbox: %w", err)
+	}
+	return nil
+}
+
+// NewSenderFromEnv choisit l'implémentation selon MAIL_SENDER ("file" par défaut, ou "log").
+// MAIL_OUTBOX_DIR définit le répertoire utilisé par le FileSender.
+func NewSenderFromEnv(sugar *zap.SugaredLogger) (Sender, error) {
+	switch strings.ToLower(os.Getenv("MAIL_SENDER")) {
+	case "log":
+		sugar.Info("Mail sender: log")
+		return NewLogSender(sugar), nil
+	case "", "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "immogestion-mail")
+		}
+		sugar.Infof("Mail sender: file (outbox: %s)", dir)
+		return NewFileSender(dir)
+	default:
+		return nil, fmt.Errorf("unknown MAIL_SENDER %q (expected \"file\" or \"log\")", os.Getenv("MAIL_SENDER"))
+	}
+}
+
+// sanitizeFilename remplace les caractères non sûrs d'une adresse pour un nom de fichier
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLog est un événement de sécurité de l'auth service (table append-only).
+// Les métadonnées ne contiennent jamais de secrets (mots de passe, tokens, codes).
+type AuditLog struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	OccurredAt time.Time `gorm:"not null" json:"occurred_at"`
+	ActorID    *uint     `json:"actor_id"`
+	ActorEmail string    `gorm:"type:varchar(255)" json:"actor_email,omitempty"`
+	// ImpersonatorID est l'administrateur réel quand l'acteur était impersonné
+	ImpersonatorID *uint          `json:"impersonator_id,omitempty"`
+	Action         string         `gorm:"type:varchar(64);not null" json:"action"`
+	TargetType     string         `gorm:"type:varchar(32)" json:"target_type,omitempty"`
+	TargetID       string         `gorm:"type:varchar(64)" json:"target_id,omitempty"`
+	IP             string         `gorm:"type:varchar(45)" json:"ip,omitempty"`
+	UserAgent      string         `gorm:"type:varchar(512)" json:"user_agent,omitempty"`
+	Outcome        string         `gorm:"type:varchar(16);not null" json:"outcome"`
+	Metadata       map[string]any `gorm:"type:jsonb;serializer:json" json:"metadata,omitempty"`
+}
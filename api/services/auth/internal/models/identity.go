@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// UserIdentity lie un compte à une identité d'un fournisseur externe (Google, Microsoft, ...).
+// Le couple (provider, subject) identifie l'utilisateur chez le fournisseur ; l'email n'est
+// conservé qu'à titre indicatif (il peut changer chez le fournisseur).
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"type:varchar(32);not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// SSOState est l'état d'une connexion en cours auprès d'un fournisseur externe
+// (stocké dans Redis sous le hash du paramètre state, usage unique)
+type SSOState struct {
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ReturnTo     string    `json:"return_to,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// OAuthClient est une application cliente OpenID Connect enregistrée (application mobile,
+// outil de comptabilité, ...). Un client sans secret est public : PKCE est alors sa seule preuve.
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ClientID     string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"client_id"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name"`
+	SecretHash   string    `gorm:"type:varchar(64)" json:"-"`
+	RedirectURIs []string  `gorm:"type:jsonb;serializer:json;not null" json:"redirect_uris"`
+	Scopes       []string  `gorm:"type:jsonb;serializer:json;not null" json:"scopes"`
+	CreatedBy    *uint     `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// IsConfidential indique si le client doit s'authentifier avec un secret sur /token
+func (c *OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirectURI vérifie qu'une redirect_uri est enregistrée (comparaison exacte)
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope vérifie qu'un scope OpenID Connect est autorisé pour le client
+func (c *OAuthClient) AllowsScope(scope string) bool {
+	for _, allowed := range c.Scopes {
+		if allowed == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationCode est un code d'autorisation OAuth2 en attente d'échange sur /token
+// (stocké dans Redis, usage unique)
+type AuthorizationCode struct {
+	ClientID            string    `json:"client_id"`
+	UserID              uint      `json:"user_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scope               string    `json:"scope"`
+	Nonce               string    `json:"nonce,omitempty"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	AuthTime            time.Time `json:"auth_time"`
+}
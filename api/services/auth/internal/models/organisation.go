@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// Rôles d'un membre au sein d'une organisation
+const (
+	OrganisationRoleOwner  = "owner"
+	OrganisationRoleAdmin  = "admin"
+	OrganisationRoleMember = "member"
+)
+
+// Organisation est une agence : ses membres partagent le même portefeuille de biens.
+// Les services en aval filtrent leurs données par organisation (claim "org_id").
+type Organisation struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganisationMember rattache un utilisateur à une organisation avec un rôle
+type OrganisationMember struct {
+	OrganisationID uint          `gorm:"primaryKey" json:"organisation_id"`
+	UserID         uint          `gorm:"primaryKey" json:"user_id"`
+	Role           string        `gorm:"type:varchar(20);not null;default:member" json:"role"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Organisation   *Organisation `gorm:"foreignKey:OrganisationID" json:"organisation,omitempty"`
+	User           *User         `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// OrganisationInvitation est une invitation par email à rejoindre une organisation.
+// Seul le hash SHA-256 du token d'invitation est stocké.
+type OrganisationInvitation struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrganisationID uint       `gorm:"not null;index" json:"organisation_id"`
+	Email          string     `gorm:"type:varchar(255);not null" json:"email"`
+	Role           string     `gorm:"type:varchar(20);not null;default:member" json:"role"`
+	TokenHash      string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	InvitedBy      *uint      `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
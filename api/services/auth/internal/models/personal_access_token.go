@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// PersonalAccessToken est un token d'API longue durée pour les intégrations (scripts du comptable,
+// imports bancaires, ...). Seul le hash SHA-256 du token est stocké ; Prefix permet de le
+// reconnaître dans la liste sans le révéler.
+type PersonalAccessToken struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix string `gorm:"type:varchar(16);not null" json:"prefix"`
+	// TokenHash est le hash SHA-256 (hex) du token
+	TokenHash string   `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes    []string `gorm:"type:jsonb;serializer:json;not null" json:"scopes"`
+	// OrganisationID est l'organisation active à la création : le token n'agit que dans celle-ci
+	OrganisationID *uint      `json:"organisation_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// Role regroupe un ensemble de permissions attribuables aux utilisateurs.
+// Les rôles système ("user", "admin") correspondent à la colonne users.role et ne peuvent pas être supprimés.
+type Role struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string       `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	Description string       `gorm:"type:varchar(255)" json:"description"`
+	System      bool         `gorm:"column:is_system;default:false" json:"system"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Permission est un droit élémentaire, nommé "ressource:action" (ex. "properties:write").
+// Les permissions effectives d'un utilisateur sont embarquées comme scopes dans l'access token.
+type Permission struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserRole associe un rôle supplémentaire à un utilisateur (en plus de users.role)
+type UserRole struct {
+	UserID    uint `gorm:"primaryKey"`
+	RoleID    uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
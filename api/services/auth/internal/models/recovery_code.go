@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// RecoveryCode est un code de secours 2FA à usage unique (seul son hash SHA-256 est stocké)
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
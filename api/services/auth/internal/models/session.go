@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// Session représente un appareil connecté : une paire de tokens et les métadonnées
+// capturées lors de la connexion. L'ID est le JTI partagé par l'access et le refresh token.
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// ClientID et Scope identifient le client OpenID Connect de la session et les scopes qui
+	// lui ont été accordés ; vides pour les sessions de l'application
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
@@ -16,6 +16,23 @@ type User struct {
 	UpdatedAt   time.Time
 	LastLoginAt *time.Time
 	IsActive    bool `gorm:"default:true"`
+	// EmailVerifiedAt est renseigné quand l'utilisateur a confirmé son adresse email
+	EmailVerifiedAt *time.Time
+	// TOTPSecret est le secret base32 de la 2FA (vide si la 2FA n'est pas activée)
+	TOTPSecret    string     `gorm:"type:varchar(64);column:totp_secret" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at"`
+	// OrganisationID est l'organisation active de l'utilisateur (claim "org_id")
+	OrganisationID *uint `gorm:"column:organisation_id" json:"organisation_id"`
 
 	changedFields map[string]any `gorm:"-"`
 }
+
+// IsEmailVerified indique si l'adresse email de l'utilisateur a été confirmée
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsTOTPEnabled indique si la double authentification TOTP est activée
+func (u *User) IsTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
@@ -17,8 +17,63 @@ type UserRepository interface {
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id uint) error
 	FindByID(ctx context.Context, id uint) (*model.User, error)
-	List(ctx context.Context, limit, offset int) ([]*model.User, error)
+	List(ctx context.Context, filter database.UserFilter, limit, offset int) ([]*model.User, int64, error)
 	Ping(ctx context.Context) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+	ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+
+	// RBAC : rôles, permissions et attribution des rôles
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	FindRoleByID(ctx context.Context, id uint) (*model.Role, error)
+	CreateRole(ctx context.Context, role *model.Role, permissionNames []string) error
+	UpdateRole(ctx context.Context, role *model.Role, permissionNames []string) error
+	DeleteRole(ctx context.Context, id uint) error
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+	CreatePermission(ctx context.Context, permission *model.Permission) error
+	DeletePermission(ctx context.Context, id uint) error
+	ListUserRoles(ctx context.Context, userID uint) ([]*model.Role, error)
+	SetUserRoles(ctx context.Context, userID uint, roleIDs []uint) error
+	ListUserPermissions(ctx context.Context, user *model.User) ([]string, error)
+
+	// Organisations (agences) : appartenance et invitations
+	CreateOrganisation(ctx context.Context, org *model.Organisation, ownerID uint) error
+	FindOrganisationByID(ctx context.Context, id uint) (*model.Organisation, error)
+	FindMembership(ctx context.Context, orgID, userID uint) (*model.OrganisationMember, error)
+	ListUserOrganisations(ctx context.Context, userID uint) ([]*model.OrganisationMember, error)
+	ListOrganisationMembers(ctx context.Context, orgID uint) ([]*model.OrganisationMember, error)
+	RemoveOrganisationMember(ctx context.Context, orgID, userID uint) error
+	CreateInvitation(ctx context.Context, invitation *model.OrganisationInvitation) error
+	ListPendingInvitations(ctx context.Context, orgID uint) ([]*model.OrganisationInvitation, error)
+	DeleteInvitation(ctx context.Context, orgID, id uint) error
+	FindPendingInvitation(ctx context.Context, tokenHash string) (*model.OrganisationInvitation, error)
+	AcceptInvitation(ctx context.Context, tokenHash string, user *model.User) (*model.OrganisationInvitation, error)
+	FindPendingInvitationByEmail(ctx context.Context, email string) (*model.OrganisationInvitation, error)
+	JoinOrganisation(ctx context.Context, orgID uint, user *model.User, role string) error
+
+	// Journal d'audit (append-only)
+	CreateAuditLog(ctx context.Context, entry *model.AuditLog) error
+	ListAuditLogs(ctx context.Context, filter database.AuditLogFilter, limit, offset int) ([]*model.AuditLog, int64, error)
+
+	// Clients OpenID Connect
+	CreateOAuthClient(ctx context.Context, client *model.OAuthClient) error
+	ListOAuthClients(ctx context.Context) ([]*model.OAuthClient, error)
+	FindOAuthClient(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, id uint) error
+
+	// Identités externes (connexion Google, Microsoft, ...)
+	FindUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	LinkUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	TouchUserIdentity(ctx context.Context, id uint, email string) error
+	ListUserIdentities(ctx context.Context, userID uint) ([]*model.UserIdentity, error)
+	DeleteUserIdentity(ctx context.Context, userID, id uint) error
+
+	// Tokens d'API (personal access tokens)
+	CreatePersonalAccessToken(ctx context.Context, token *model.PersonalAccessToken) error
+	ListPersonalAccessTokens(ctx context.Context, userID uint) ([]*model.PersonalAccessToken, error)
+	CountActivePersonalAccessTokens(ctx context.Context, userID uint) (int64, error)
+	FindPersonalAccessToken(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+	TouchPersonalAccessToken(ctx context.Context, id uint) error
+	RevokePersonalAccessToken(ctx context.Context, userID, id uint) error
 }
 
 // UserRepositoryImpl implémente UserRepository en utilisant DBClient.
@@ -51,15 +106,16 @@ func (r *UserRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.User
 }
 
 // List implements UserRepository.
-func (r *UserRepositoryImpl) List(ctx context.Context, limit int, offset int) ([]*model.User, error) {
+func (r *UserRepositoryImpl) List(ctx context.Context, filter database.UserFilter, limit int, offset int) ([]*model.User, int64, error) {
 	r.logger.Infof("------------ Listing users (limit=%d, offset=%d) ----------", limit, offset)
 	gormDB := r.db
-	return gormDB.List(ctx, limit, offset, r.logger)
+	return gormDB.List(ctx, filter, limit, offset, r.logger)
 }
 
 // Ping implements UserRepository.
 func (r *UserRepositoryImpl) Ping(ctx context.Context) error {
-	panic("unimplemented")
+	gormDB := r.db
+	return gormDB.Ping(ctx, r.logger)
 }
 
 // Update implements UserRepository.
@@ -76,6 +132,306 @@ func (r *UserRepositoryImpl) Delete(ctx context.Context, id uint) error {
 	return gormDB.Delete(ctx, id, r.logger)
 }
 
+// ReplaceRecoveryCodes implements UserRepository.
+func (r *UserRepositoryImpl) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	r.logger.Infof("------------ Replacing recovery codes for user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ReplaceRecoveryCodes(ctx, userID, codeHashes, r.logger)
+}
+
+// ConsumeRecoveryCode implements UserRepository.
+func (r *UserRepositoryImpl) ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	r.logger.Infof("------------ Consuming recovery code for user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ConsumeRecoveryCode(ctx, userID, codeHash, r.logger)
+}
+
+// ListRoles implements UserRepository.
+func (r *UserRepositoryImpl) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	r.logger.Infof("------------ Listing roles ----------")
+	gormDB := r.db
+	return gormDB.ListRoles(ctx, r.logger)
+}
+
+// FindRoleByID implements UserRepository.
+func (r *UserRepositoryImpl) FindRoleByID(ctx context.Context, id uint) (*model.Role, error) {
+	r.logger.Infof("------------ FindRoleByID : %d ----------", id)
+	gormDB := r.db
+	return gormDB.FindRoleByID(ctx, id, r.logger)
+}
+
+// CreateRole implements UserRepository.
+func (r *UserRepositoryImpl) CreateRole(ctx context.Context, role *model.Role, permissionNames []string) error {
+	r.logger.Infof("------------ Creating role: %s ----------", role.Name)
+	gormDB := r.db
+	return gormDB.CreateRole(ctx, role, permissionNames, r.logger)
+}
+
+// UpdateRole implements UserRepository.
+func (r *UserRepositoryImpl) UpdateRole(ctx context.Context, role *model.Role, permissionNames []string) error {
+	r.logger.Infof("------------ Updating role ID: %d ----------", role.ID)
+	gormDB := r.db
+	return gormDB.UpdateRole(ctx, role, permissionNames, r.logger)
+}
+
+// DeleteRole implements UserRepository.
+func (r *UserRepositoryImpl) DeleteRole(ctx context.Context, id uint) error {
+	r.logger.Infof("------------ Deleting role ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.DeleteRole(ctx, id, r.logger)
+}
+
+// ListPermissions implements UserRepository.
+func (r *UserRepositoryImpl) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	r.logger.Infof("------------ Listing permissions ----------")
+	gormDB := r.db
+	return gormDB.ListPermissions(ctx, r.logger)
+}
+
+// CreatePermission implements UserRepository.
+func (r *UserRepositoryImpl) CreatePermission(ctx context.Context, permission *model.Permission) error {
+	r.logger.Infof("------------ Creating permission: %s ----------", permission.Name)
+	gormDB := r.db
+	return gormDB.CreatePermission(ctx, permission, r.logger)
+}
+
+// DeletePermission implements UserRepository.
+func (r *UserRepositoryImpl) DeletePermission(ctx context.Context, id uint) error {
+	r.logger.Infof("------------ Deleting permission ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.DeletePermission(ctx, id, r.logger)
+}
+
+// ListUserRoles implements UserRepository.
+func (r *UserRepositoryImpl) ListUserRoles(ctx context.Context, userID uint) ([]*model.Role, error) {
+	r.logger.Infof("------------ Listing roles for user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ListUserRoles(ctx, userID, r.logger)
+}
+
+// SetUserRoles implements UserRepository.
+func (r *UserRepositoryImpl) SetUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
+	r.logger.Infof("------------ Setting roles for user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.SetUserRoles(ctx, userID, roleIDs, r.logger)
+}
+
+// ListUserPermissions implements UserRepository.
+func (r *UserRepositoryImpl) ListUserPermissions(ctx context.Context, user *model.User) ([]string, error) {
+	r.logger.Infof("------------ Listing permissions for user ID: %d ----------", user.ID)
+	gormDB := r.db
+	return gormDB.ListUserPermissions(ctx, user, r.logger)
+}
+
+// CreateOrganisation implements UserRepository.
+func (r *UserRepositoryImpl) CreateOrganisation(ctx context.Context, org *model.Organisation, ownerID uint) error {
+	r.logger.Infof("------------ Creating organisation: %s ----------", org.Name)
+	gormDB := r.db
+	return gormDB.CreateOrganisation(ctx, org, ownerID, r.logger)
+}
+
+// FindOrganisationByID implements UserRepository.
+func (r *UserRepositoryImpl) FindOrganisationByID(ctx context.Context, id uint) (*model.Organisation, error) {
+	r.logger.Infof("------------ FindOrganisationByID : %d ----------", id)
+	gormDB := r.db
+	return gormDB.FindOrganisationByID(ctx, id, r.logger)
+}
+
+// FindMembership implements UserRepository.
+func (r *UserRepositoryImpl) FindMembership(ctx context.Context, orgID, userID uint) (*model.OrganisationMember, error) {
+	r.logger.Infof("------------ FindMembership : user ID %d, organisation ID %d ----------", userID, orgID)
+	gormDB := r.db
+	return gormDB.FindMembership(ctx, orgID, userID, r.logger)
+}
+
+// ListUserOrganisations implements UserRepository.
+func (r *UserRepositoryImpl) ListUserOrganisations(ctx context.Context, userID uint) ([]*model.OrganisationMember, error) {
+	r.logger.Infof("------------ Listing organisations for user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ListUserOrganisations(ctx, userID, r.logger)
+}
+
+// ListOrganisationMembers implements UserRepository.
+func (r *UserRepositoryImpl) ListOrganisationMembers(ctx context.Context, orgID uint) ([]*model.OrganisationMember, error) {
+	r.logger.Infof("------------ Listing members of organisation ID: %d ----------", orgID)
+	gormDB := r.db
+	return gormDB.ListOrganisationMembers(ctx, orgID, r.logger)
+}
+
+// RemoveOrganisationMember implements UserRepository.
+func (r *UserRepositoryImpl) RemoveOrganisationMember(ctx context.Context, orgID, userID uint) error {
+	r.logger.Infof("------------ Removing user ID %d from organisation ID %d ----------", userID, orgID)
+	gormDB := r.db
+	return gormDB.RemoveOrganisationMember(ctx, orgID, userID, r.logger)
+}
+
+// CreateInvitation implements UserRepository.
+func (r *UserRepositoryImpl) CreateInvitation(ctx context.Context, invitation *model.OrganisationInvitation) error {
+	r.logger.Infof("------------ Creating invitation for organisation ID: %d ----------", invitation.OrganisationID)
+	gormDB := r.db
+	return gormDB.CreateInvitation(ctx, invitation, r.logger)
+}
+
+// ListPendingInvitations implements UserRepository.
+func (r *UserRepositoryImpl) ListPendingInvitations(ctx context.Context, orgID uint) ([]*model.OrganisationInvitation, error) {
+	r.logger.Infof("------------ Listing invitations of organisation ID: %d ----------", orgID)
+	gormDB := r.db
+	return gormDB.ListPendingInvitations(ctx, orgID, r.logger)
+}
+
+// DeleteInvitation implements UserRepository.
+func (r *UserRepositoryImpl) DeleteInvitation(ctx context.Context, orgID, id uint) error {
+	r.logger.Infof("------------ Deleting invitation ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.DeleteInvitation(ctx, orgID, id, r.logger)
+}
+
+// FindPendingInvitation implements UserRepository.
+func (r *UserRepositoryImpl) FindPendingInvitation(ctx context.Context, tokenHash string) (*model.OrganisationInvitation, error) {
+	r.logger.Infof("------------ FindPendingInvitation ----------")
+	gormDB := r.db
+	return gormDB.FindPendingInvitation(ctx, tokenHash, r.logger)
+}
+
+// AcceptInvitation implements UserRepository.
+func (r *UserRepositoryImpl) AcceptInvitation(ctx context.Context, tokenHash string, user *model.User) (*model.OrganisationInvitation, error) {
+	r.logger.Infof("------------ Accepting invitation for user ID: %d ----------", user.ID)
+	gormDB := r.db
+	return gormDB.AcceptInvitation(ctx, tokenHash, user, r.logger)
+}
+
+// CreateAuditLog implements UserRepository.
+func (r *UserRepositoryImpl) CreateAuditLog(ctx context.Context, entry *model.AuditLog) error {
+	gormDB := r.db
+	return gormDB.CreateAuditLog(ctx, entry, r.logger)
+}
+
+// ListAuditLogs implements UserRepository.
+func (r *UserRepositoryImpl) ListAuditLogs(ctx context.Context, filter database.AuditLogFilter, limit, offset int) ([]*model.AuditLog, int64, error) {
+	r.logger.Infof("------------ Listing audit logs (limit=%d, offset=%d) ----------", limit, offset)
+	gormDB := r.db
+	return gormDB.ListAuditLogs(ctx, filter, limit, offset, r.logger)
+}
+
+// CreateOAuthClient implements UserRepository.
+func (r *UserRepositoryImpl) CreateOAuthClient(ctx context.Context, client *model.OAuthClient) error {
+	r.logger.Infof("------------ Creating OAuth client: %s ----------", client.ClientID)
+	gormDB := r.db
+	return gormDB.CreateOAuthClient(ctx, client, r.logger)
+}
+
+// ListOAuthClients implements UserRepository.
+func (r *UserRepositoryImpl) ListOAuthClients(ctx context.Context) ([]*model.OAuthClient, error) {
+	r.logger.Infof("------------ Listing OAuth clients ----------")
+	gormDB := r.db
+	return gormDB.ListOAuthClients(ctx, r.logger)
+}
+
+// FindOAuthClient implements UserRepository.
+func (r *UserRepositoryImpl) FindOAuthClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	r.logger.Infof("------------ Finding OAuth client: %s ----------", clientID)
+	gormDB := r.db
+	return gormDB.FindOAuthClient(ctx, clientID, r.logger)
+}
+
+// DeleteOAuthClient implements UserRepository.
+func (r *UserRepositoryImpl) DeleteOAuthClient(ctx context.Context, id uint) error {
+	r.logger.Infof("------------ Deleting OAuth client ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.DeleteOAuthClient(ctx, id, r.logger)
+}
+
+// FindPendingInvitationByEmail implements UserRepository.
+func (r *UserRepositoryImpl) FindPendingInvitationByEmail(ctx context.Context, email string) (*model.OrganisationInvitation, error) {
+	r.logger.Infof("------------ Finding pending invitation for: %s ----------", email)
+	gormDB := r.db
+	return gormDB.FindPendingInvitationByEmail(ctx, email, r.logger)
+}
+
+// JoinOrganisation implements UserRepository.
+func (r *UserRepositoryImpl) JoinOrganisation(ctx context.Context, orgID uint, user *model.User, role string) error {
+	r.logger.Infof("------------ Adding user ID %d to organisation ID %d ----------", user.ID, orgID)
+	gormDB := r.db
+	return gormDB.JoinOrganisation(ctx, orgID, user, role, r.logger)
+}
+
+// FindUserIdentity implements UserRepository.
+func (r *UserRepositoryImpl) FindUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	r.logger.Infof("------------ Finding %s identity ----------", provider)
+	gormDB := r.db
+	return gormDB.FindUserIdentity(ctx, provider, subject, r.logger)
+}
+
+// LinkUserIdentity implements UserRepository.
+func (r *UserRepositoryImpl) LinkUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	r.logger.Infof("------------ Linking %s identity to user ID: %d ----------", identity.Provider, identity.UserID)
+	gormDB := r.db
+	return gormDB.LinkUserIdentity(ctx, identity, r.logger)
+}
+
+// TouchUserIdentity implements UserRepository.
+func (r *UserRepositoryImpl) TouchUserIdentity(ctx context.Context, id uint, email string) error {
+	r.logger.Infof("------------ Touching identity ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.TouchUserIdentity(ctx, id, email, r.logger)
+}
+
+// ListUserIdentities implements UserRepository.
+func (r *UserRepositoryImpl) ListUserIdentities(ctx context.Context, userID uint) ([]*model.UserIdentity, error) {
+	r.logger.Infof("------------ Listing identities of user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ListUserIdentities(ctx, userID, r.logger)
+}
+
+// DeleteUserIdentity implements UserRepository.
+func (r *UserRepositoryImpl) DeleteUserIdentity(ctx context.Context, userID, id uint) error {
+	r.logger.Infof("------------ Unlinking identity ID %d of user ID: %d ----------", id, userID)
+	gormDB := r.db
+	return gormDB.DeleteUserIdentity(ctx, userID, id, r.logger)
+}
+
+// CreatePersonalAccessToken implements UserRepository.
+func (r *UserRepositoryImpl) CreatePersonalAccessToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	r.logger.Infof("------------ Creating personal access token for user ID: %d ----------", token.UserID)
+	gormDB := r.db
+	return gormDB.CreatePersonalAccessToken(ctx, token, r.logger)
+}
+
+// ListPersonalAccessTokens implements UserRepository.
+func (r *UserRepositoryImpl) ListPersonalAccessTokens(ctx context.Context, userID uint) ([]*model.PersonalAccessToken, error) {
+	r.logger.Infof("------------ Listing personal access tokens of user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.ListPersonalAccessTokens(ctx, userID, r.logger)
+}
+
+// CountActivePersonalAccessTokens implements UserRepository.
+func (r *UserRepositoryImpl) CountActivePersonalAccessTokens(ctx context.Context, userID uint) (int64, error) {
+	r.logger.Infof("------------ Counting personal access tokens of user ID: %d ----------", userID)
+	gormDB := r.db
+	return gormDB.CountActivePersonalAccessTokens(ctx, userID, r.logger)
+}
+
+// FindPersonalAccessToken implements UserRepository.
+func (r *UserRepositoryImpl) FindPersonalAccessToken(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	r.logger.Infof("------------ Finding personal access token ----------")
+	gormDB := r.db
+	return gormDB.FindPersonalAccessToken(ctx, tokenHash, r.logger)
+}
+
+// TouchPersonalAccessToken implements UserRepository.
+func (r *UserRepositoryImpl) TouchPersonalAccessToken(ctx context.Context, id uint) error {
+	r.logger.Infof("------------ Touching personal access token ID: %d ----------", id)
+	gormDB := r.db
+	return gormDB.TouchPersonalAccessToken(ctx, id, r.logger)
+}
+
+// RevokePersonalAccessToken implements UserRepository.
+func (r *UserRepositoryImpl) RevokePersonalAccessToken(ctx context.Context, userID, id uint) error {
+	r.logger.Infof("------------ Revoking personal access token ID %d of user ID: %d ----------", id, userID)
+	gormDB := r.db
+	return gormDB.RevokePersonalAccessToken(ctx, userID, id, r.logger)
+}
+
 func NewUserRepository(db *database.GORM, logger *zap.SugaredLogger) UserRepository {
 	return &UserRepositoryImpl{db: db, logger: logger}
 }
@@ -1,226 +1,276 @@
 package services
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// =============================================================================
-// STRATÉGIE 1: VARIABLES GLOBALES + FONCTIONS GLOBALES
-// =============================================================================
-//
-// Description:
-// - Utilise des variables globales pour stocker la configuration
-// - Fonctions globales simples à utiliser
-// - Pas besoin d'instancier de service
-//
-// Avantages:
-// + Simplicité d'utilisation (pas d'instance à créer)
-// + Compatible avec du code legacy
-// + Accès rapide depuis n'importe où dans l'application
-// + Moins de boilerplate code
-//
-// Inconvénients:
-// - Variables globales mutables (risque de concurrence)
-// - Difficile à tester (état global partagé)
-// - Pas de configuration par instance
-// - Risque de collision de configuration
-// - Plus difficile à mocker dans les tests
-//
-// Usage:
-//   Initialize("secret-key")
-//   token, err := GenerateAccessToken(userID, email, ttl)
-//   claims, err := ParseAndValidateAccessToken(token)
-//
-// =============================================================================
+// Types de tokens (claim "typ")
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
 
-var jwtSecret []byte
+// Erreurs de validation des claims
+var (
+	ErrInvalidIssuer          = errors.New("invalid issuer")
+	ErrInvalidAudience        = errors.New("invalid audience")
+	ErrTokenNotYetValid       = errors.New("token not yet valid (nbf)")
+	ErrTokenWithoutExpiration = errors.New("token without expiration")
+	ErrTokenExpired           = errors.New("token expired")
+	ErrTokenIssuedInFuture    = errors.New("token issued in the future")
+	ErrInvalidTokenType       = errors.New("invalid token type")
+)
 
-// Initialize initialise le secret JWT global (à appeler au démarrage de l'application)
-func Initialize(secret string) {
-	jwtSecret = []byte(secret)
+// Les dates des tokens (iat, nbf, exp) sont émises à la milliseconde : la révocation de tous
+// les tokens d'un utilisateur (IsUserTokenRevoked) épargne ainsi un token émis juste après elle,
+// dans la même seconde (reconnexion immédiate après un changement de mot de passe)
+func init() {
+	jwt.TimePrecision = time.Millisecond
 }
 
-// ParseAndValidateAccessToken version globale compatible avec du code existant
-func ParseAndValidateAccessToken(tok string) (*AccessClaims, error) {
-	if len(jwtSecret) == 0 {
-		return nil, errors.New("jwt secret not initialized - call Initialize() first")
-	}
-
-	// Parse et valider la signature
-	token, err := jwt.ParseWithClaims(tok, &AccessClaims{}, func(t *jwt.Token) (interface{}, error) {
-		// Vérifier la méthode de signature pour éviter alg=none attacks
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+// =============================================================================
+// CONFIGURATION
+// =============================================================================
 
-	if err != nil {
-		return nil, err
-	}
+// JWTConfig regroupe la configuration de l'émetteur de tokens
+type JWTConfig struct {
+	// Issuer est la valeur du claim "iss" émise et exigée
+	Issuer string
+	// Audience est la valeur du claim "aud" émise et exigée
+	Audience string
+	// AccessTokenTTL est la durée de vie des access tokens
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL est la durée de vie des refresh tokens
+	RefreshTokenTTL time.Duration
+	// ClockSkew est la tolérance accordée sur exp, nbf et iat (horloges désynchronisées entre services)
+	ClockSkew time.Duration
+	// Clock fournit l'heure courante ; nil utilise l'horloge système
+	Clock Clock
+}
 
-	// Assertions et conversion de types
-	claims, ok := token.Claims.(*AccessClaims)
-	if !ok || !token.Valid {
-		return nil, errors.New("invalid claims or token")
+// DefaultJWTConfig retourne la configuration par défaut de l'auth service
+func DefaultJWTConfig() JWTConfig {
+	return JWTConfig{
+		Issuer:          "immogestion-auth",
+		Audience:        "immogestion-gateway",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 7 * 24 * time.Hour,
+		ClockSkew:       30 * time.Second,
 	}
+}
 
-	// Vérifier issuer (iss)
-	if claims.Issuer != "immogestion-auth" {
-		return nil, errors.New("invalid issuer")
-	}
+// Clock abstrait l'heure courante pour rendre l'émission et la validation testables
+type Clock interface {
+	Now() time.Time
+}
 
-	// Vérifier audience (aud)
-	requiredAud := "immogestion-gateway"
-	audOK := false
-	for _, a := range claims.Audience {
-		if a == requiredAud {
-			audOK = true
-			break
-		}
-	}
-	if !audOK {
-		return nil, errors.New("invalid audience")
-	}
+// systemClock utilise l'horloge système
+type systemClock struct{}
 
-	// Vérifier not before (nbf)
-	if claims.NotBefore != nil && claims.NotBefore.Time.After(time.Now()) {
-		return nil, errors.New("token not yet valid (nbf)")
-	}
+func (systemClock) Now() time.Time { return time.Now() }
 
-	// Vérifier expiration (exp)
-	if claims.ExpiresAt == nil {
-		return nil, errors.New("token without expiration")
-	}
-	if claims.ExpiresAt.Time.Before(time.Now()) {
-		return nil, errors.New("token expired")
-	}
+// FakeClock est une horloge contrôlable pour les tests
+type FakeClock struct {
+	now time.Time
+}
 
-	return claims, nil
+// NewFakeClock crée une horloge figée à now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
 }
 
-// GenerateAccessToken version globale compatible avec du code existant
-func GenerateAccessToken(userID uint, email string, ttl time.Duration) (string, error) {
-	if len(jwtSecret) == 0 {
-		return "", errors.New("jwt secret not initialized - call Initialize() first")
-	}
+// Now implémente Clock.
+func (c *FakeClock) Now() time.Time { return c.now }
 
-	now := time.Now()
-	claims := AccessClaims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        fmt.Sprintf("%d_%d", userID, now.Unix()), // jti unique
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-			NotBefore: jwt.NewNumericDate(now),
-			Subject:   fmt.Sprintf("%d", userID),
-			Issuer:    "immogestion-auth",
-			Audience:  []string{"immogestion-gateway"},
-		},
-	}
+// Advance avance l'horloge de d
+func (c *FakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
-}
+// Set positionne l'horloge à now
+func (c *FakeClock) Set(now time.Time) { c.now = now }
 
 // =============================================================================
-// STRATÉGIE 2: SERVICE ORIENTÉ OBJET (RECOMMANDÉ)
-// =============================================================================
-//
-// Description:
-// - Service structuré avec état encapsulé
-// - Configuration par instance
-// - Méthodes d'instance pour toutes les opérations
-//
-// Avantages:
-// + Isolation de la configuration (plusieurs instances possibles)
-// + Facilité de test (injection de dépendances)
-// + État encapsulé et thread-safe
-// + Configuration flexible par instance
-// + Meilleure architecture (SOLID principles)
-// + Facilité de mocking dans les tests
-//
-// Inconvénients:
-// - Plus de boilerplate (création d'instances)
-// - Légèrement plus complexe à utiliser
-// - Besoin de passer l'instance partout
-//
-// Usage:
-//   jwtService, err := NewJWTService(accessTTL, refreshTTL)
-//   accessToken, refreshToken, tokenID, _, _, err := jwtService.GenerateTokenPair(userID, email, role)
-//   claims, err := jwtService.ValidateAccessToken(token)
-//
+// CLAIMS
 // =============================================================================
 
 // AccessClaims représente les claims pour les access tokens
 type AccessClaims struct {
-	UserID uint   `json:"uid"`
-	Email  string `json:"email"`
-	Role   string `json:"role,omitempty"`
+	UserID        uint   `json:"uid"`
+	Email         string `json:"email"`
+	Role          string `json:"role,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	TokenType     string `json:"typ,omitempty"` // "access"
+	// OrgID est l'organisation active : les services en aval filtrent leurs données dessus
+	OrgID uint `json:"org_id,omitempty"`
+	// Scope liste les permissions effectives de l'utilisateur, séparées par des espaces (RFC 8693)
+	Scope string `json:"scope,omitempty"`
+	// Actor identifie l'administrateur qui agit au nom de l'utilisateur (impersonation, RFC 8693 §4.1)
+	Actor *ActorClaim `json:"act,omitempty"`
+	// ClientID est le client OpenID Connect destinataire du token (RFC 9068) ; vide pour les
+	// tokens de l'application, seuls acceptés par la gateway
+	ClientID string `json:"client_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim est le claim "act" d'un token d'impersonation : l'utilisateur réel
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"uid"`
+	Email   string `json:"email,omitempty"`
+}
+
+// IsImpersonated indique si le token a été émis pour un administrateur agissant au nom de l'utilisateur
+func (c *AccessClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// Scopes retourne les permissions embarquées dans le token
+func (c *AccessClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope indique si le token porte la permission demandée
+func (c *AccessClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenSubject regroupe les informations de l'utilisateur embarquées dans une paire de tokens
+type TokenSubject struct {
+	UserID        uint
+	Email         string
+	Role          string
+	EmailVerified bool
+	Scopes        []string
+	OrgID         uint
+}
+
 // RefreshClaims représente les claims pour les refresh tokens
 type RefreshClaims struct {
 	UserID    uint   `json:"uid"`
 	TokenType string `json:"typ"` // "refresh"
+	// FamilyID regroupe les refresh tokens issus d'une même connexion au fil des rotations.
+	// Il vaut le JTI du premier token de la famille.
+	FamilyID string `json:"fid,omitempty"`
+	// ClientID et Scope lient le refresh token d'un client OpenID Connect à ce client et aux
+	// scopes accordés ; vides pour les refresh tokens de l'application
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTService gère la création et validation des tokens JWT
+// =============================================================================
+// SERVICE
+// =============================================================================
+
+// JWTService est l'unique émetteur et vérificateur de tokens JWT de l'auth service
 type JWTService struct {
-	secretKey       []byte
-	accessTokenTTL  time.Duration
-	refreshTokenTTL time.Duration
+	keyring *Keyring
+	config  JWTConfig
+	clock   Clock
+	parser  *jwt.Parser
 }
 
-// NewJWTService crée une nouvelle instance de JWTService
-func NewJWTService(accessTokenTTL, refreshTokenTTL time.Duration) (*JWTService, error) {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		return nil, errors.New("JWT_SECRET non configuré dans les variables d'environnement")
+// NewJWTService crée un émetteur de tokens à partir d'un trousseau de clés et d'une configuration
+//
+// Usage:
+//
+//	keyring, err := LoadKeyringFromEnv()
+//	jwtService, err := NewJWTService(keyring, DefaultJWTConfig())
+//	accessToken, refreshToken, tokenID, _, _, err := jwtService.GenerateTokenPair(subject)
+//	claims, err := jwtService.ValidateAccessToken(accessToken)
+func NewJWTService(keyring *Keyring, config JWTConfig) (*JWTService, error) {
+	if keyring == nil {
+		return nil, errors.New("clés JWT non configurées")
+	}
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("issuer et audience JWT requis")
+	}
+	if config.AccessTokenTTL <= 0 || config.RefreshTokenTTL <= 0 {
+		return nil, errors.New("les durées de vie des tokens doivent être positives")
+	}
+	if config.ClockSkew < 0 {
+		return nil, errors.New("la tolérance d'horloge ne peut pas être négative")
 	}
 
-	if len(secretKey) < 32 {
-		return nil, errors.New("JWT_SECRET doit contenir au moins 32 caractères pour la sécurité")
+	clock := config.Clock
+	if clock == nil {
+		clock = systemClock{}
 	}
 
 	return &JWTService{
-		secretKey:       []byte(secretKey),
-		accessTokenTTL:  accessTokenTTL,
-		refreshTokenTTL: refreshTokenTTL,
+		keyring: keyring,
+		config:  config,
+		clock:   clock,
+		// Les claims sont validés par validateClaims/validateRefreshClaims (horloge injectable)
+		parser: jwt.NewParser(jwt.WithoutClaimsValidation()),
 	}, nil
 }
 
-// GenerateTokenPair génère une paire de tokens (access + refresh) avec un TokenID commun
-func (j *JWTService) GenerateTokenPair(userID uint, email, role string) (accessToken, refreshToken, tokenID string, accessExp, refreshExp int64, err error) {
+// JWKS retourne les clés publiques de vérification (document /.well-known/jwks.json)
+func (j *JWTService) JWKS() JWKSet {
+	return j.keyring.JWKS()
+}
+
+// SigningAlgorithm retourne l'algorithme de la clé de signature active
+func (j *JWTService) SigningAlgorithm() string {
+	key, err := j.keyring.Active()
+	if err != nil {
+		return ""
+	}
+	return key.Method.Alg()
+}
+
+// GetAccessTokenTTL retourne la durée de vie de l'access token
+func (j *JWTService) GetAccessTokenTTL() time.Duration {
+	return j.config.AccessTokenTTL
+}
+
+// GetRefreshTokenTTL retourne la durée de vie du refresh token
+func (j *JWTService) GetRefreshTokenTTL() time.Duration {
+	return j.config.RefreshTokenTTL
+}
+
+// GenerateTokenPair génère une paire de tokens (access + refresh) avec un TokenID commun.
+// Le refresh token démarre une nouvelle famille dont l'ID est le TokenID.
+func (j *JWTService) GenerateTokenPair(subject TokenSubject) (accessToken, refreshToken, tokenID string, accessExp, refreshExp int64, err error) {
+	return j.GenerateTokenPairInFamily(subject, "")
+}
+
+// GenerateTokenPairInFamily génère une paire de tokens dont le refresh token appartient
+// à la famille familyID (rotation). Si familyID est vide, une nouvelle famille est créée.
+func (j *JWTService) GenerateTokenPairInFamily(subject TokenSubject, familyID string) (accessToken, refreshToken, tokenID string, accessExp, refreshExp int64, err error) {
 	// Générer un ID unique pour cette session de token
-	tokenID, err = j.generateTokenID()
+	tokenID, err = generateTokenID()
 	if err != nil {
 		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération de l'ID de token: %w", err)
 	}
+	if familyID == "" {
+		familyID = tokenID
+	}
 
-	now := time.Now()
-	accessExp = now.Add(j.accessTokenTTL).Unix()
-	refreshExp = now.Add(j.refreshTokenTTL).Unix()
+	now := j.clock.Now()
+	accessExp = now.Add(j.config.AccessTokenTTL).Unix()
+	refreshExp = now.Add(j.config.RefreshTokenTTL).Unix()
 
 	// Générer l'access token
-	accessToken, err = j.generateAccessToken(userID, email, role, tokenID, now, accessExp)
+	accessToken, err = j.generateAccessToken(subject, tokenID, now, accessExp)
 	if err != nil {
 		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération de l'access token: %w", err)
 	}
 
 	// Générer le refresh token
-	refreshToken, err = j.generateRefreshToken(userID, email, role, tokenID, now, refreshExp)
+	refreshToken, err = j.generateRefreshToken(RefreshClaims{UserID: subject.UserID, FamilyID: familyID}, tokenID, now, refreshExp)
 	if err != nil {
 		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération du refresh token: %w", err)
 	}
@@ -228,213 +278,186 @@ func (j *JWTService) GenerateTokenPair(userID uint, email, role string) (accessT
 	return accessToken, refreshToken, tokenID, accessExp, refreshExp, nil
 }
 
-// ValidateAccessToken parse et valide un access token
-func (j *JWTService) ValidateAccessToken(tokenString string) (*AccessClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AccessClaims{}, j.keyFunc)
+// GenerateClientTokenPair génère la paire de tokens d'un client OpenID Connect : l'access token
+// a le client pour audience et ne porte que les scopes accordés (ni rôle ni permissions RBAC),
+// le refresh token reste lié au client et à ces scopes au fil des rotations.
+// Si familyID est vide, une nouvelle famille est créée.
+func (j *JWTService) GenerateClientTokenPair(userID uint, clientID, scope, familyID string) (accessToken, refreshToken, tokenID string, accessExp, refreshExp int64, err error) {
+	if clientID == "" {
+		return "", "", "", 0, 0, errors.New("client_id requis")
+	}
+	tokenID, err = generateTokenID()
 	if err != nil {
-		return nil, fmt.Errorf("error parsing token: %w", err)
+		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération de l'ID de token: %w", err)
+	}
+	if familyID == "" {
+		familyID = tokenID
 	}
 
-	claims, ok := token.Claims.(*AccessClaims)
-	if !ok || !token.Valid {
-		return nil, errors.New("invalid claims or token")
+	now := j.clock.Now()
+	accessExp = now.Add(j.config.AccessTokenTTL).Unix()
+	refreshExp = now.Add(j.config.RefreshTokenTTL).Unix()
+
+	registered := j.registeredClaims(userID, tokenID, now, accessExp)
+	registered.Audience = []string{clientID}
+	accessToken, err = j.sign(AccessClaims{
+		UserID:           userID,
+		TokenType:        TokenTypeAccess,
+		Scope:            scope,
+		ClientID:         clientID,
+		RegisteredClaims: registered,
+	})
+	if err != nil {
+		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération de l'access token: %w", err)
 	}
 
-	// Validations supplémentaires
-	if err := j.validateClaims(claims); err != nil {
-		return nil, err
+	refreshToken, err = j.generateRefreshToken(RefreshClaims{UserID: userID, FamilyID: familyID, ClientID: clientID, Scope: scope}, tokenID, now, refreshExp)
+	if err != nil {
+		return "", "", "", 0, 0, fmt.Errorf("erreur lors de la génération du refresh token: %w", err)
 	}
 
-	return claims, nil
+	return accessToken, refreshToken, tokenID, accessExp, refreshExp, nil
 }
 
-// GetTokenExpiry extrait la date d'expiration du token sans validation
-func (j *JWTService) GetTokenExpiry(tokenString string) (int64, error) {
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &AccessClaims{})
+// GenerateImpersonationToken génère un access token de l'utilisateur subject portant le claim
+// "act" de l'administrateur actor. Aucun refresh token n'est émis : l'impersonation expire avec
+// le token, dont la durée de vie ne dépasse jamais celle d'un access token normal.
+func (j *JWTService) GenerateImpersonationToken(subject TokenSubject, actor ActorClaim, ttl time.Duration) (token, tokenID string, expiresAt int64, err error) {
+	tokenID, err = generateTokenID()
 	if err != nil {
-		return 0, fmt.Errorf("erreur lors de l'extraction de l'expiration du token: %w", err)
+		return "", "", 0, fmt.Errorf("erreur lors de la génération de l'ID de token: %w", err)
 	}
-
-	if claims, ok := token.Claims.(*AccessClaims); ok && claims.ExpiresAt != nil {
-		return claims.ExpiresAt.Time.Unix(), nil
+	if ttl <= 0 || ttl > j.config.AccessTokenTTL {
+		ttl = j.config.AccessTokenTTL
 	}
 
-	return 0, fmt.Errorf("impossible d'extraire l'expiration du token")
+	now := j.clock.Now()
+	expiresAt = now.Add(ttl).Unix()
+	actor.Subject = fmt.Sprintf("%d", actor.UserID)
+
+	claims := AccessClaims{
+		UserID:           subject.UserID,
+		Email:            subject.Email,
+		Role:             subject.Role,
+		EmailVerified:    subject.EmailVerified,
+		TokenType:        TokenTypeAccess,
+		Scope:            strings.Join(subject.Scopes, " "),
+		OrgID:            subject.OrgID,
+		Actor:            &actor,
+		RegisteredClaims: j.registeredClaims(subject.UserID, tokenID, now, expiresAt),
+	}
+	token, err = j.sign(claims)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("erreur lors de la génération du token d'impersonation: %w", err)
+	}
+	return token, tokenID, expiresAt, nil
 }
 
-// IsTokenExpired vérifie si un token est expiré sans validation complète
-func (j *JWTService) IsTokenExpired(tokenString string) bool {
-	claims, err := j.ExtractClaims(tokenString)
+// ValidateAccessToken parse et valide un access token de l'application.
+// Les tokens délivrés à un client OpenID Connect sont refusés.
+func (j *JWTService) ValidateAccessToken(tokenString string) (*AccessClaims, error) {
+	claims, err := j.parseAccessToken(tokenString)
 	if err != nil {
-		return true
+		return nil, err
 	}
 
-	if claims.ExpiresAt == nil {
-		return true
+	if err := j.validateClaims(claims); err != nil {
+		return nil, err
 	}
 
-	return claims.ExpiresAt.Time.Before(time.Now())
+	return claims, nil
 }
 
-// ExtractClaims extrait les claims d'un token sans validation complète
-func (j *JWTService) ExtractClaims(tokenString string) (*AccessClaims, error) {
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &AccessClaims{})
+// ValidateClientAccessToken parse et valide un access token délivré à un client OpenID Connect :
+// l'audience doit être le client_id porté par le token
+func (j *JWTService) ValidateClientAccessToken(tokenString string) (*AccessClaims, error) {
+	claims, err := j.parseAccessToken(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing token: %w", err)
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*AccessClaims); ok {
-		return claims, nil
+	if err := j.validateClientClaims(claims); err != nil {
+		return nil, err
 	}
 
-	return nil, errors.New("unable to extract claims")
+	return claims, nil
 }
 
-// GetAccessTokenTTL retourne la durée de vie de l'access token
-func (j *JWTService) GetAccessTokenTTL() time.Duration {
-	return j.accessTokenTTL
-}
+// ValidateRefreshToken parse et valide un refresh token
+func (j *JWTService) ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
+	token, err := j.parser.ParseWithClaims(tokenString, &RefreshClaims{}, j.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors du parsing du refresh token: %w", err)
+	}
 
-// GetRefreshTokenTTL retourne la durée de vie du refresh token
-func (j *JWTService) GetRefreshTokenTTL() time.Duration {
-	return j.refreshTokenTTL
+	claims, ok := token.Claims.(*RefreshClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("claims invalides ou refresh token invalide")
+	}
+
+	// validations de base + typ=refresh
+	if err := j.validateRefreshClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
 // =============================================================================
-// STRATÉGIE 3: APPROCHE HYBRIDE/FACTORY
-// =============================================================================
-//
-// Description:
-// - Combine les avantages des deux approches précédentes
-// - Utilise le service structuré mais avec des méthodes de création simplifiées
-// - Factory pattern pour créer des instances pré-configurées
-//
-// Avantages:
-// + Flexibilité d'usage (global ou instance)
-// + Configuration par défaut simple
-// + Possibilité de personnalisation avancée
-// + Transition facile entre les approches
-//
-// Inconvénients:
-// - Plus de complexité dans le code
-// - Peut créer de la confusion sur quelle méthode utiliser
-// - Duplicitation de logique potentielle
-//
-// Usage:
-//   // Approche simple
-//   token, err := CreateAccessToken(ctx, userID, email, role)
-//
-//   // Approche avec configuration
-//   service := NewJWTServiceWithSecret("custom-secret", ttl1, ttl2)
-//   token, err := service.GenerateAccessToken(userID, email, role)
-//
+// MÉTHODES PRIVÉES
 // =============================================================================
 
-// CreateAccessToken crée un token JWT avec configuration par défaut
-func CreateAccessToken(ctx context.Context, userID uint, email, role string) (string, error) {
-	// Utiliser des valeurs par défaut
-	service, err := NewJWTService(15*time.Minute, 7*24*time.Hour)
-	if err != nil {
-		return "", err
+// registeredClaims construit les claims standards communs à tous les tokens
+func (j *JWTService) registeredClaims(userID uint, tokenID string, issuedAt time.Time, expiresAt int64) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ID:        tokenID, // JTI (JWT ID)
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		ExpiresAt: jwt.NewNumericDate(time.Unix(expiresAt, 0)),
+		NotBefore: jwt.NewNumericDate(issuedAt),
+		Subject:   fmt.Sprintf("%d", userID),
+		Issuer:    j.config.Issuer,
+		Audience:  []string{j.config.Audience},
 	}
-
-	return service.GenerateAccessToken(userID, email, role)
 }
 
-// NewJWTServiceWithSecret crée un service JWT avec un secret personnalisé
-func NewJWTServiceWithSecret(secret string, accessTokenTTL, refreshTokenTTL time.Duration) (*JWTService, error) {
-	if len(secret) < 32 {
-		return nil, errors.New("secret must be at least 32 characters long")
+// generateAccessToken génère un access token
+func (j *JWTService) generateAccessToken(subject TokenSubject, tokenID string, issuedAt time.Time, expiresAt int64) (string, error) {
+	claims := AccessClaims{
+		UserID:           subject.UserID,
+		Email:            subject.Email,
+		Role:             subject.Role,
+		EmailVerified:    subject.EmailVerified,
+		TokenType:        TokenTypeAccess,
+		Scope:            strings.Join(subject.Scopes, " "),
+		OrgID:            subject.OrgID,
+		RegisteredClaims: j.registeredClaims(subject.UserID, tokenID, issuedAt, expiresAt),
 	}
 
-	return &JWTService{
-		secretKey:       []byte(secret),
-		accessTokenTTL:  accessTokenTTL,
-		refreshTokenTTL: refreshTokenTTL,
-	}, nil
+	return j.sign(claims)
 }
 
-// SetSecret définit le secret JWT pour une instance existante
-func (j *JWTService) SetSecret(secret string) error {
-	if len(secret) < 32 {
-		return errors.New("secret must be at least 32 characters long")
-	}
-	j.secretKey = []byte(secret)
-	return nil
+// generateRefreshToken génère un refresh token à partir de ses claims propres (uid, famille, client)
+func (j *JWTService) generateRefreshToken(claims RefreshClaims, tokenID string, issuedAt time.Time, expiresAt int64) (string, error) {
+	claims.TokenType = TokenTypeRefresh
+	claims.RegisteredClaims = j.registeredClaims(claims.UserID, tokenID, issuedAt, expiresAt)
+	return j.sign(claims)
 }
 
-// GenerateAccessToken génère un access token simple (version hybride)
-func (j *JWTService) GenerateAccessToken(userID uint, email, role string) (string, error) {
-	if len(j.secretKey) == 0 {
-		return "", errors.New("jwt secret not configured")
+// parseAccessToken parse un access token et vérifie sa signature, sans valider ses claims
+func (j *JWTService) parseAccessToken(tokenString string) (*AccessClaims, error) {
+	token, err := j.parser.ParseWithClaims(tokenString, &AccessClaims{}, j.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %w", err)
 	}
 
-	now := time.Now()
-	claims := &AccessClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        fmt.Sprintf("%d_%d", userID, now.Unix()), // jti unique
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessTokenTTL)),
-			NotBefore: jwt.NewNumericDate(now),
-			Subject:   fmt.Sprintf("%d", userID),
-			Issuer:    "immogestion-auth",
-			Audience:  []string{"immogestion-gateway"},
-		},
+	claims, ok := token.Claims.(*AccessClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid claims or token")
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
-}
-
-// =============================================================================
-// MÉTHODES PRIVÉES PARTAGÉES (utilisées par toutes les stratégies)
-// =============================================================================
-
-// generateAccessToken génère un access token
-func (j *JWTService) generateAccessToken(userID uint, email, role, tokenID string, issuedAt time.Time, expiresAt int64) (string, error) {
-	claims := AccessClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        tokenID, // JTI (JWT ID)
-			IssuedAt:  jwt.NewNumericDate(issuedAt),
-			ExpiresAt: jwt.NewNumericDate(time.Unix(expiresAt, 0)),
-			NotBefore: jwt.NewNumericDate(issuedAt),
-			Subject:   fmt.Sprintf("%d", userID),
-			Issuer:    "immogestion-auth",
-			Audience:  []string{"immogestion-gateway"},
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
-}
-
-// generateRefreshToken génère un refresh token
-func (j *JWTService) generateRefreshToken(userID uint, email, role, tokenID string, issuedAt time.Time, expiresAt int64) (string, error) {
-	claims := RefreshClaims{
-		UserID:    userID,
-		TokenType: "refresh",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        tokenID, // <— plus de suffixe
-			IssuedAt:  jwt.NewNumericDate(issuedAt),
-			ExpiresAt: jwt.NewNumericDate(time.Unix(expiresAt, 0)),
-			NotBefore: jwt.NewNumericDate(issuedAt),
-			Subject:   fmt.Sprintf("%d", userID),
-			Issuer:    "immogestion-auth",
-			Audience:  []string{"immogestion-gateway"},
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return claims, nil
 }
 
 // generateTokenID génère un ID unique sécurisé pour le token
-func (j *JWTService) generateTokenID() (string, error) {
+func generateTokenID() (string, error) {
 	bytes := make([]byte, 16) // 128 bits
 	if _, err := rand.Read(bytes); err != nil {
 		return "", fmt.Errorf("erreur lors de la génération de l'ID: %w", err)
@@ -442,113 +465,128 @@ func (j *JWTService) generateTokenID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// keyFunc retourne la clé de signature pour la validation JWT
+// sign signe les claims avec la clé active et ajoute son kid dans l'en-tête
+func (j *JWTService) sign(claims jwt.Claims) (string, error) {
+	key, err := j.keyring.Active()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyFunc retourne la clé de vérification correspondant au kid du token
 func (j *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
-	// Vérifier la méthode de signature pour éviter les attaques alg=none
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, fmt.Errorf("méthode de signature inattendue: %v", token.Header["alg"])
+	var key *SigningKey
+	if kid, ok := token.Header["kid"].(string); ok {
+		found, exists := j.keyring.Get(kid)
+		if !exists {
+			return nil, fmt.Errorf("kid inconnu: %s", kid)
+		}
+		key = found
+	} else if found, ok := j.keyring.single(); ok {
+		// Tokens émis avant l'ajout du kid
+		key = found
+	} else {
+		return nil, errors.New("kid manquant dans l'en-tête du token")
 	}
 
-	if len(j.secretKey) == 0 {
-		return nil, errors.New("clé secrète JWT non configurée")
+	// L'algorithme doit être celui de la clé (évite alg=none et la confusion HMAC/RSA)
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("méthode de signature inattendue: %v", token.Header["alg"])
 	}
 
-	return j.secretKey, nil
+	return key.PublicKey, nil
 }
 
-// validateClaims valide les claims standards
-func (j *JWTService) validateClaims(claims *AccessClaims) error {
-	now := time.Now()
+// validateRegisteredClaims valide les claims standards (iss, aud, nbf, exp, iat)
+// en tolérant ClockSkew de décalage d'horloge ; audience est la valeur exigée dans "aud"
+func (j *JWTService) validateRegisteredClaims(claims *jwt.RegisteredClaims, audience string) error {
+	now := j.clock.Now()
+	skew := j.config.ClockSkew
 
 	// Vérifier l'issuer
-	if claims.Issuer != "immogestion-auth" {
-		return fmt.Errorf("invalid issuer: expected 'immogestion-auth', got '%s'", claims.Issuer)
+	if claims.Issuer != j.config.Issuer {
+		return fmt.Errorf("%w: expected '%s', got '%s'", ErrInvalidIssuer, j.config.Issuer, claims.Issuer)
 	}
 
 	// Vérifier l'audience
-	requiredAud := "immogestion-gateway"
 	audOK := false
 	for _, a := range claims.Audience {
-		if a == requiredAud {
+		if a == audience {
 			audOK = true
 			break
 		}
 	}
 	if !audOK {
-		return errors.New("invalid audience")
+		return ErrInvalidAudience
 	}
 
 	// Vérifier not before (nbf)
-	if claims.NotBefore != nil && claims.NotBefore.Time.After(now) {
-		return errors.New("token not yet valid (nbf)")
+	if claims.NotBefore != nil && claims.NotBefore.Time.After(now.Add(skew)) {
+		return ErrTokenNotYetValid
 	}
 
 	// Vérifier expiration (exp)
 	if claims.ExpiresAt == nil {
-		return errors.New("token without expiration")
+		return ErrTokenWithoutExpiration
 	}
-	if claims.ExpiresAt.Time.Before(now) {
-		return errors.New("token expired")
+	if !now.Add(-skew).Before(claims.ExpiresAt.Time) {
+		return ErrTokenExpired
 	}
 
 	// Vérifier issued at (iat)
-	if claims.IssuedAt != nil && claims.IssuedAt.Time.After(now.Add(5*time.Minute)) {
-		return errors.New("token issued in the future")
+	if claims.IssuedAt != nil && claims.IssuedAt.Time.After(now.Add(skew)) {
+		return ErrTokenIssuedInFuture
 	}
 
 	return nil
 }
 
-// validateRefreshTokenClaims valide les claims d'un refresh token
-func (j *JWTService) ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, j.keyFunc)
-	if err != nil {
-		return nil, fmt.Errorf("erreur lors du parsing du refresh token: %w", err)
+// validateClaims valide les claims d'un access token de l'application
+func (j *JWTService) validateClaims(claims *AccessClaims) error {
+	if err := j.validateRegisteredClaims(&claims.RegisteredClaims, j.config.Audience); err != nil {
+		return err
 	}
 
-	claims, ok := token.Claims.(*RefreshClaims)
-	if !ok || !token.Valid {
-		return nil, errors.New("claims invalides ou refresh token invalide")
+	// Un token délivré à un client OpenID Connect n'ouvre pas l'accès à l'API
+	if claims.ClientID != "" {
+		return ErrInvalidAudience
 	}
 
-	// validations de base + typ=refresh
-	if err := j.validateRefreshClaims(claims); err != nil {
-		return nil, err
+	// Un refresh token ne doit jamais être accepté comme access token
+	// (les access tokens émis avant l'ajout de "typ" n'ont pas ce claim)
+	if claims.TokenType != "" && claims.TokenType != TokenTypeAccess {
+		return ErrInvalidTokenType
 	}
-	return claims, nil
-}
-func (j *JWTService) validateRefreshClaims(claims *RefreshClaims) error {
-	now := time.Now()
 
-	if claims.Issuer != "immogestion-auth" {
-		return fmt.Errorf("invalid issuer: expected 'immogestion-auth', got '%s'", claims.Issuer)
-	}
+	return nil
+}
 
-	requiredAud := "immogestion-gateway"
-	audOK := false
-	for _, a := range claims.Audience {
-		if a == requiredAud {
-			audOK = true
-			break
-		}
+// validateClientClaims valide les claims d'un access token délivré à un client OpenID Connect
+func (j *JWTService) validateClientClaims(claims *AccessClaims) error {
+	if claims.ClientID == "" {
+		return ErrInvalidAudience
 	}
-	if !audOK {
-		return errors.New("invalid audience")
+	if err := j.validateRegisteredClaims(&claims.RegisteredClaims, claims.ClientID); err != nil {
+		return err
 	}
-
-	if claims.NotBefore != nil && claims.NotBefore.Time.After(now) {
-		return errors.New("refresh token not yet valid (nbf)")
+	if claims.TokenType != TokenTypeAccess {
+		return ErrInvalidTokenType
 	}
+	return nil
+}
 
-	if claims.ExpiresAt == nil {
-		return errors.New("refresh token without expiration")
-	}
-	if claims.ExpiresAt.Time.Before(now) {
-		return errors.New("refresh token expired")
+// validateRefreshClaims valide les claims d'un refresh token
+func (j *JWTService) validateRefreshClaims(claims *RefreshClaims) error {
+	if err := j.validateRegisteredClaims(&claims.RegisteredClaims, j.config.Audience); err != nil {
+		return fmt.Errorf("refresh token: %w", err)
 	}
 
-	if claims.TokenType != "refresh" {
-		return errors.New("invalid refresh token format") // typ manquant ou incorrect
+	if claims.TokenType != TokenTypeRefresh {
+		return fmt.Errorf("refresh token: %w", ErrInvalidTokenType) // typ manquant ou incorrect
 	}
 
 	return nil
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"api/services/auth/internal/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestJWTService crée un émetteur HMAC dont l'horloge est contrôlée par le test
+func newTestJWTService(t *testing.T) (*JWTService, *FakeClock) {
+	t.Helper()
+	keyring, err := NewHMACKeyring("test-secret-with-enough-entropy-0123456789")
+	if err != nil {
+		t.Fatalf("NewHMACKeyring: %v", err)
+	}
+	clock := NewFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	config := DefaultJWTConfig()
+	config.Clock = clock
+	service, err := NewJWTService(keyring, config)
+	if err != nil {
+		t.Fatalf("NewJWTService: %v", err)
+	}
+	return service, clock
+}
+
+// validRegisteredClaims retourne des claims standards valides à l'instant de l'horloge
+func validRegisteredClaims(j *JWTService, now time.Time) jwt.RegisteredClaims {
+	return j.registeredClaims(42, "jti", now, now.Add(j.config.AccessTokenTTL).Unix())
+}
+
+func TestValidateClaims(t *testing.T) {
+	service, clock := newTestJWTService(t)
+	now := clock.Now()
+	skew := service.config.ClockSkew
+	ttl := service.config.AccessTokenTTL
+
+	tests := []struct {
+		name    string
+		mutate  func(*AccessClaims)
+		advance time.Duration
+		wantErr error
+	}{
+		{name: "valid", mutate: func(*AccessClaims) {}},
+		{name: "valid without typ (legacy token)", mutate: func(c *AccessClaims) { c.TokenType = "" }},
+		{name: "wrong issuer", mutate: func(c *AccessClaims) { c.Issuer = "someone-else" }, wantErr: ErrInvalidIssuer},
+		{name: "wrong audience", mutate: func(c *AccessClaims) { c.Audience = jwt.ClaimStrings{"other-audience"} }, wantErr: ErrInvalidAudience},
+		{name: "missing audience", mutate: func(c *AccessClaims) { c.Audience = nil }, wantErr: ErrInvalidAudience},
+		{
+			name:    "nbf in the future beyond skew",
+			mutate:  func(c *AccessClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(skew + time.Second)) },
+			wantErr: ErrTokenNotYetValid,
+		},
+		{name: "nbf in the future within skew", mutate: func(c *AccessClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(skew)) }},
+		{
+			name:    "iat in the future beyond skew",
+			mutate:  func(c *AccessClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(skew + time.Second)) },
+			wantErr: ErrTokenIssuedInFuture,
+		},
+		{name: "iat in the future within skew", mutate: func(c *AccessClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(skew)) }},
+		{name: "missing exp", mutate: func(c *AccessClaims) { c.ExpiresAt = nil }, wantErr: ErrTokenWithoutExpiration},
+		{name: "expired beyond skew", mutate: func(*AccessClaims) {}, advance: ttl + skew, wantErr: ErrTokenExpired},
+		{name: "expired within skew", mutate: func(*AccessClaims) {}, advance: ttl + skew - time.Second},
+		{name: "refresh typ rejected", mutate: func(c *AccessClaims) { c.TokenType = TokenTypeRefresh }, wantErr: ErrInvalidTokenType},
+		{name: "client token rejected", mutate: func(c *AccessClaims) { c.ClientID = "client-1" }, wantErr: ErrInvalidAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(now)
+			claims := &AccessClaims{UserID: 42, TokenType: TokenTypeAccess, RegisteredClaims: validRegisteredClaims(service, now)}
+			tt.mutate(claims)
+			clock.Advance(tt.advance)
+
+			err := service.validateClaims(claims)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("validateClaims() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validateClaims() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRefreshClaims(t *testing.T) {
+	service, clock := newTestJWTService(t)
+	now := clock.Now()
+	skew := service.config.ClockSkew
+	ttl := service.config.AccessTokenTTL
+
+	tests := []struct {
+		name    string
+		mutate  func(*RefreshClaims)
+		advance time.Duration
+		wantErr error
+	}{
+		{name: "valid", mutate: func(*RefreshClaims) {}},
+		{name: "wrong issuer", mutate: func(c *RefreshClaims) { c.Issuer = "someone-else" }, wantErr: ErrInvalidIssuer},
+		{name: "wrong audience", mutate: func(c *RefreshClaims) { c.Audience = jwt.ClaimStrings{"other-audience"} }, wantErr: ErrInvalidAudience},
+		{
+			name:    "nbf in the future beyond skew",
+			mutate:  func(c *RefreshClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(skew + time.Second)) },
+			wantErr: ErrTokenNotYetValid,
+		},
+		{
+			name:    "iat in the future beyond skew",
+			mutate:  func(c *RefreshClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(skew + time.Second)) },
+			wantErr: ErrTokenIssuedInFuture,
+		},
+		{name: "missing exp", mutate: func(c *RefreshClaims) { c.ExpiresAt = nil }, wantErr: ErrTokenWithoutExpiration},
+		{name: "expired beyond skew", mutate: func(*RefreshClaims) {}, advance: ttl + skew, wantErr: ErrTokenExpired},
+		{name: "expired within skew", mutate: func(*RefreshClaims) {}, advance: ttl + skew - time.Second},
+		{name: "access typ rejected", mutate: func(c *RefreshClaims) { c.TokenType = TokenTypeAccess }, wantErr: ErrInvalidTokenType},
+		{name: "missing typ rejected", mutate: func(c *RefreshClaims) { c.TokenType = "" }, wantErr: ErrInvalidTokenType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(now)
+			claims := &RefreshClaims{UserID: 42, TokenType: TokenTypeRefresh, FamilyID: "jti", RegisteredClaims: validRegisteredClaims(service, now)}
+			tt.mutate(claims)
+			clock.Advance(tt.advance)
+
+			err := service.validateRefreshClaims(claims)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("validateRefreshClaims() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validateRefreshClaims() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTokenTypeConfusion vérifie, de bout en bout, qu'aucun token n'est accepté à la place de l'autre
+func TestTokenTypeConfusion(t *testing.T) {
+	service, clock := newTestJWTService(t)
+	accessToken, refreshToken, _, _, _, err := service.GenerateTokenPair(TokenSubject{UserID: 42, Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(accessToken); err != nil {
+		t.Fatalf("ValidateAccessToken(access) error = %v", err)
+	}
+	if _, err := service.ValidateRefreshToken(refreshToken); err != nil {
+		t.Fatalf("ValidateRefreshToken(refresh) error = %v", err)
+	}
+	if _, err := service.ValidateAccessToken(refreshToken); !errors.Is(err, ErrInvalidTokenType) {
+		t.Fatalf("ValidateAccessToken(refresh) error = %v, want %v", err, ErrInvalidTokenType)
+	}
+	if _, err := service.ValidateRefreshToken(accessToken); !errors.Is(err, ErrInvalidTokenType) {
+		t.Fatalf("ValidateRefreshToken(access) error = %v, want %v", err, ErrInvalidTokenType)
+	}
+
+	clock.Advance(service.config.AccessTokenTTL + service.config.ClockSkew)
+	if _, err := service.ValidateAccessToken(accessToken); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("ValidateAccessToken(expired) error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+// TestClientTokenPair vérifie que les tokens d'un client OpenID Connect restent liés au client
+func TestClientTokenPair(t *testing.T) {
+	service, _ := newTestJWTService(t)
+	accessToken, refreshToken, _, _, _, err := service.GenerateClientTokenPair(42, "client-1", "openid email", "")
+	if err != nil {
+		t.Fatalf("GenerateClientTokenPair: %v", err)
+	}
+
+	claims, err := service.ValidateClientAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("ValidateClientAccessToken(client) error = %v", err)
+	}
+	if claims.ClientID != "client-1" || claims.Scope != "openid email" || claims.Role != "" {
+		t.Fatalf("client claims = %+v, want client-1 with scope \"openid email\" and no role", claims)
+	}
+	if _, err := service.ValidateAccessToken(accessToken); !errors.Is(err, ErrInvalidAudience) {
+		t.Fatalf("ValidateAccessToken(client) error = %v, want %v", err, ErrInvalidAudience)
+	}
+
+	firstParty, _, _, _, _, err := service.GenerateTokenPair(TokenSubject{UserID: 42})
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if _, err := service.ValidateClientAccessToken(firstParty); !errors.Is(err, ErrInvalidAudience) {
+		t.Fatalf("ValidateClientAccessToken(first-party) error = %v, want %v", err, ErrInvalidAudience)
+	}
+
+	refreshClaims, err := service.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		t.Fatalf("ValidateRefreshToken(client) error = %v", err)
+	}
+	if refreshClaims.ClientID != "client-1" || refreshClaims.Scope != "openid email" {
+		t.Fatalf("refresh claims = %+v, want client-1 with scope \"openid email\"", refreshClaims)
+	}
+}
+
+func TestReloginAfterRevocation(t *testing.T) {
+	service, clock := newTestJWTService(t)
+	clock.Advance(100 * time.Millisecond)
+	subject := TokenSubject{UserID: 42, Role: "user"}
+
+	issuedAt := func(accessToken string) time.Time {
+		t.Helper()
+		claims, err := service.ValidateAccessToken(accessToken)
+		if err != nil {
+			t.Fatalf("ValidateAccessToken: %v", err)
+		}
+		return claims.IssuedAt.Time
+	}
+
+	before, _, _, _, _, err := service.GenerateTokenPair(subject)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	// Changement de mot de passe puis reconnexion immédiate, dans la même seconde
+	clock.Advance(300 * time.Millisecond)
+	revokedAt := clock.Now().UnixMilli()
+	clock.Advance(50 * time.Millisecond)
+	after, _, _, _, _, err := service.GenerateTokenPair(subject)
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	if !database.IssuedBeforeRevocation(issuedAt(before), revokedAt) {
+		t.Fatalf("token issued before the revocation is still valid")
+	}
+	if database.IssuedBeforeRevocation(issuedAt(after), revokedAt) {
+		t.Fatalf("token issued right after the revocation, in the same second, is revoked")
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey est une clé de signature JWT identifiée par son kid.
+// PrivateKey est nil pour une clé retirée conservée uniquement pour la vérification.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+}
+
+// Keyring regroupe les clés de signature : une clé active signe les nouveaux tokens,
+// les autres restent valides pour la vérification pendant une rotation.
+type Keyring struct {
+	mu        sync.RWMutex
+	keys      map[string]*SigningKey
+	activeKID string
+}
+
+// NewKeyring crée un trousseau de clés vide
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]*SigningKey)}
+}
+
+// NewHMACKeyring crée un trousseau avec une seule clé HS256 (secret partagé)
+func NewHMACKeyring(secret string) (*Keyring, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("secret must be at least 32 characters long")
+	}
+	keyring := NewKeyring()
+	keyring.Add(&SigningKey{
+		ID:         "hs256",
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: []byte(secret),
+		PublicKey:  []byte(secret),
+	}, true)
+	return keyring, nil
+}
+
+// Add ajoute une clé au trousseau ; si active, elle devient la clé de signature
+func (k *Keyring) Add(key *SigningKey, active bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.ID] = key
+	if active {
+		k.activeKID = key.ID
+	}
+}
+
+// Active retourne la clé utilisée pour signer les nouveaux tokens
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[k.activeKID]
+	if !ok || key.PrivateKey == nil {
+		return nil, errors.New("aucune clé de signature active")
+	}
+	return key, nil
+}
+
+// Get retourne une clé par son kid
+func (k *Keyring) Get(kid string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// single retourne l'unique clé du trousseau (tokens émis avant l'ajout du kid)
+func (k *Keyring) single() (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.keys) != 1 {
+		return nil, false
+	}
+	for _, key := range k.keys {
+		return key, true
+	}
+	return nil, false
+}
+
+// JWK est la représentation JSON (RFC 7517) d'une clé publique
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519) et EC (Y uniquement)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet est le document publié sur /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS retourne les clés publiques asymétriques du trousseau (les secrets HMAC ne sont jamais publiés)
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := k.keys[kid]
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: kid,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// LoadKeyringFromEnv construit le trousseau à partir de l'environnement :
+//   - JWT_SIGNING_ALG : HS256 (défaut, JWT_SECRET), RS256 ou EdDSA
+//   - JWT_KEYS_DIR : répertoire de clés PEM, le nom du fichier (sans .pem) sert de kid.
+//     Une clé privée signe et vérifie, une clé publique seule ne fait que vérifier (clé retirée).
+//   - JWT_ACTIVE_KID : kid de la clé de signature (défaut : dernier kid par ordre alphabétique)
+//
+// Sans JWT_KEYS_DIR en RS256/EdDSA, une clé éphémère est générée (développement uniquement).
+func LoadKeyringFromEnv() (*Keyring, error) {
+	alg := strings.ToUpper(os.Getenv("JWT_SIGNING_ALG"))
+	if alg == "" || alg == "HS256" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET non configuré dans les variables d'environnement")
+		}
+		if len(secret) < 32 {
+			return nil, errors.New("JWT_SECRET doit contenir au moins 32 caractères pour la sécurité")
+		}
+		return NewHMACKeyring(secret)
+	}
+	if alg != "RS256" && alg != "EDDSA" {
+		return nil, fmt.Errorf("JWT_SIGNING_ALG %q non supporté (HS256, RS256 ou EdDSA)", alg)
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		key, err := GenerateSigningKey("ephemeral", alg)
+		if err != nil {
+			return nil, err
+		}
+		keyring := NewKeyring()
+		keyring.Add(key, true)
+		return keyring, nil
+	}
+
+	keyring, err := LoadKeyringFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if activeKID := os.Getenv("JWT_ACTIVE_KID"); activeKID != "" {
+		key, ok := keyring.Get(activeKID)
+		if !ok || key.PrivateKey == nil {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID %q introuvable ou sans clé privée dans %s", activeKID, dir)
+		}
+		keyring.Add(key, true)
+	}
+	return keyring, nil
+}
+
+// LoadKeyringFromDir charge toutes les clés PEM (*.pem) d'un répertoire.
+// La clé privée de plus grand kid (ordre alphabétique) devient la clé active.
+func LoadKeyringFromDir(dir string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture de %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	keyring := NewKeyring()
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la lecture de la clé %s: %w", path, err)
+		}
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("clé %s invalide: %w", path, err)
+		}
+		keyring.Add(key, key.PrivateKey != nil)
+	}
+
+	if _, err := keyring.Active(); err != nil {
+		return nil, fmt.Errorf("aucune clé privée dans %s", dir)
+	}
+	return keyring, nil
+}
+
+// ParseSigningKeyPEM décode une clé RSA ou Ed25519 (privée PKCS#1/PKCS#8 ou publique PKIX)
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("aucun bloc PEM trouvé")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("type PEM %q non supporté", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PublicKey: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: key, PublicKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PublicKey: key}, nil
+	default:
+		return nil, fmt.Errorf("type de clé %T non supporté", parsed)
+	}
+}
+
+// GenerateSigningKey génère une nouvelle clé RS256 (RSA 2048) ou EdDSA (Ed25519)
+func GenerateSigningKey(kid, alg string) (*SigningKey, error) {
+	switch strings.ToUpper(alg) {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la génération de la clé RSA: %w", err)
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case "EDDSA":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la génération de la clé Ed25519: %w", err)
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: priv, PublicKey: pub}, nil
+	default:
+		return nil, fmt.Errorf("algorithme %q non supporté", alg)
+	}
+}
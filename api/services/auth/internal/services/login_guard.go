@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"api/services/auth/internal/database"
+)
+
+// LoginGuardConfig configure la protection contre le brute-force sur /login
+type LoginGuardConfig struct {
+	// MaxAccountFailures est le nombre d'échecs tolérés par compte avant verrouillage
+	MaxAccountFailures int64
+	// MaxIPFailures est le nombre d'échecs tolérés par adresse IP avant verrouillage
+	MaxIPFailures int64
+	// FailureWindow est la fenêtre glissante de comptage des échecs ; elle doit couvrir
+	// les verrouillages pour que le backoff continue de croître après leur expiration
+	FailureWindow time.Duration
+	// BaseLockout est la durée du premier verrouillage, doublée à chaque échec supplémentaire
+	BaseLockout time.Duration
+	// MaxLockout plafonne la durée de verrouillage
+	MaxLockout time.Duration
+}
+
+// LoginLockout décrit un verrouillage déclenché par un échec de connexion
+type LoginLockout struct {
+	// Scope vaut "account" ou "ip"
+	Scope    string
+	Failures int64
+	Duration time.Duration
+}
+
+// LoginGuard applique un backoff exponentiel et des verrouillages temporaires
+// par compte (email) et par adresse IP
+type LoginGuard struct {
+	attempts database.LoginAttemptRepository
+	config   LoginGuardConfig
+}
+
+// NewLoginGuard crée une nouvelle instance de LoginGuard
+func NewLoginGuard(attempts database.LoginAttemptRepository, config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		attempts: attempts,
+		config:   config,
+	}
+}
+
+// Check retourne la durée restante si le compte ou l'IP est verrouillé (0 sinon)
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	accountTTL, err := g.attempts.LockTTL(ctx, accountKey(email))
+	if err != nil {
+		return 0, err
+	}
+	ipTTL, err := g.attempts.LockTTL(ctx, ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipTTL > accountTTL {
+		return ipTTL, nil
+	}
+	return accountTTL, nil
+}
+
+// RecordFailure enregistre un échec de connexion et verrouille le compte et/ou l'IP
+// si le seuil est dépassé. Retourne les verrouillages déclenchés.
+func (g *LoginGuard) RecordFailure(ctx context.Context, email, ip string) ([]LoginLockout, error) {
+	var lockouts []LoginLockout
+
+	for _, target := range []struct {
+		scope     string
+		key       string
+		threshold int64
+	}{
+		{"account", accountKey(email), g.config.MaxAccountFailures},
+		{"ip", ipKey(ip), g.config.MaxIPFailures},
+	} {
+		failures, err := g.attempts.RegisterFailure(ctx, target.key, g.config.FailureWindow)
+		if err != nil {
+			return lockouts, err
+		}
+		if failures < target.threshold {
+			continue
+		}
+
+		duration := g.lockoutDuration(failures - target.threshold)
+		if err := g.attempts.Lock(ctx, target.key, duration); err != nil {
+			return lockouts, err
+		}
+		lockouts = append(lockouts, LoginLockout{Scope: target.scope, Failures: failures, Duration: duration})
+	}
+
+	return lockouts, nil
+}
+
+// RecordSuccess remet à zéro le compteur d'échecs du compte après une connexion réussie
+func (g *LoginGuard) RecordSuccess(ctx context.Context, email string) error {
+	return g.attempts.ResetFailures(ctx, accountKey(email))
+}
+
+// UnlockAccount lève le verrouillage d'un compte (action administrateur)
+func (g *LoginGuard) UnlockAccount(ctx context.Context, email string) error {
+	if err := g.attempts.Unlock(ctx, accountKey(email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+// lockoutDuration calcule BaseLockout * 2^excess, plafonné à MaxLockout
+func (g *LoginGuard) lockoutDuration(excess int64) time.Duration {
+	duration := g.config.BaseLockout
+	for i := int64(0); i < excess && duration < g.config.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > g.config.MaxLockout {
+		duration = g.config.MaxLockout
+	}
+	return duration
+}
+
+// accountKey normalise l'email pour que la casse ne contourne pas le compteur
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
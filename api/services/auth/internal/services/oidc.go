@@ -0,0 +1,123 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	model "api/services/auth/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scopes OpenID Connect supportés
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// SupportedOIDCScopes liste les scopes OpenID Connect annoncés dans le document de découverte
+var SupportedOIDCScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeOfflineAccess}
+
+// PKCEMethodS256 est la seule méthode PKCE acceptée ("plain" n'apporte aucune protection)
+const PKCEMethodS256 = "S256"
+
+// pkceVerifierPattern impose le format du code_verifier (RFC 7636 §4.1)
+var pkceVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// VerifyPKCE vérifie qu'un code_verifier correspond au code_challenge S256 reçu sur /authorize
+func VerifyPKCE(verifier, challenge, method string) bool {
+	if method != PKCEMethodS256 || !pkceVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// OIDCUserClaims construit les claims standards OpenID Connect d'un utilisateur
+// (ID token et /userinfo) selon les scopes accordés
+func OIDCUserClaims(user *model.User, scopes []string) map[string]any {
+	claims := map[string]any{
+		"sub": fmt.Sprintf("%d", user.ID),
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeProfile:
+			claims["name"] = strings.TrimSpace(user.Firstname + " " + user.Lastname)
+			claims["given_name"] = user.Firstname
+			claims["family_name"] = user.Lastname
+			claims["updated_at"] = user.UpdatedAt.Unix()
+			if user.OrganisationID != nil {
+				claims["org_id"] = *user.OrganisationID
+			}
+		case ScopeEmail:
+			claims["email"] = user.Email
+			claims["email_verified"] = user.IsEmailVerified()
+		}
+	}
+	return claims
+}
+
+// GenerateIDToken génère un ID token OpenID Connect signé avec la clé active du trousseau.
+// issuer est l'URL de l'émetteur OIDC et clientID l'audience du token.
+func (j *JWTService) GenerateIDToken(issuer, clientID, nonce string, authTime time.Time, userClaims map[string]any) (string, error) {
+	now := j.clock.Now()
+
+	claims := jwt.MapClaims{}
+	for name, value := range userClaims {
+		claims[name] = value
+	}
+	claims["iss"] = issuer
+	claims["aud"] = clientID
+	claims["azp"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(j.config.AccessTokenTTL).Unix()
+	claims["auth_time"] = authTime.Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	token, err := j.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("erreur lors de la génération de l'ID token: %w", err)
+	}
+	return token, nil
+}
+
+// OIDCDiscovery construit le document /.well-known/openid-configuration
+func (j *JWTService) OIDCDiscovery(issuer string) map[string]any {
+	return map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"response_modes_supported":              []string{"query"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{j.SigningAlgorithm()},
+		"scopes_supported":                      SupportedOIDCScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{PKCEMethodS256},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "azp",
+			"name", "given_name", "family_name", "updated_at", "email", "email_verified", "org_id",
+		},
+	}
+}
+
+// VerifyClientSecret compare en temps constant un client_secret au hash enregistré
+func VerifyClientSecret(secret, secretHash string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashOpaqueToken(secret)), []byte(secretHash)) == 1
+}
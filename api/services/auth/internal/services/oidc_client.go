@@ -0,0 +1,604 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	model "api/services/auth/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Fournisseurs d'identité préconfigurés : seuls les identifiants client restent à renseigner
+var wellKnownIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+	// Endpoint multi-tenant : l'issuer annoncé contient "{tenantid}", remplacé par le claim "tid"
+	"microsoft": "https://login.microsoftonline.com/common/v2.0",
+}
+
+// ErrExternalIdentityInvalid indique une réponse du fournisseur d'identité refusée
+// (ID token invalide, nonce ou audience inattendus, ...)
+var ErrExternalIdentityInvalid = errors.New("invalid external identity")
+
+// providerNamePattern limite les noms de fournisseurs à ce qui peut figurer dans une URL et une variable d'environnement
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ExternalProviderConfig configure un fournisseur d'identité OpenID Connect externe (Google, Microsoft, IdP de test, ...)
+type ExternalProviderConfig struct {
+	// Name identifie le fournisseur dans les URLs (/sso/{name}/login) et dans auth.user_identities
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RedirectURL est l'URL de callback enregistrée auprès du fournisseur
+	RedirectURL string
+	// TrustEmail considère l'email comme vérifié en l'absence du claim email_verified
+	// (Microsoft Entra ID ne le fournit pas ; à réserver aux tenants maîtrisés)
+	TrustEmail bool
+	// AllowedDomains restreint les domaines email acceptés (vide : tous)
+	AllowedDomains []string
+	// OrganisationID est l'organisation des comptes créés à la volée (0 : nouvelle agence par compte)
+	OrganisationID uint
+}
+
+// ExternalIdentity est l'identité vérifiée retournée par un fournisseur externe
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+}
+
+// ExternalProvider est un client OpenID Connect (relying party) : flux authorization code
+// avec PKCE, vérification de l'ID token avec les clés publiées par le fournisseur (JWKS).
+// Les documents de découverte et JWKS sont chargés à la demande puis mis en cache.
+type ExternalProvider struct {
+	config ExternalProviderConfig
+	client *http.Client
+	clock  Clock
+
+	mu          sync.Mutex
+	discovery   *providerDiscovery
+	discoveryAt time.Time
+	keys        map[string]crypto.PublicKey
+	keysAt      time.Time
+}
+
+// providerDiscovery est le sous-ensemble utile de /.well-known/openid-configuration
+type providerDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+const (
+	// discoveryCacheTTL est la durée de cache des documents de découverte et JWKS
+	discoveryCacheTTL = time.Hour
+	// jwksMinRefresh évite de recharger le JWKS en boucle sur un kid inconnu
+	jwksMinRefresh = time.Minute
+	// idTokenLeeway tolère un léger décalage d'horloge avec le fournisseur
+	idTokenLeeway = time.Minute
+	// maxProviderResponse borne la taille des réponses du fournisseur
+	maxProviderResponse = 1 << 20
+)
+
+// NewExternalProvider crée un client pour un fournisseur d'identité ; client peut être nil (timeout de 10s)
+func NewExternalProvider(config ExternalProviderConfig, client *http.Client) (*ExternalProvider, error) {
+	if !providerNamePattern.MatchString(config.Name) {
+		return nil, fmt.Errorf("nom de fournisseur d'identité invalide %q", config.Name)
+	}
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("fournisseur d'identité %s : issuer, client_id et redirect_url sont obligatoires", config.Name)
+	}
+	if _, err := url.ParseRequestURI(config.Issuer); err != nil {
+		return nil, fmt.Errorf("fournisseur d'identité %s : issuer invalide: %w", config.Name, err)
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{ScopeOpenID, ScopeEmail, ScopeProfile}
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ExternalProvider{config: config, client: client, clock: systemClock{}}, nil
+}
+
+// SetClock remplace l'horloge utilisée pour valider les ID tokens
+func (p *ExternalProvider) SetClock(clock Clock) {
+	p.clock = clock
+}
+
+// Config retourne la configuration du fournisseur
+func (p *ExternalProvider) Config() ExternalProviderConfig {
+	return p.config
+}
+
+// AuthCodeURL construit l'URL d'autorisation vers laquelle rediriger le navigateur
+func (p *ExternalProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", PKCEMethodS256)
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange échange le code d'autorisation contre des tokens et retourne l'identité
+// extraite de l'ID token après vérification (signature, issuer, audience, expiration, nonce)
+func (p *ExternalProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error) {
+	discovery, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("échange du code auprès de %s: %w", p.config.Name, err)
+	}
+	if status != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("échange du code refusé par %s (HTTP %d): %s %s: %w",
+			p.config.Name, status, tokens.Error, tokens.ErrorDescription, ErrExternalIdentityInvalid)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("réponse de %s sans id_token: %w", p.config.Name, ErrExternalIdentityInvalid)
+	}
+
+	return p.verifyIDToken(ctx, discovery, tokens.IDToken, nonce)
+}
+
+// verifyIDToken valide un ID token et en extrait l'identité
+func (p *ExternalProvider) verifyIDToken(ctx context.Context, discovery *providerDiscovery, rawToken, nonce string) (*ExternalIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.publicKey(ctx, discovery, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(idTokenLeeway),
+		jwt.WithTimeFunc(p.clock.Now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID token de %s: %v: %w", p.config.Name, err, ErrExternalIdentityInvalid)
+	}
+
+	issuer, _ := claims["iss"].(string)
+	expectedIssuer := discovery.Issuer
+	if strings.Contains(expectedIssuer, "{tenantid}") {
+		tenantID, _ := claims["tid"].(string)
+		expectedIssuer = strings.ReplaceAll(expectedIssuer, "{tenantid}", tenantID)
+	}
+	if issuer == "" || issuer != expectedIssuer {
+		return nil, fmt.Errorf("ID token de %s: issuer %q inattendu: %w", p.config.Name, issuer, ErrExternalIdentityInvalid)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("ID token de %s: nonce invalide: %w", p.config.Name, ErrExternalIdentityInvalid)
+	}
+	// Plusieurs audiences : le token doit avoir été émis pour ce client (OIDC Core §3.1.3.7)
+	if audiences, _ := claims.GetAudience(); len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("ID token de %s: azp %q inattendu: %w", p.config.Name, azp, ErrExternalIdentityInvalid)
+		}
+	}
+
+	identity := &ExternalIdentity{Provider: p.config.Name}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("ID token de %s sans sub: %w", p.config.Name, ErrExternalIdentityInvalid)
+	}
+	identity.Email = strings.ToLower(strings.TrimSpace(stringClaim(claims, "email")))
+	if identity.Email == "" {
+		// Comptes professionnels Microsoft sans boîte mail : l'UPN fait office d'email
+		identity.Email = strings.ToLower(strings.TrimSpace(stringClaim(claims, "preferred_username")))
+		if !strings.Contains(identity.Email, "@") {
+			identity.Email = ""
+		}
+	}
+	identity.Name = stringClaim(claims, "name")
+	identity.GivenName = stringClaim(claims, "given_name")
+	identity.FamilyName = stringClaim(claims, "family_name")
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified, _ = strconv.ParseBool(verified)
+	default:
+		identity.EmailVerified = p.config.TrustEmail
+	}
+	if identity.Email == "" {
+		identity.EmailVerified = false
+	}
+	return identity, nil
+}
+
+// ExternalAccountAction est la suite donnée à une identité externe qui n'est liée à aucun compte
+type ExternalAccountAction int
+
+const (
+	// ExternalAccountLink : lier l'identité au compte local de même email
+	ExternalAccountLink ExternalAccountAction = iota
+	// ExternalAccountProvision : créer le compte à la volée
+	ExternalAccountProvision
+	// ExternalAccountRejectUnverifiedIdentity : le fournisseur ne garantit pas l'email
+	ExternalAccountRejectUnverifiedIdentity
+	// ExternalAccountRejectUnverifiedAccount : le compte local de même email n'a jamais été vérifié
+	ExternalAccountRejectUnverifiedAccount
+)
+
+// ResolveExternalAccount décide du sort d'une identité externe non liée ; existing est le compte
+// local de même email (nil s'il n'existe pas). Un compte dont l'email n'a jamais été vérifié n'est
+// pas lié : il peut avoir été créé par un tiers avec l'adresse de la victime (pré-détournement de
+// compte), qui garderait l'accès par mot de passe.
+func ResolveExternalAccount(identity *ExternalIdentity, existing *model.User) ExternalAccountAction {
+	switch {
+	case !identity.EmailVerified:
+		return ExternalAccountRejectUnverifiedIdentity
+	case existing == nil:
+		return ExternalAccountProvision
+	case !existing.IsEmailVerified():
+		return ExternalAccountRejectUnverifiedAccount
+	default:
+		return ExternalAccountLink
+	}
+}
+
+// AllowsEmail vérifie que le domaine de l'email est autorisé pour ce fournisseur
+func (p *ExternalProvider) AllowsEmail(email string) bool {
+	if len(p.config.AllowedDomains) == 0 {
+		return true
+	}
+	_, domain, found := strings.Cut(strings.ToLower(email), "@")
+	if !found {
+		return false
+	}
+	for _, allowed := range p.config.AllowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDiscovery charge (ou retourne depuis le cache) le document de découverte du fournisseur
+func (p *ExternalProvider) loadDiscovery(ctx context.Context) (*providerDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && p.clock.Now().Sub(p.discoveryAt) < discoveryCacheTTL {
+		return p.discovery, nil
+	}
+
+	discoveryURL := strings.TrimRight(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var discovery providerDiscovery
+	status, err := p.doJSON(req, &discovery)
+	if err != nil {
+		return nil, fmt.Errorf("découverte OIDC de %s: %w", p.config.Name, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("découverte OIDC de %s: HTTP %d", p.config.Name, status)
+	}
+	if discovery.Issuer == "" || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("découverte OIDC de %s: document incomplet", p.config.Name)
+	}
+
+	p.discovery = &discovery
+	p.discoveryAt = p.clock.Now()
+	return p.discovery, nil
+}
+
+// publicKey retourne la clé de vérification d'un kid, en rechargeant le JWKS si le kid est
+// inconnu (rotation des clés chez le fournisseur) ou si le cache a expiré
+func (p *ExternalProvider) publicKey(ctx context.Context, discovery *providerDiscovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	key, found := p.lookupKey(kid)
+	expired := now.Sub(p.keysAt) >= discoveryCacheTTL
+	if found && !expired {
+		return key, nil
+	}
+	if !found && !expired && now.Sub(p.keysAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("clé %q inconnue", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set JWKSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("chargement du JWKS de %s: %w", p.config.Name, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("chargement du JWKS de %s: HTTP %d", p.config.Name, status)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if parsed, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = parsed
+		}
+	}
+	p.keys = keys
+	p.keysAt = now
+
+	if key, found := p.lookupKey(kid); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("clé %q inconnue", kid)
+}
+
+// lookupKey cherche une clé par kid ; sans kid, la clé n'est retenue que si elle est unique
+func (p *ExternalProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid != "" {
+		key, found := p.keys[kid]
+		return key, found
+	}
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// doJSON exécute une requête vers le fournisseur et décode la réponse JSON
+func (p *ExternalProvider) doJSON(req *http.Request, target any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProviderResponse))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return resp.StatusCode, fmt.Errorf("réponse JSON invalide (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// parseJWK convertit une clé publique JWK (RSA, EC, Ed25519) en clé Go
+func parseJWK(jwk JWK) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("exposant RSA invalide")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("courbe %q non supportée", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("point EC invalide")
+		}
+		return key, nil
+	case "OKP":
+		x, err := decode(jwk.X)
+		if err != nil || jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("clé OKP invalide")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("type de clé %q non supporté", jwk.Kty)
+	}
+}
+
+// stringClaim retourne un claim de type chaîne (vide s'il est absent)
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// GeneratePKCE génère un code_verifier et son code_challenge S256 (RFC 7636)
+func GeneratePKCE() (verifier string, challenge string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", fmt.Errorf("erreur lors de la génération du code_verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(bytes)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// ExternalProviders regroupe les fournisseurs d'identité configurés, dans l'ordre de configuration
+type ExternalProviders struct {
+	providers map[string]*ExternalProvider
+	order     []string
+}
+
+// NewExternalProviders crée les clients des fournisseurs configurés
+func NewExternalProviders(configs []ExternalProviderConfig, client *http.Client) (*ExternalProviders, error) {
+	registry := &ExternalProviders{providers: make(map[string]*ExternalProvider)}
+	for _, config := range configs {
+		if _, exists := registry.providers[config.Name]; exists {
+			return nil, fmt.Errorf("fournisseur d'identité %s configuré deux fois", config.Name)
+		}
+		provider, err := NewExternalProvider(config, client)
+		if err != nil {
+			return nil, err
+		}
+		registry.providers[config.Name] = provider
+		registry.order = append(registry.order, config.Name)
+	}
+	return registry, nil
+}
+
+// Get retourne un fournisseur par son nom
+func (r *ExternalProviders) Get(name string) (*ExternalProvider, bool) {
+	provider, found := r.providers[name]
+	return provider, found
+}
+
+// List retourne les fournisseurs dans l'ordre de configuration
+func (r *ExternalProviders) List() []*ExternalProvider {
+	providers := make([]*ExternalProvider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
+
+// LoadExternalProvidersFromEnv lit la configuration des fournisseurs d'identité externes :
+//   - SSO_PROVIDERS : noms des fournisseurs séparés par des virgules (ex. "google,microsoft")
+//   - SSO_<NOM>_ISSUER : URL de l'issuer (défaut pour google et microsoft), par exemple
+//     l'URL d'un IdP de test local (mock-oauth2-server, Keycloak, ...)
+//   - SSO_<NOM>_CLIENT_ID, SSO_<NOM>_CLIENT_SECRET (vide : client public, PKCE seul)
+//   - SSO_<NOM>_SCOPES (défaut "openid email profile"), SSO_<NOM>_DISPLAY_NAME
+//   - SSO_<NOM>_REDIRECT_URL (défaut : callbackBaseURL + "/sso/<nom>/callback")
+//   - SSO_<NOM>_TRUST_EMAIL, SSO_<NOM>_ALLOWED_DOMAINS, SSO_<NOM>_ORGANISATION_ID
+func LoadExternalProvidersFromEnv(callbackBaseURL string) ([]ExternalProviderConfig, error) {
+	var configs []ExternalProviderConfig
+	for _, name := range strings.Split(os.Getenv("SSO_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("SSO_PROVIDERS: nom de fournisseur invalide %q", name)
+		}
+		prefix := "SSO_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		env := func(key, defaultValue string) string {
+			if value := strings.TrimSpace(os.Getenv(prefix + key)); value != "" {
+				return value
+			}
+			return defaultValue
+		}
+
+		config := ExternalProviderConfig{
+			Name:         name,
+			DisplayName:  env("DISPLAY_NAME", strings.ToUpper(name[:1])+name[1:]),
+			Issuer:       env("ISSUER", wellKnownIssuers[name]),
+			ClientID:     env("CLIENT_ID", ""),
+			ClientSecret: env("CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(env("SCOPES", "openid email profile")),
+			RedirectURL:  env("REDIRECT_URL", strings.TrimRight(callbackBaseURL, "/")+"/sso/"+name+"/callback"),
+		}
+		if value := env("TRUST_EMAIL", ""); value != "" {
+			trust, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%sTRUST_EMAIL invalide: %w", prefix, err)
+			}
+			config.TrustEmail = trust
+		}
+		for _, domain := range strings.Split(env("ALLOWED_DOMAINS", ""), ",") {
+			if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+				config.AllowedDomains = append(config.AllowedDomains, domain)
+			}
+		}
+		if value := env("ORGANISATION_ID", ""); value != "" {
+			orgID, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%sORGANISATION_ID invalide: %w", prefix, err)
+			}
+			config.OrganisationID = uint(orgID)
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("fournisseur d'identité %s : %sISSUER et %sCLIENT_ID sont obligatoires", name, prefix, prefix)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	model "api/services/auth/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIdPClientID = "immogestion"
+	testIdPKid      = "idp-key"
+	testIdPCode     = "valid-code"
+	testIdPNonce    = "expected-nonce"
+)
+
+// testIdP est un fournisseur d'identité minimal : découverte, JWKS et token endpoint.
+// Le token endpoint signe les claims idToken avec signer (la clé publiée par défaut).
+type testIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	signer  *rsa.PrivateKey
+	idToken jwt.MapClaims
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	idp := &testIdP{key: generateRSAKey(t)}
+	idp.signer = idp.key
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, providerDiscovery{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, JWKSet{Keys: []JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: testIdPKid,
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != testIdPCode || r.PostFormValue("code_verifier") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.idToken)
+		token.Header["kid"] = testIdPKid
+		signed, err := token.SignedString(idp.signer)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// validIDToken retourne les claims d'un ID token valide émis à l'instant now
+func (idp *testIdP) validIDToken(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testIdPClientID,
+		"sub":            "external-42",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          testIdPNonce,
+		"email":          "Jane.Doe@Example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+	}
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	return key
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestExternalProviderExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	otherKey := generateRSAKey(t)
+	clock := NewFakeClock(time.Now())
+
+	tests := []struct {
+		name       string
+		mutate     func(jwt.MapClaims)
+		forge      bool
+		trustEmail bool
+		code       string
+		wantErr    bool
+		wantEmail  string
+		wantVerify bool
+	}{
+		{name: "valid", mutate: func(jwt.MapClaims) {}, wantEmail: "jane.doe@example.com", wantVerify: true},
+		{name: "bad signature", mutate: func(jwt.MapClaims) {}, forge: true, wantErr: true},
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "another-client" }, wantErr: true},
+		{
+			name:    "multiple audiences without azp",
+			mutate:  func(c jwt.MapClaims) { c["aud"] = []string{testIdPClientID, "another-client"} },
+			wantErr: true,
+		},
+		{
+			name: "multiple audiences with foreign azp",
+			mutate: func(c jwt.MapClaims) {
+				c["aud"] = []string{testIdPClientID, "another-client"}
+				c["azp"] = "another-client"
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple audiences with our azp",
+			mutate: func(c jwt.MapClaims) {
+				c["aud"] = []string{testIdPClientID, "another-client"}
+				c["azp"] = testIdPClientID
+			},
+			wantEmail:  "jane.doe@example.com",
+			wantVerify: true,
+		},
+		{name: "wrong nonce", mutate: func(c jwt.MapClaims) { c["nonce"] = "replayed-nonce" }, wantErr: true},
+		{name: "missing nonce", mutate: func(c jwt.MapClaims) { delete(c, "nonce") }, wantErr: true},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = clock.Now().Add(-2 * idTokenLeeway).Unix() }, wantErr: true},
+		{name: "missing sub", mutate: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+		{name: "rejected code", mutate: func(jwt.MapClaims) {}, code: "stolen-code", wantErr: true},
+		{
+			name:      "email not verified",
+			mutate:    func(c jwt.MapClaims) { c["email_verified"] = false },
+			wantEmail: "jane.doe@example.com",
+		},
+		{
+			name:       "email_verified as string",
+			mutate:     func(c jwt.MapClaims) { c["email_verified"] = "true" },
+			wantEmail:  "jane.doe@example.com",
+			wantVerify: true,
+		},
+		{
+			name:      "email_verified missing",
+			mutate:    func(c jwt.MapClaims) { delete(c, "email_verified") },
+			wantEmail: "jane.doe@example.com",
+		},
+		{
+			name:       "email_verified missing with trusted emails",
+			mutate:     func(c jwt.MapClaims) { delete(c, "email_verified") },
+			trustEmail: true,
+			wantEmail:  "jane.doe@example.com",
+			wantVerify: true,
+		},
+		{
+			name: "no email is never verified",
+			mutate: func(c jwt.MapClaims) {
+				delete(c, "email")
+				c["preferred_username"] = "not-an-email"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewExternalProvider(ExternalProviderConfig{
+				Name:        "test",
+				Issuer:      idp.server.URL,
+				ClientID:    testIdPClientID,
+				RedirectURL: "http://localhost/sso/test/callback",
+				TrustEmail:  tt.trustEmail,
+			}, idp.server.Client())
+			if err != nil {
+				t.Fatalf("NewExternalProvider: %v", err)
+			}
+			provider.SetClock(clock)
+
+			idp.idToken = idp.validIDToken(clock.Now())
+			tt.mutate(idp.idToken)
+			idp.signer = idp.key
+			if tt.forge {
+				idp.signer = otherKey
+			}
+			code := tt.code
+			if code == "" {
+				code = testIdPCode
+			}
+
+			identity, err := provider.Exchange(context.Background(), code, "verifier", testIdPNonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrExternalIdentityInvalid) {
+					t.Fatalf("Exchange() error = %v, want %v", err, ErrExternalIdentityInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if identity.Subject != "external-42" || identity.Provider != "test" {
+				t.Fatalf("identity = %+v, want subject external-42 from provider test", identity)
+			}
+			if identity.Email != tt.wantEmail || identity.EmailVerified != tt.wantVerify {
+				t.Fatalf("identity email = %q (verified=%v), want %q (verified=%v)",
+					identity.Email, identity.EmailVerified, tt.wantEmail, tt.wantVerify)
+			}
+		})
+	}
+}
+
+func TestResolveExternalAccount(t *testing.T) {
+	verifiedAt := time.Now()
+	verified := &model.User{Email: "jane.doe@example.com", EmailVerifiedAt: &verifiedAt}
+	unverified := &model.User{Email: "jane.doe@example.com"}
+
+	tests := []struct {
+		name     string
+		identity *ExternalIdentity
+		existing *model.User
+		want     ExternalAccountAction
+	}{
+		{name: "unknown email is provisioned", identity: &ExternalIdentity{EmailVerified: true}, want: ExternalAccountProvision},
+		{name: "verified account is linked", identity: &ExternalIdentity{EmailVerified: true}, existing: verified, want: ExternalAccountLink},
+		{
+			name:     "unverified account is not linked",
+			identity: &ExternalIdentity{EmailVerified: true},
+			existing: unverified,
+			want:     ExternalAccountRejectUnverifiedAccount,
+		},
+		{name: "unverified identity is never provisioned", identity: &ExternalIdentity{}, want: ExternalAccountRejectUnverifiedIdentity},
+		{name: "unverified identity is never linked", identity: &ExternalIdentity{}, existing: verified, want: ExternalAccountRejectUnverifiedIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveExternalAccount(tt.identity, tt.existing); got != tt.want {
+				t.Fatalf("ResolveExternalAccount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
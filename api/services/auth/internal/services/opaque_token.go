@@ -0,0 +1,25 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateOpaqueToken génère un token aléatoire à usage unique (reset de mot de passe, vérification, ...).
+// Le token en clair est envoyé à l'utilisateur ; seul son hash est stocké côté serveur.
+func GenerateOpaqueToken() (token string, tokenHash string, err error) {
+	bytes := make([]byte, 32) // 256 bits
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", fmt.Errorf("erreur lors de la génération du token: %w", err)
+	}
+	token = hex.EncodeToString(bytes)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken retourne le hash SHA-256 (hex) d'un token opaque
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithmes de hachage des mots de passe
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	// ErrPasswordMismatch indique que le mot de passe ne correspond pas au hash
+	ErrPasswordMismatch = errors.New("password does not match")
+	// ErrUnknownPasswordHash indique un hash dont l'algorithme n'est pas reconnu
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hache et vérifie les mots de passe
+type PasswordHasher interface {
+	// Hash retourne le hash encodé (avec algorithme et paramètres) d'un mot de passe
+	Hash(password string) (string, error)
+	// Verify retourne ErrPasswordMismatch si le mot de passe ne correspond pas au hash
+	Verify(password, encodedHash string) error
+	// NeedsRehash indique qu'un hash a été produit avec un algorithme ou des paramètres obsolètes
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2idParams configure Argon2id (recommandations OWASP par défaut)
+type Argon2idParams struct {
+	// Memory est la mémoire utilisée, en KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams retourne les paramètres Argon2id par défaut
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// PasswordHasherConfig configure le hachage des mots de passe
+type PasswordHasherConfig struct {
+	// Algorithm est l'algorithme des nouveaux hashs (argon2id ou bcrypt)
+	Algorithm  string
+	Argon2id   Argon2idParams
+	BcryptCost int
+}
+
+// DefaultPasswordHasherConfig retourne la configuration par défaut (Argon2id)
+func DefaultPasswordHasherConfig() PasswordHasherConfig {
+	return PasswordHasherConfig{
+		Algorithm:  PasswordAlgorithmArgon2id,
+		Argon2id:   DefaultArgon2idParams(),
+		BcryptCost: 12,
+	}
+}
+
+// NewPasswordHasher crée un hasher qui produit des hashs avec l'algorithme configuré
+// et vérifie les hashs Argon2id comme bcrypt (détectés par leur préfixe). Les hashs
+// d'un autre algorithme ou aux paramètres plus faibles sont signalés par NeedsRehash.
+func NewPasswordHasher(config PasswordHasherConfig) (PasswordHasher, error) {
+	if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d (expected %d-%d)", config.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	p := config.Argon2id
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength < 8 || p.KeyLength < 16 {
+		return nil, fmt.Errorf("invalid argon2id parameters: %+v", p)
+	}
+
+	argon := &argon2idHasher{params: p}
+	bcryptHasher := &bcryptHasher{cost: config.BcryptCost}
+
+	var preferred PasswordHasher
+	switch strings.ToLower(config.Algorithm) {
+	case PasswordAlgorithmArgon2id:
+		preferred = argon
+	case PasswordAlgorithmBcrypt:
+		preferred = bcryptHasher
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q (expected argon2id or bcrypt)", config.Algorithm)
+	}
+
+	return &passwordHasher{preferred: preferred, argon2id: argon, bcrypt: bcryptHasher}, nil
+}
+
+// passwordHasher délègue à l'algorithme détecté dans le hash
+type passwordHasher struct {
+	preferred PasswordHasher
+	argon2id  *argon2idHasher
+	bcrypt    *bcryptHasher
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *passwordHasher) Verify(password, encodedHash string) error {
+	hasher, err := h.detect(encodedHash)
+	if err != nil {
+		return err
+	}
+	return hasher.Verify(password, encodedHash)
+}
+
+func (h *passwordHasher) NeedsRehash(encodedHash string) bool {
+	hasher, err := h.detect(encodedHash)
+	if err != nil || hasher != h.preferred {
+		return true
+	}
+	return hasher.NeedsRehash(encodedHash)
+}
+
+func (h *passwordHasher) detect(encodedHash string) (PasswordHasher, error) {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return h.argon2id, nil
+	// $2a$ : bcrypt de Go et pgcrypto (gen_salt('bf')), $2b$/$2y$ : autres implémentations
+	case strings.HasPrefix(encodedHash, "$2a$"), strings.HasPrefix(encodedHash, "$2b$"), strings.HasPrefix(encodedHash, "$2y$"):
+		return h.bcrypt, nil
+	default:
+		return nil, ErrUnknownPasswordHash
+	}
+}
+
+// bcryptHasher hache avec bcrypt
+type bcryptHasher struct {
+	cost int
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("erreur lors du hachage bcrypt: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Verify(password, encodedHash string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+func (h *bcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost < h.cost
+}
+
+// argon2idHasher hache avec Argon2id au format PHC :
+// $argon2id$v=19$m=65536,t=3,p=2$<sel base64>$<hash base64>
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("erreur lors de la génération du sel: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(password, encodedHash string) error {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (h *argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism < h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLength ||
+		uint32(len(key)) < h.params.KeyLength
+}
+
+// decodeArgon2id extrait les paramètres, le sel et la clé d'un hash Argon2id encodé
+func decodeArgon2id(encodedHash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q: %w", parts[2], ErrUnknownPasswordHash)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", ErrUnknownPasswordHash)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", ErrUnknownPasswordHash)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", ErrUnknownPasswordHash)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
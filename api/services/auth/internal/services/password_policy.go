@@ -0,0 +1,288 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes des règles de la politique de mot de passe
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingLower     = "missing_lowercase"
+	PasswordMissingUpper     = "missing_uppercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordContainsPersonal = "contains_personal_info"
+	PasswordBreached         = "breached"
+)
+
+// PasswordPolicyConfig configure la politique de mot de passe
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// ForbidPersonalInfo interdit que le mot de passe contienne l'email ou le nom
+	ForbidPersonalInfo bool
+}
+
+// DefaultPasswordPolicyConfig retourne la politique par défaut
+func DefaultPasswordPolicyConfig() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:          10,
+		MaxLength:          128,
+		RequireLower:       true,
+		RequireUpper:       true,
+		RequireDigit:       true,
+		RequireSymbol:      false,
+		ForbidPersonalInfo: true,
+	}
+}
+
+// PasswordOwner regroupe les informations personnelles interdites dans le mot de passe
+type PasswordOwner struct {
+	Email     string
+	Firstname string
+	Lastname  string
+}
+
+// PasswordViolation décrit une règle non respectée
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordCheck est le résultat de l'évaluation d'un mot de passe
+type PasswordCheck struct {
+	Valid      bool                `json:"valid"`
+	Score      int                 `json:"score"` // 0 (très faible) à 4 (fort)
+	Violations []PasswordViolation `json:"violations"`
+}
+
+// BreachedPasswordChecker indique si un mot de passe figure dans une liste de fuites connues
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// PasswordPolicy évalue les mots de passe (longueur, classes de caractères,
+// informations personnelles, fuites connues)
+type PasswordPolicy struct {
+	config   PasswordPolicyConfig
+	breached BreachedPasswordChecker
+}
+
+// NewPasswordPolicy crée une politique de mot de passe ; breached peut être nil (pas de vérification des fuites)
+func NewPasswordPolicy(config PasswordPolicyConfig, breached BreachedPasswordChecker) (*PasswordPolicy, error) {
+	if config.MinLength < 1 || (config.MaxLength > 0 && config.MaxLength < config.MinLength) {
+		return nil, fmt.Errorf("invalid password length bounds: min=%d max=%d", config.MinLength, config.MaxLength)
+	}
+	return &PasswordPolicy{config: config, breached: breached}, nil
+}
+
+// Config retourne la configuration de la politique (exposée au formulaire d'inscription)
+func (p *PasswordPolicy) Config() PasswordPolicyConfig {
+	return p.config
+}
+
+// Check évalue un mot de passe. Une erreur n'est retournée que si la liste des fuites
+// est illisible ; les autres règles sont alors tout de même évaluées.
+func (p *PasswordPolicy) Check(password string, owner PasswordOwner) (PasswordCheck, error) {
+	check := PasswordCheck{Violations: []PasswordViolation{}}
+	add := func(code, message string) {
+		check.Violations = append(check.Violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		add(PasswordTooShort, fmt.Sprintf("Le mot de passe doit contenir au moins %d caractères", p.config.MinLength))
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		add(PasswordTooLong, fmt.Sprintf("Le mot de passe doit contenir au plus %d caractères", p.config.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.config.RequireLower && !lower {
+		add(PasswordMissingLower, "Le mot de passe doit contenir une lettre minuscule")
+	}
+	if p.config.RequireUpper && !upper {
+		add(PasswordMissingUpper, "Le mot de passe doit contenir une lettre majuscule")
+	}
+	if p.config.RequireDigit && !digit {
+		add(PasswordMissingDigit, "Le mot de passe doit contenir un chiffre")
+	}
+	if p.config.RequireSymbol && !symbol {
+		add(PasswordMissingSymbol, "Le mot de passe doit contenir un caractère spécial")
+	}
+
+	if p.config.ForbidPersonalInfo && containsPersonalInfo(password, owner) {
+		add(PasswordContainsPersonal, "Le mot de passe ne doit contenir ni votre email ni votre nom")
+	}
+
+	var breachErr error
+	if p.breached != nil && password != "" {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			breachErr = fmt.Errorf("breached password check failed: %w", err)
+		} else if breached {
+			add(PasswordBreached, "Ce mot de passe apparaît dans des fuites de données connues, choisissez-en un autre")
+		}
+	}
+
+	check.Valid = len(check.Violations) == 0
+	check.Score = passwordScore(length, lower, upper, digit, symbol, check.Valid)
+	return check, breachErr
+}
+
+// containsPersonalInfo vérifie (sans tenir compte de la casse) si le mot de passe contient
+// l'email, sa partie locale, le prénom ou le nom (fragments d'au moins 3 caractères)
+func containsPersonalInfo(password string, owner PasswordOwner) bool {
+	lowered := strings.ToLower(password)
+	email := strings.ToLower(strings.TrimSpace(owner.Email))
+	candidates := []string{email, owner.Firstname, owner.Lastname}
+	if local, _, found := strings.Cut(email, "@"); found {
+		candidates = append(candidates, local)
+	}
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if utf8.RuneCountInString(candidate) >= 3 && strings.Contains(lowered, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordScore estime la robustesse d'un mot de passe de 0 à 4
+func passwordScore(length int, lower, upper, digit, symbol, valid bool) int {
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	score := 0
+	switch {
+	case length >= 16:
+		score = 3
+	case length >= 12:
+		score = 2
+	case length >= 8:
+		score = 1
+	}
+	if classes >= 3 {
+		score++
+	}
+	if score > 4 {
+		score = 4
+	}
+	// Un mot de passe refusé par la politique n'est jamais considéré comme fort
+	if !valid && score > 1 {
+		score = 1
+	}
+	return score
+}
+
+// BreachedPasswordList vérifie les mots de passe contre une liste hors ligne de hashs SHA-1
+// au format k-anonymity de Have I Been Pwned.
+//
+// Deux formats sont acceptés :
+//   - un fichier dont chaque ligne est "SHA1[:COUNT]" (chargé en mémoire, indexé par préfixe) ;
+//   - un répertoire de plages "<PREFIXE>.txt" (5 caractères hexadécimaux) contenant des lignes
+//     "SUFFIXE:COUNT", tel que produit par l'outil de téléchargement HIBP (lu à la demande).
+type BreachedPasswordList struct {
+	dir    string
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswordList charge une liste de mots de passe compromis depuis un fichier
+// ou un répertoire de plages
+func LoadBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedPasswordList{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachedPasswordList{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" || strings.HasPrefix(hash, "#") {
+			continue
+		}
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breached password list %s:%d: invalid SHA-1 hash", path, line)
+		}
+		prefix, suffix := hash[:5], hash[5:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = make(map[string]struct{})
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	return list, nil
+}
+
+// IsBreached implémente BreachedPasswordChecker
+func (l *BreachedPasswordList) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	if l.dir == "" {
+		_, found := l.ranges[prefix][suffix]
+		return found, nil
+	}
+
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
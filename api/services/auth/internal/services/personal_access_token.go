@@ -0,0 +1,44 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix préfixe les tokens d'API : ils se distinguent ainsi des JWT
+// (et sont repérables par les scanners de secrets)
+const PersonalAccessTokenPrefix = "igp_"
+
+// GeneratePersonalAccessToken génère un token d'API. Le token en clair n'est montré qu'une fois
+// à l'utilisateur ; seuls son hash et son préfixe d'affichage sont stockés.
+func GeneratePersonalAccessToken() (token, tokenHash, displayPrefix string, err error) {
+	bytes := make([]byte, 32) // 256 bits
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", "", fmt.Errorf("erreur lors de la génération du token d'API: %w", err)
+	}
+	token = PersonalAccessTokenPrefix + hex.EncodeToString(bytes)
+	return token, HashOpaqueToken(token), token[:len(PersonalAccessTokenPrefix)+8], nil
+}
+
+// IsPersonalAccessToken indique si un bearer token est un token d'API (et non un JWT)
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// EffectiveScopes retourne les scopes d'un token d'API encore accordés à l'utilisateur :
+// retirer une permission à l'utilisateur la retire aussi à ses tokens
+func EffectiveScopes(tokenScopes, userPermissions []string) []string {
+	granted := make(map[string]struct{}, len(userPermissions))
+	for _, permission := range userPermissions {
+		granted[permission] = struct{}{}
+	}
+	scopes := make([]string, 0, len(tokenScopes))
+	for _, scope := range tokenScopes {
+		if _, ok := granted[scope]; ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
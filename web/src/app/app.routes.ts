@@ -16,6 +16,12 @@ export const routes: Routes = [
         loadChildren: () => import('./features/public/auth/auth.routes')
                            .then(m => m.AUTH_ROUTES),
       },
+      // Consentement OpenID Connect : cible de la redirection de GET /authorize
+      {
+        path: 'oauth/authorize',
+        loadComponent: () => import('./features/public/auth/components/oauth-consent/oauth-consent')
+                           .then(m => m.OAuthConsent),
+      },
     ]
   },
 
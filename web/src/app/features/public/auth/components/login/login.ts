@@ -78,8 +78,8 @@ export class Login implements OnInit, OnDestroy {
     
     // Vérifier si l'utilisateur est déjà connecté
     if (this.isAuthenticated()) {
-      this.logger.info('User already authenticated, redirecting to dashboard');
-      this.router.navigate(['/dashboard']);
+      this.logger.info('User already authenticated, redirecting');
+      this.router.navigateByUrl(this.determineRedirectUrl(null));
       return;
     }
 
@@ -263,8 +263,8 @@ export class Login implements OnInit, OnDestroy {
     // Reset du formulaire
     this.loginForm.reset();
     
-    // Navigation
-    await this.router.navigate([redirectUrl]);
+    // Navigation (navigateByUrl conserve les paramètres d'une URL mémorisée, ex. consentement OAuth)
+    await this.router.navigateByUrl(redirectUrl);
   }
 
   // ===== STOCKAGE DES DONNÉES D'AUTHENTIFICATION =====
@@ -491,7 +491,11 @@ export class Login implements OnInit, OnDestroy {
 
   private determineRedirectUrl(user: any): string {
     const storedUrl = sessionStorage.getItem('redirect_url');
-    if (storedUrl) return storedUrl;
+    if (storedUrl) {
+      sessionStorage.removeItem('redirect_url');
+      // Uniquement une URL interne à l'application
+      if (storedUrl.startsWith('/') && !storedUrl.startsWith('//')) return storedUrl;
+    }
     
     const roleUrls: Record<string, string> = {
       admin: '/admin/dashboard',
@@ -499,7 +503,7 @@ export class Login implements OnInit, OnDestroy {
       user: '/dashboard'
     };
     
-    return roleUrls[user.role] || '/dashboard';
+    return roleUrls[user?.role] || '/dashboard';
   }
 
   private handleHttpError(error: HttpErrorResponse): Observable<never> {
@@ -0,0 +1,31 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing';
+import { provideHttpClient } from '@angular/common/http';
+import { provideRouter } from '@angular/router';
+
+import { OAuthConsent } from './oauth-consent';
+
+describe('OAuthConsent', () => {
+  let component: OAuthConsent;
+  let fixture: ComponentFixture<OAuthConsent>;
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      imports: [OAuthConsent],
+      providers: [provideRouter([]), provideHttpClient()]
+    })
+    .compileComponents();
+
+    fixture = TestBed.createComponent(OAuthConsent);
+    component = fixture.componentInstance;
+    fixture.detectChanges();
+  });
+
+  it('should create', () => {
+    expect(component).toBeTruthy();
+  });
+
+  it('should reject a request without client_id', () => {
+    expect(component.request()).toBeNull();
+    expect(component.showError()).toBeTrue();
+  });
+});
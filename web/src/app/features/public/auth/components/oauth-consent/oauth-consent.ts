@@ -0,0 +1,131 @@
+import { Component, OnInit, inject, signal, computed } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { ActivatedRoute, Router } from '@angular/router';
+
+import { OAuthAuthorizeRequest } from '../../models/auth.interface';
+import { AuthService } from '../../services/auth';
+import { Logger } from '@core/logging/logger';
+import { environment } from '@environments/environment';
+
+/**
+ * Page de consentement OpenID Connect.
+ * GET /authorize de l'auth service redirige ici avec la demande du client : l'utilisateur est
+ * d'abord envoyé sur la connexion s'il n'a pas de session, puis accepte ou refuse. La décision est
+ * transmise à POST /authorize, qui retourne l'URL de retour vers le client (code ou access_denied).
+ */
+@Component({
+  selector: 'app-oauth-consent',
+  standalone: true,
+  imports: [CommonModule],
+  templateUrl: './oauth-consent.html',
+  styleUrl: './oauth-consent.scss',
+})
+export class OAuthConsent implements OnInit {
+  // ===== Dependency injection =====
+  private readonly route = inject(ActivatedRoute);
+  private readonly router = inject(Router);
+  private readonly authService = inject(AuthService);
+  private readonly logger = inject(Logger);
+
+  // ===== Signals =====
+  readonly request = signal<OAuthAuthorizeRequest | null>(null);
+  readonly isLoading = signal(false);
+  readonly errorMessage = signal('');
+
+  // Computed signals
+  readonly scopes = computed(() => (this.request()?.scope ?? '').split(' ').filter(scope => scope !== ''));
+  readonly showError = computed(() => this.errorMessage() !== '');
+
+  // Libellés des scopes présentés à l'utilisateur
+  readonly scopeLabels: Record<string, string> = {
+    openid: 'Vous identifier avec votre compte ImmoGestion',
+    profile: 'Accéder à votre nom et à votre agence',
+    email: 'Accéder à votre adresse email',
+    offline_access: 'Rester connecté à votre compte en votre absence',
+  };
+
+  ngOnInit(): void {
+    const params = this.route.snapshot.queryParamMap;
+    const request: OAuthAuthorizeRequest = {
+      response_type: params.get('response_type') ?? '',
+      client_id: params.get('client_id') ?? '',
+      redirect_uri: params.get('redirect_uri') ?? '',
+      scope: params.get('scope') ?? '',
+      state: params.get('state') ?? '',
+      nonce: params.get('nonce') ?? '',
+      code_challenge: params.get('code_challenge') ?? '',
+      code_challenge_method: params.get('code_challenge_method') ?? '',
+    };
+
+    if (!request.client_id || !request.redirect_uri || !request.scope) {
+      this.logger.warn('Invalid authorization request');
+      this.errorMessage.set('Demande d\'autorisation invalide');
+      return;
+    }
+    this.request.set(request);
+
+    // Sans session, passer par la connexion qui ramène ensuite sur cette page
+    if (!this.isAuthenticated()) {
+      this.logger.info('User not authenticated, redirecting to login');
+      this.redirectToLogin();
+    }
+  }
+
+  // ===== DÉCISION DE L'UTILISATEUR =====
+  approve(): Promise<void> {
+    return this.submit(false);
+  }
+
+  deny(): Promise<void> {
+    return this.submit(true);
+  }
+
+  private async submit(deny: boolean): Promise<void> {
+    const request = this.request();
+    if (!request || this.isLoading()) {
+      return;
+    }
+
+    this.isLoading.set(true);
+    this.errorMessage.set('');
+
+    try {
+      const response = await this.authService.callAuthorizeAPI({ ...request, deny });
+      // Retour vers le client : navigation complète hors de l'application
+      window.location.assign(response.data.redirect_to);
+    } catch (error: any) {
+      if (error?.status === 401) {
+        this.redirectToLogin();
+        return;
+      }
+      this.logger.error('Authorization request failed', error);
+      this.errorMessage.set(error?.error?.error_description ?? 'Erreur serveur. Veuillez réessayer plus tard.');
+    } finally {
+      this.isLoading.set(false);
+    }
+  }
+
+  // ===== MÉTHODES UTILITAIRES =====
+  scopeLabel(scope: string): string {
+    return this.scopeLabels[scope] ?? scope;
+  }
+
+  private redirectToLogin(): void {
+    sessionStorage.setItem('redirect_url', this.router.url);
+    this.router.navigate(['/auth/login']);
+  }
+
+  // Vérification d'authentification (token présent et non expiré)
+  private isAuthenticated(): boolean {
+    const token = localStorage.getItem(environment.TOKEN_KEY);
+    if (!token) {
+      return false;
+    }
+    try {
+      const payload = JSON.parse(atob(token.split('.')[1]));
+      return payload.exp > Math.floor(Date.now() / 1000);
+    } catch (error) {
+      return false;
+    }
+  }
+}
@@ -33,4 +33,25 @@ export interface AuthResponse {
     firstName?: string;
     lastName?: string;
   };
-}
\ No newline at end of file
+}
+
+// Demande d'autorisation OpenID Connect transmise par GET /authorize à la page de consentement
+export interface OAuthAuthorizeRequest {
+  response_type: string;
+  client_id: string;
+  redirect_uri: string;
+  scope: string;
+  state?: string;
+  nonce?: string;
+  code_challenge?: string;
+  code_challenge_method?: string;
+  deny?: boolean;
+}
+
+export interface OAuthAuthorizeResponse {
+  status: string;
+  message: string;
+  data: {
+    redirect_to: string;
+  };
+}
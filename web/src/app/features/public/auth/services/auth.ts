@@ -1,7 +1,7 @@
 import { Injectable, inject } from '@angular/core';
 import { HttpClient, HttpHeaders, HttpErrorResponse } from '@angular/common/http';
 import { catchError, timeout, retry, tap, takeUntil, Observable, throwError, timer, firstValueFrom } from 'rxjs';
-import { LoginCredentials, AuthResponse } from '../models/auth.interface';
+import { LoginCredentials, AuthResponse, OAuthAuthorizeRequest, OAuthAuthorizeResponse } from '../models/auth.interface';
 import { Subject } from 'rxjs';
 
 import { environment } from '@environments/environment';
@@ -69,6 +69,33 @@ export class AuthService {
     );
   }
 
+  /**
+   * Call OpenID Connect authorize endpoint with the user's consent decision
+   * @param request Authorization request received from GET /authorize, with the decision
+   * @returns Promise with the URL to send the browser back to the client
+   */
+  async callAuthorizeAPI(request: OAuthAuthorizeRequest): Promise<OAuthAuthorizeResponse> {
+    console.log('Calling authorize API...');
+
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'X-Requested-With': 'XMLHttpRequest',
+      'X-Request-ID': this.generateRequestId(),
+      'Authorization': `Bearer ${localStorage.getItem(environment.TOKEN_KEY) ?? ''}`
+    });
+
+    const url = `${this.API_URL}/${this.API_VERSION}/auth/authorize`;
+
+    // Pas de retry : un code d'autorisation ne doit être émis qu'une fois
+    return firstValueFrom(
+      this.http.post<OAuthAuthorizeResponse>(url, request, { headers, withCredentials: true }).pipe(
+        timeout(environment.timeout),
+        takeUntil(this.destroy$),
+        catchError(this.handleHttpError.bind(this))
+      )
+    );
+  }
+
   /**
    * Retry strategy for failed HTTP requests
    * Implements exponential backoff for server errors